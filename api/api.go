@@ -0,0 +1,397 @@
+// Package api serves a read only JSON API over the bot's stored data and the
+// players it currently sees in its channels. The API is versioned by path and
+// described by the OpenAPI document served at /v1/openapi.yaml.
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Handler serves the API. It is meant to be mounted under a prefix with
+// http.StripPrefix.
+type Handler struct {
+	store    eribo.Store
+	channels *eribo.ChannelMap
+	tokens   [][]byte
+	mux      *http.ServeMux
+
+	// Log is where internal errors are logged.
+	Log *slog.Logger
+}
+
+// NewHandler returns a handler for the store and channels that answers
+// requests carrying any of the tokens as a bearer token.
+func NewHandler(store eribo.Store, channels *eribo.ChannelMap, tokens []string) *Handler {
+	h := &Handler{
+		store:    store,
+		channels: channels,
+		mux:      http.NewServeMux(),
+		Log:      slog.Default(),
+	}
+	for _, t := range tokens {
+		if t != "" {
+			h.tokens = append(h.tokens, []byte(t))
+		}
+	}
+	h.mux.Handle("/v1/images", h.handle(h.serveImages))
+	h.mux.Handle("/v1/images/", h.handle(h.serveImage))
+	h.mux.Handle("/v1/feedback", h.handle(h.serveFeedback))
+	h.mux.Handle("/v1/cmdlogs", h.handle(h.serveCmdLogs))
+	h.mux.Handle("/v1/cmdstats", h.handle(h.serveCmdStats))
+	h.mux.Handle("/v1/cmdusage", h.handle(h.serveCmdUsage))
+	h.mux.Handle("/v1/lothlogs", h.handle(h.serveLothLogs))
+	h.mux.Handle("/v1/channels", h.handle(h.serveChannels))
+	h.mux.Handle("/v1/channels/", h.handle(h.serveChannel))
+	h.mux.Handle("/", h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		return errorf(http.StatusNotFound, "no such endpoint %s", r.URL.Path)
+	}))
+	return h
+}
+
+// apiError is an error that is replied to the client with its status code.
+type apiError struct {
+	code int
+	msg  string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &apiError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// handle returns a handler that serves an endpoint and replies with its
+// error. Errors other than apiErrors are logged and hidden from the client.
+func (h *Handler) handle(fn func(w http.ResponseWriter, r *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := fn(w, r)
+		if err == nil {
+			return
+		}
+		if _, ok := err.(*apiError); !ok {
+			h.Log.Error("serving request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			err = &apiError{code: http.StatusInternalServerError, msg: "internal error"}
+		}
+		replyError(w, err.(*apiError))
+	})
+}
+
+func replyError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{e.msg})
+}
+
+// ServeHTTP authenticates the request and serves it. The OpenAPI document is
+// served to anyone.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		replyError(w, &apiError{http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method)})
+		return
+	}
+	if r.URL.Path == "/v1/openapi.yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+		return
+	}
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="eribo"`)
+		replyError(w, &apiError{http.StatusUnauthorized, "missing or invalid bearer token"})
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return false
+	}
+	token := []byte(auth[len("Bearer "):])
+	ok := false
+	for _, t := range h.tokens {
+		if subtle.ConstantTimeCompare(token, t) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func reply(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(v)
+}
+
+// page is a page of a list with the cursor of the next page, which is empty
+// on the last page.
+type page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// encodeCursor returns the cursor of the page after the record with the id.
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("bad cursor id %q", b)
+	}
+	return id, nil
+}
+
+// parseQuery returns the store query of the request's query parameters. The
+// query asks for one record more than the page holds so that the handlers can
+// tell whether there is a next page.
+func parseQuery(r *http.Request) (eribo.Query, int, error) {
+	v := r.URL.Query()
+	q := eribo.Query{
+		Player:  v.Get("player"),
+		Channel: v.Get("channel"),
+	}
+	limit := defaultLimit
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return q, 0, errorf(http.StatusBadRequest, "limit must be a number from 1 to %d", maxLimit)
+		}
+		limit = n
+	}
+	q.Limit = limit + 1
+	if s := v.Get("cursor"); s != "" {
+		id, err := decodeCursor(s)
+		if err != nil {
+			return q, 0, errorf(http.StatusBadRequest, "invalid cursor")
+		}
+		q.BeforeID = id
+	}
+	var err error
+	if q.Since, err = parseTime(v.Get("since")); err != nil {
+		return q, 0, errorf(http.StatusBadRequest, "since: %v", err)
+	}
+	if q.Until, err = parseTime(v.Get("until")); err != nil {
+		return q, 0, errorf(http.StatusBadRequest, "until: %v", err)
+	}
+	return q, limit, nil
+}
+
+// parseTime parses an RFC 3339 time or a date, which is the midnight UTC that
+// starts it.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a YYYY-MM-DD date", s)
+	}
+	return t, nil
+}
+
+// paginate trims the one extra record of a list to the limit and returns the
+// cursor of the next page if there was one.
+func paginate(n, limit int, id func(i int) int64) (int, string) {
+	if n <= limit {
+		return n, ""
+	}
+	return limit, encodeCursor(id(limit - 1))
+}
+
+func (h *Handler) serveImages(w http.ResponseWriter, r *http.Request) error {
+	q, limit, err := parseQuery(r)
+	if err != nil {
+		return err
+	}
+	images, err := h.store.ListImages(q)
+	if err != nil {
+		return err
+	}
+	n, next := paginate(len(images), limit, func(i int) int64 { return images[i].ID })
+	items := make([]*image, 0, n)
+	for _, img := range images[:n] {
+		items = append(items, newImage(img))
+	}
+	return reply(w, page{items, next})
+}
+
+func (h *Handler) serveImage(w http.ResponseWriter, r *http.Request) error {
+	s := strings.TrimPrefix(r.URL.Path, "/v1/images/")
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "bad image id %q", s)
+	}
+	img, err := h.store.GetImage(id)
+	if err != nil {
+		// The store does not tell missing images from failures.
+		h.Log.Warn("getting image failed", "id", id, "err", err)
+		return errorf(http.StatusNotFound, "image %d not found", id)
+	}
+	return reply(w, newImage(img))
+}
+
+func (h *Handler) serveFeedback(w http.ResponseWriter, r *http.Request) error {
+	q, limit, err := parseQuery(r)
+	if err != nil {
+		return err
+	}
+	if q.Channel != "" {
+		return errorf(http.StatusBadRequest, "feedback has no channel")
+	}
+	fb, err := h.store.ListFeedback(q)
+	if err != nil {
+		return err
+	}
+	n, next := paginate(len(fb), limit, func(i int) int64 { return fb[i].ID })
+	items := make([]*feedback, 0, n)
+	for _, f := range fb[:n] {
+		tags := []string(f.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		items = append(items, &feedback{f.ID, f.Message, f.Player, f.Created, string(f.Status), f.Note, tags})
+	}
+	return reply(w, page{items, next})
+}
+
+func (h *Handler) serveCmdLogs(w http.ResponseWriter, r *http.Request) error {
+	q, limit, err := parseQuery(r)
+	if err != nil {
+		return err
+	}
+	logs, err := h.store.ListCmdLogs(q)
+	if err != nil {
+		return err
+	}
+	n, next := paginate(len(logs), limit, func(i int) int64 { return logs[i].ID })
+	items := make([]*cmdLog, 0, n)
+	for _, l := range logs[:n] {
+		items = append(items, &cmdLog{l.ID, l.Command.String(), l.Args, l.Player, l.Channel, l.Created})
+	}
+	return reply(w, page{items, next})
+}
+
+func (h *Handler) serveCmdStats(w http.ResponseWriter, r *http.Request) error {
+	stats, err := h.store.CmdStats()
+	if err != nil {
+		return err
+	}
+	items := make([]*cmdStat, 0, len(stats))
+	for _, s := range stats {
+		items = append(items, &cmdStat{s.Command.String(), s.Uses})
+	}
+	return reply(w, page{Items: items})
+}
+
+// serveCmdUsage counts the uses of commands grouped by the by parameter. The
+// counts are a single page and limit, without a default, keeps the top ones.
+func (h *Handler) serveCmdUsage(w http.ResponseWriter, r *http.Request) error {
+	v := r.URL.Query()
+	q := eribo.CmdUsageQuery{Query: eribo.Query{Player: v.Get("player"), Channel: v.Get("channel")}, By: eribo.ByCommand}
+	var err error
+	if s := v.Get("by"); s != "" {
+		if q.By, err = eribo.ParseCmdGrouping(s); err != nil {
+			return errorf(http.StatusBadRequest, "by: %v", err)
+		}
+	}
+	if s := v.Get("command"); s != "" {
+		if q.Command, _ = eribo.ParseCommand(s); q.Command == eribo.CmdUnknown {
+			return errorf(http.StatusBadRequest, "unknown command %q", s)
+		}
+	}
+	if q.By == eribo.ByArg && q.Command == eribo.CmdUnknown {
+		return errorf(http.StatusBadRequest, "by=arg needs a command")
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return errorf(http.StatusBadRequest, "limit must be a number from 1 to %d", maxLimit)
+		}
+		q.Limit = n
+	}
+	if q.Since, err = parseTime(v.Get("since")); err != nil {
+		return errorf(http.StatusBadRequest, "since: %v", err)
+	}
+	if q.Until, err = parseTime(v.Get("until")); err != nil {
+		return errorf(http.StatusBadRequest, "until: %v", err)
+	}
+	counts, err := h.store.CmdUsage(q)
+	if err != nil {
+		return err
+	}
+	items := make([]*cmdCount, 0, len(counts))
+	for _, c := range counts {
+		items = append(items, &cmdCount{c.Key, c.Uses})
+	}
+	return reply(w, page{Items: items})
+}
+
+func (h *Handler) serveLothLogs(w http.ResponseWriter, r *http.Request) error {
+	q, limit, err := parseQuery(r)
+	if err != nil {
+		return err
+	}
+	logs, err := h.store.ListLothLogs(q)
+	if err != nil {
+		return err
+	}
+	n, next := paginate(len(logs), limit, func(i int) int64 { return logs[i].ID })
+	items := make([]*lothLog, 0, n)
+	for _, l := range logs[:n] {
+		items = append(items, newLothLog(l))
+	}
+	return reply(w, page{items, next})
+}
+
+func (h *Handler) serveChannels(w http.ResponseWriter, r *http.Request) error {
+	items := []*channel{}
+	h.channels.ForEach(func(name string, pm *eribo.PlayerMap) {
+		items = append(items, newChannel(name, pm))
+	})
+	sortChannels(items)
+	return reply(w, page{Items: items})
+}
+
+func (h *Handler) serveChannel(w http.ResponseWriter, r *http.Request) error {
+	name := strings.TrimPrefix(r.URL.Path, "/v1/channels/")
+	var ch *channel
+	h.channels.ForEach(func(n string, pm *eribo.PlayerMap) {
+		if n == name {
+			ch = newChannel(n, pm)
+		}
+	})
+	if ch == nil {
+		return errorf(http.StatusNotFound, "not in channel %q", name)
+	}
+	return reply(w, ch)
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+	"github.com/kusubooru/eribo/flist"
+)
+
+var day = time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	s := memstore.New()
+	players := []string{"Alice", "Bob", "Alice", "Carol", "Alice"}
+	for i, p := range players {
+		created := day.Add(time.Duration(i) * 24 * time.Hour)
+		channel := "Room"
+		if i == 3 {
+			channel = "Other"
+		}
+		m := &eribo.Message{Player: p, Channel: channel, Created: created}
+		images := []*eribo.Image{{URL: fmt.Sprintf("https://example.com/%d.png", i+1)}}
+		if err := s.AddMessageWithImages(m, images); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+		if err := s.AddFeedback(&eribo.Feedback{Player: p, Message: "hi", Created: created}); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+		if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTieup, Player: p, Channel: channel, Created: created}); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+	if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "Bob", Channel: "Room", Created: day}); err != nil {
+		t.Fatal("AddCmdLog failed:", err)
+	}
+	loth := eribo.NewLoth(&eribo.Player{Name: "Bob", Role: flist.RoleFullSub}, time.Hour)
+	lothLogs := []*eribo.LothLog{
+		{Issuer: "Alice", Channel: "Room", Created: day, Loth: loth, IsNew: true, Targets: eribo.Targets{loth.Player}},
+		{Issuer: "Carol", Channel: "Room", Created: day, Loth: &eribo.Loth{}},
+	}
+	for _, l := range lothLogs {
+		if err := s.AddLothLog(l); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+	}
+	channels := eribo.NewChannelMap()
+	channels.SetPlayer("Room", &eribo.Player{Name: "Bob", Status: flist.StatusOnline})
+	channels.SetPlayer("Room", &eribo.Player{Name: "Alice", Status: flist.StatusAway})
+	return NewHandler(s, channels, []string{"secret", ""})
+}
+
+func get(h http.Handler, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// getPage gets a page of a list and returns the ids of its items.
+func getPage(t *testing.T, h http.Handler, target string) ([]int64, string) {
+	t.Helper()
+	w := get(h, target, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, want %d: %s", target, w.Code, http.StatusOK, w.Body)
+	}
+	var p struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("GET %s returned bad JSON: %v", target, err)
+	}
+	ids := []int64{}
+	for _, it := range p.Items {
+		ids = append(ids, it.ID)
+	}
+	return ids, p.NextCursor
+}
+
+func TestAuth(t *testing.T) {
+	h := newTestHandler(t)
+	for _, token := range []string{"", "wrong", "secret2"} {
+		w := get(h, "/v1/images", token)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("GET with token %q = %d, want %d", token, w.Code, http.StatusUnauthorized)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("GET with token %q has no WWW-Authenticate header", token)
+		}
+	}
+	r := httptest.NewRequest("GET", "/v1/images", nil)
+	r.Header.Set("Authorization", "Basic c2VjcmV0")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GET with basic auth = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := get(h, "/v1/openapi.yaml", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /v1/openapi.yaml without token = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := httptest.NewRequest("POST", "/v1/images", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	newTestHandler(t).ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /v1/images = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestLists(t *testing.T) {
+	var tests = []struct {
+		target string
+		want   []int64
+	}{
+		{"/v1/images", []int64{5, 4, 3, 2, 1}},
+		{"/v1/images?player=alice", []int64{5, 3, 1}},
+		{"/v1/images?channel=Other", []int64{4}},
+		{"/v1/images?since=2018-05-02&until=2018-05-04", []int64{3, 2}},
+		{"/v1/images?since=" + url.QueryEscape(day.Add(36*time.Hour).Format(time.RFC3339)), []int64{5, 4, 3}},
+		{"/v1/feedback?player=Bob", []int64{2}},
+		{"/v1/cmdlogs?player=alice&channel=Room", []int64{5, 3, 1}},
+		{"/v1/lothlogs?player=bob", []int64{1}},
+		{"/v1/lothlogs?player=carol", []int64{2}},
+	}
+	h := newTestHandler(t)
+	for _, tt := range tests {
+		got, next := getPage(t, h, tt.target)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || next != "" {
+			t.Errorf("GET %s = %v %q, want %v and no next page", tt.target, got, next, tt.want)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	h := newTestHandler(t)
+	var all []int64
+	target := "/v1/cmdlogs?player=alice&limit=2"
+	for pages := 0; ; pages++ {
+		if pages == 5 {
+			t.Fatalf("too many pages: %v", all)
+		}
+		ids, next := getPage(t, h, target)
+		all = append(all, ids...)
+		if next == "" {
+			break
+		}
+		target = "/v1/cmdlogs?player=alice&limit=2&cursor=" + next
+	}
+	if want := []int64{5, 3, 1}; fmt.Sprint(all) != fmt.Sprint(want) {
+		t.Errorf("pages of cmdlogs = %v, want %v", all, want)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	targets := []string{
+		"/v1/images?limit=0",
+		"/v1/images?limit=501",
+		"/v1/images?limit=x",
+		"/v1/images?cursor=!!",
+		"/v1/images?cursor=" + encodeCursor(0),
+		"/v1/images?since=yesterday",
+		"/v1/images?until=2018-13-01",
+		"/v1/images/x",
+		"/v1/feedback?channel=Room",
+		"/v1/cmdusage?by=week",
+		"/v1/cmdusage?command=!nope",
+		"/v1/cmdusage?by=arg",
+		"/v1/cmdusage?limit=0",
+		"/v1/cmdusage?since=yesterday",
+	}
+	h := newTestHandler(t)
+	for _, target := range targets {
+		w := get(h, target, "secret")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", target, w.Code, http.StatusBadRequest)
+		}
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Error == "" {
+			t.Errorf("GET %s returned no JSON error: %s", target, w.Body)
+		}
+	}
+}
+
+func TestCmdUsage(t *testing.T) {
+	var tests = []struct {
+		target string
+		want   string
+	}{
+		{"/v1/cmdusage?by=player&command=!tieup&channel=Room&since=2018-05-01&limit=2", `{"items":[{"key":"Alice","uses":3},{"key":"Bob","uses":1}]}`},
+		{"/v1/cmdusage?by=player&command=!tieup&limit=1", `{"items":[{"key":"Alice","uses":3}]}`},
+		{"/v1/cmdusage?since=2018-05-02", `{"items":[{"key":"!tieup","uses":4}]}`},
+		{"/v1/cmdusage", `{"items":[{"key":"!tieup","uses":5},{"key":"!tomato","uses":1}]}`},
+	}
+	h := newTestHandler(t)
+	for _, tt := range tests {
+		w := get(h, tt.target, "secret")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d, want %d: %s", tt.target, w.Code, http.StatusOK, w.Body)
+		}
+		if got := w.Body.String(); got != tt.want+"\n" {
+			t.Errorf("GET %s = %s, want %s", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	var tests = []struct {
+		target string
+		code   int
+		want   string
+	}{
+		{"/v1/images/2", http.StatusOK, `"url":"https://example.com/2.png"`},
+		{"/v1/images/2", http.StatusOK, `"player":"Bob"`},
+		{"/v1/images/9", http.StatusNotFound, `"error"`},
+		{"/v1/cmdstats", http.StatusOK, `{"items":[{"command":"!tieup","uses":5},{"command":"!tomato","uses":1}]}`},
+		{"/v1/channels", http.StatusOK, `{"items":[{"name":"Room","players":[{"name":"Alice"`},
+		{"/v1/channels/Room", http.StatusOK, `"name":"Bob","role":"","status":"online"`},
+		{"/v1/channels/Nowhere", http.StatusNotFound, `"error"`},
+		{"/v1/lothlogs?player=bob", http.StatusOK, `"loth":{"name":"Bob","role":"Always submissive"`},
+		{"/v2/images", http.StatusNotFound, `"error"`},
+	}
+	h := newTestHandler(t)
+	for _, tt := range tests {
+		w := get(h, tt.target, "secret")
+		if w.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.target, w.Code, tt.code)
+		}
+		if got := w.Body.String(); !strings.Contains(got, tt.want) {
+			t.Errorf("GET %s = %s, want it to contain %s", tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestOpenAPIPaths checks that every path of the OpenAPI document is served.
+func TestOpenAPIPaths(t *testing.T) {
+	paths := regexp.MustCompile(`(?m)^  (/\S*):$`).FindAllStringSubmatch(string(openAPI), -1)
+	if len(paths) == 0 {
+		t.Fatal("no paths in the OpenAPI document")
+	}
+	args := strings.NewReplacer("{id}", "1", "{name}", "Room")
+	h := newTestHandler(t)
+	for _, p := range paths {
+		target := "/v1" + args.Replace(p[1])
+		if w := get(h, target, "secret"); w.Code != http.StatusOK {
+			t.Errorf("GET %s of the OpenAPI document = %d, want %d", target, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+// The types below are the JSON representations of the records of the store.
+// They are kept apart from the eribo types so that the API stays the same
+// when the store changes.
+
+type image struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Done        bool      `json:"done"`
+	Kuid        int       `json:"kuid,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	Created     time.Time `json:"created"`
+	Message     *message  `json:"message,omitempty"`
+}
+
+type message struct {
+	ID      int64     `json:"id"`
+	Message string    `json:"message"`
+	Player  string    `json:"player"`
+	Channel string    `json:"channel"`
+	Created time.Time `json:"created"`
+}
+
+func newImage(img *eribo.Image) *image {
+	i := &image{
+		ID:          img.ID,
+		URL:         img.URL,
+		Done:        img.Done,
+		Kuid:        img.Kuid,
+		ContentType: img.ContentType,
+		Size:        img.Size,
+		Created:     img.Created,
+	}
+	if m := img.Message; m != nil {
+		i.Message = &message{m.ID, m.Message, m.Player, m.Channel, m.Created}
+	}
+	return i
+}
+
+type feedback struct {
+	ID      int64     `json:"id"`
+	Message string    `json:"message"`
+	Player  string    `json:"player"`
+	Created time.Time `json:"created"`
+	Status  string    `json:"status"`
+	Note    string    `json:"note"`
+	Tags    []string  `json:"tags"`
+}
+
+type cmdLog struct {
+	ID      int64     `json:"id"`
+	Command string    `json:"command"`
+	Args    string    `json:"args"`
+	Player  string    `json:"player"`
+	Channel string    `json:"channel"`
+	Created time.Time `json:"created"`
+}
+
+type cmdStat struct {
+	Command string `json:"command"`
+	Uses    int    `json:"uses"`
+}
+
+type cmdCount struct {
+	Key  string `json:"key"`
+	Uses int    `json:"uses"`
+}
+
+type player struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Status string `json:"status"`
+	Gender string `json:"gender"`
+	Fave   bool   `json:"fave"`
+}
+
+func newPlayer(p *eribo.Player) *player {
+	return &player{p.Name, string(p.Role), string(p.Status), string(p.Gender), p.Fave}
+}
+
+type loth struct {
+	*player
+	Expires time.Time `json:"expires"`
+}
+
+type lothLog struct {
+	ID      int64     `json:"id"`
+	Issuer  string    `json:"issuer"`
+	Channel string    `json:"channel"`
+	Created time.Time `json:"created"`
+	Loth    *loth     `json:"loth,omitempty"`
+	IsNew   bool      `json:"is_new"`
+	Targets []*player `json:"targets"`
+}
+
+func newLothLog(l *eribo.LothLog) *lothLog {
+	ll := &lothLog{
+		ID:      l.ID,
+		Issuer:  l.Issuer,
+		Channel: l.Channel,
+		Created: l.Created,
+		IsNew:   l.IsNew,
+		Targets: []*player{},
+	}
+	if l.Loth != nil && l.Loth.Player != nil && l.Loth.Name != "" {
+		ll.Loth = &loth{newPlayer(l.Loth.Player), l.Loth.Expires}
+	}
+	for _, p := range l.Targets {
+		ll.Targets = append(ll.Targets, newPlayer(p))
+	}
+	return ll
+}
+
+type channel struct {
+	Name    string    `json:"name"`
+	Players []*player `json:"players"`
+}
+
+func newChannel(name string, pm *eribo.PlayerMap) *channel {
+	ch := &channel{Name: name, Players: []*player{}}
+	pm.ForEach(func(_ string, p *eribo.Player) {
+		ch.Players = append(ch.Players, newPlayer(p))
+	})
+	sort.Slice(ch.Players, func(i, j int) bool { return ch.Players[i].Name < ch.Players[j].Name })
+	return ch
+}
+
+func sortChannels(channels []*channel) {
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+}
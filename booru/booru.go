@@ -0,0 +1,191 @@
+// Package booru uploads images to a Shimmie booru such as kusubooru through
+// its Danbooru compatible API.
+package booru
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBaseURL   = "https://kusubooru.com/"
+	defaultUserAgent = "Eribo (https://github.com/kusubooru/eribo)"
+
+	// MaxImageSize is the largest image that is downloaded for upload.
+	MaxImageSize = 20 << 20
+)
+
+// Client is a client for the upload API of a Shimmie booru.
+type Client struct {
+	client *http.Client
+
+	// User agent used when communicating with the booru.
+	UserAgent string
+
+	// Base URL of the booru, e.g. https://kusubooru.com/.
+	BaseURL *url.URL
+
+	// Credentials of the account that posts are uploaded with.
+	Username string
+	Password string
+}
+
+// NewClient returns a new booru client. With an empty baseURL the client uses
+// kusubooru.
+func NewClient(httpClient *http.Client, baseURL, username, password string) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing booru base URL: %v", err)
+	}
+	c := &Client{
+		client:    httpClient,
+		UserAgent: defaultUserAgent,
+		BaseURL:   u,
+		Username:  username,
+		Password:  password,
+	}
+	return c, nil
+}
+
+// PostURL returns the URL of the post with the id.
+func (c *Client) PostURL(id int) string {
+	return c.BaseURL.ResolveReference(&url.URL{Path: "post/view/" + strconv.Itoa(id)}).String()
+}
+
+// Post is an image to upload.
+type Post struct {
+	Filename string
+	Data     []byte
+	Tags     []string
+	// Source is where the image was found.
+	Source string
+	// Description is saved with the post when the booru supports it.
+	Description string
+}
+
+// Result is the result of an upload.
+type Result struct {
+	// ID is the id of the new post, or of the existing one if the image was
+	// already uploaded.
+	ID        int
+	Duplicate bool
+}
+
+var locationPostID = regexp.MustCompile(`/post/view/(\d+)`)
+
+// Upload uploads a post.
+func (c *Client) Upload(ctx context.Context, p *Post) (*Result, error) {
+	if len(p.Tags) == 0 {
+		return nil, errors.New("post has no tags")
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := []struct{ name, value string }{
+		{"login", c.Username},
+		{"password", c.Password},
+		{"tags", strings.Join(p.Tags, " ")},
+		{"source", p.Source},
+		{"description", p.Description},
+	}
+	for _, f := range fields {
+		if err := w.WriteField(f.name, f.value); err != nil {
+			return nil, err
+		}
+	}
+	fw, err := w.CreateFormFile("file", p.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(p.Data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	u := c.BaseURL.ResolveReference(&url.URL{Path: "api/danbooru/add_post"})
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("X-Danbooru-Location")
+	errs := resp.Header.Get("X-Danbooru-Errors")
+	duplicate := strings.HasPrefix(errs, "duplicate")
+	if errs != "" && !duplicate {
+		return nil, fmt.Errorf("POST %v: %d %s", u, resp.StatusCode, errs)
+	}
+	if resp.StatusCode >= 400 && !duplicate {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("POST %v: %d %s", u, resp.StatusCode, data)
+	}
+	m := locationPostID.FindStringSubmatch(location)
+	if m == nil {
+		return nil, fmt.Errorf("POST %v: %d: no post location in response", u, resp.StatusCode)
+	}
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("POST %v: bad post location %q: %v", u, location, err)
+	}
+	return &Result{ID: id, Duplicate: duplicate}, nil
+}
+
+// Download downloads the image at the URL so that it can be uploaded. It
+// returns the image and a file name for it.
+func (c *Client) Download(ctx context.Context, imageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("GET %v: %d", imageURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("GET %v: %v", imageURL, err)
+	}
+	if len(data) > MaxImageSize {
+		return nil, "", fmt.Errorf("GET %v: image larger than %d bytes", imageURL, MaxImageSize)
+	}
+	name := path.Base(resp.Request.URL.Path)
+	if name == "/" || name == "." {
+		name = "image"
+	}
+	return data, name, nil
+}
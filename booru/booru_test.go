@@ -0,0 +1,139 @@
+package booru
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stub is a booru that accepts uploads like the Shimmie Danbooru API.
+type stub struct {
+	t      *testing.T
+	fields map[string]string
+	file   string
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/danbooru/add_post" || r.Method != "POST" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		s.t.Errorf("parsing upload: %v", err)
+	}
+	s.fields = make(map[string]string)
+	for k, v := range r.MultipartForm.Value {
+		s.fields[k] = v[0]
+	}
+	if r.FormValue("login") != "bot" || r.FormValue("password") != "secret" {
+		w.Header().Set("X-Danbooru-Errors", "authentication error")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		s.t.Errorf("upload has no file: %v", err)
+		return
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	s.file = fh.Filename + ":" + string(data)
+	if string(data) == "old" {
+		w.Header().Set("X-Danbooru-Location", "/post/view/7")
+		w.Header().Set("X-Danbooru-Errors", "duplicate")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	w.Header().Set("X-Danbooru-Location", "/post/view/42")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestUpload(t *testing.T) {
+	s := &stub{t: t}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	c, err := NewClient(ts.Client(), ts.URL, "bot", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Post{
+		Filename:    "a.png",
+		Data:        []byte("new"),
+		Tags:        []string{"feet", "rope"},
+		Source:      "https://example.com/a.png",
+		Description: "Posted by Alice",
+	}
+	res, err := c.Upload(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	if res.ID != 42 || res.Duplicate {
+		t.Errorf("Upload = %+v, want new post 42", res)
+	}
+	if got, want := s.file, "a.png:new"; got != want {
+		t.Errorf("uploaded file = %q, want %q", got, want)
+	}
+	for k, want := range map[string]string{"tags": "feet rope", "source": p.Source, "description": p.Description} {
+		if got := s.fields[k]; got != want {
+			t.Errorf("uploaded %s = %q, want %q", k, got, want)
+		}
+	}
+
+	p.Data = []byte("old")
+	res, err = c.Upload(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Upload of duplicate returned error: %v", err)
+	}
+	if res.ID != 7 || !res.Duplicate {
+		t.Errorf("Upload of duplicate = %+v, want duplicate of post 7", res)
+	}
+
+	c.Password = "wrong"
+	if _, err := c.Upload(context.Background(), p); err == nil || !strings.Contains(err.Error(), "authentication error") {
+		t.Errorf("Upload with wrong password error = %v, want authentication error", err)
+	}
+
+	if _, err := c.Upload(context.Background(), &Post{Data: []byte("x")}); err == nil {
+		t.Errorf("Upload without tags expected error")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img/cat.png":
+			io.WriteString(w, "png data")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c, err := NewClient(ts.Client(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, name, err := c.Download(context.Background(), ts.URL+"/img/cat.png")
+	if err != nil {
+		t.Fatalf("Download returned error: %v", err)
+	}
+	if string(data) != "png data" || name != "cat.png" {
+		t.Errorf("Download = %q, %q, want %q, %q", data, name, "png data", "cat.png")
+	}
+	if _, _, err := c.Download(context.Background(), ts.URL+"/missing.png"); err == nil {
+		t.Errorf("Download of missing image expected error")
+	}
+}
+
+func TestPostURL(t *testing.T) {
+	c, err := NewClient(nil, "https://booru.example.com/sub", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.PostURL(5), "https://booru.example.com/sub/post/view/5"; got != want {
+		t.Errorf("PostURL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+// Command eribo-content checks and previews the rp content files before they
+// are used by the bot.
+//
+// Usage:
+//
+//	eribo-content [flags] lint [content dir]
+//	eribo-content [flags] preview [content dir]
+//
+// Without a content dir the default content compiled into the bot is used.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+	"github.com/kusubooru/eribo/rp"
+)
+
+func main() {
+	flag.Usage = usage
+	users := flag.String("users", "John Doe:Male,Jane Doe:Female,Alex Doe", "comma separated sample `names` to render the preview with, each optionally followed by :gender")
+	file := flag.String("file", "", "only preview items of content `file` e.g. tietools.json")
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	c, err := load(flag.Arg(1))
+	if err != nil {
+		var cerr *rp.ContentError
+		if errors.As(err, &cerr) {
+			for _, p := range cerr.Problems {
+				fmt.Println(p)
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch flag.Arg(0) {
+	case "lint":
+		problems := c.Lint()
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) != 0 {
+			os.Exit(1)
+		}
+	case "preview":
+		for _, user := range strings.Split(*users, ",") {
+			for _, it := range c.Preview(sampleData(user)) {
+				if *file != "" && it.File != *file {
+					continue
+				}
+				fmt.Printf("%s: item %d: %s\n\t%s\n", it.File, it.Item, it.Name, it.Message)
+			}
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// sampleData returns the data for the user described by s as name[:gender]
+// using the user on themselves.
+func sampleData(s string) rp.Data {
+	name, gender, _ := strings.Cut(s, ":")
+	p := &eribo.Player{Name: strings.TrimSpace(name), Gender: flist.Gender(strings.TrimSpace(gender))}
+	return rp.NewData(p, p, &eribo.Player{Name: "Owner"}, "Channel", "Bot")
+}
+
+func load(dir string) (*rp.Content, error) {
+	if dir == "" {
+		return rp.DefaultContent()
+	}
+	return rp.LoadContentDir(dir)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: eribo-content [flags] lint|preview [content dir]\n\n")
+	flag.PrintDefaults()
+}
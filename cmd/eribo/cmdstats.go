@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+// cmdStats runs the owner's !cmdstats command, which counts the uses of the
+// commands grouped by command, channel, player, day, hour or argument.
+func cmdStats(logger *slog.Logger, store eribo.Store, args []string) string {
+	var groupings []string
+	for _, g := range eribo.CmdGroupings {
+		groupings = append(groupings, string(g))
+	}
+	a := eribo.NewArgSet("!cmdstats")
+	command := a.StringOption("command", "", "only count the uses of a command, e.g. tieup; needed to count by arg")
+	player := a.StringOption("player", "", "only count the uses by a player")
+	channel := a.StringOption("channel", "", "only count the uses in a channel")
+	since := a.StringOption("since", "", "only count the uses at or after a date like 2018-05-01 or an RFC 3339 time")
+	until := a.StringOption("until", "", "only count the uses before a date or time")
+	by := a.OptionalString("by", string(eribo.ByCommand), "what to count the uses by: "+strings.Join(groupings, ", ")+"; days and hours are UTC")
+	limit := a.OptionalInt("limit", 0, "how many counts to list, or all of them if 0")
+	if msg := parseArgs(a, args); msg != "" {
+		return msg
+	}
+
+	q := eribo.CmdUsageQuery{Query: eribo.Query{Player: *player, Channel: *channel, Limit: *limit}}
+	var err error
+	if q.By, err = eribo.ParseCmdGrouping(*by); err != nil {
+		return fmt.Sprintf("%v\n%s", err, a.Usage())
+	}
+	if *command != "" {
+		name := "!" + strings.TrimPrefix(*command, "!")
+		if q.Command, _ = eribo.ParseCommand(name); q.Command == eribo.CmdUnknown {
+			return fmt.Sprintf("unknown command %q\n%s", *command, a.Usage())
+		}
+	}
+	if q.By == eribo.ByArg && q.Command == eribo.CmdUnknown {
+		return fmt.Sprintf("%v, e.g. command=tieup\n%s", eribo.ErrArgNeedsCommand, a.Usage())
+	}
+	if q.Since, err = parseDumpTime(*since); err != nil {
+		return fmt.Sprintf("since: %v\n%s", err, a.Usage())
+	}
+	if q.Until, err = parseDumpTime(*until); err != nil {
+		return fmt.Sprintf("until: %v\n%s", err, a.Usage())
+	}
+
+	counts, err := store.CmdUsage(q)
+	if err != nil {
+		logger.Error("getting command usage failed", "by", q.By, "err", err)
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, c := range counts {
+		fmt.Fprintf(&b, "%v\n", c)
+	}
+	return b.String()
+}
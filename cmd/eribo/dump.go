@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo/dump"
+)
+
+const exportUsage = `usage: eribo export [-datasource=<datasource>] [-format=jsonl|csv] [-since=<time>] [-until=<time>] <dir>
+
+Writes the messages, images, feedback, cmd_logs, loth_logs and audit_logs
+created in [since, until), and the feedback_replies of the feedback, to a file
+per table in dir. Times are dates like 2018-05-01 or RFC 3339 times like
+2018-05-01T12:00:00Z.`
+
+const importUsage = `usage: eribo import [-datasource=<datasource>] <dir>
+
+Adds the records of an export in dir to the database with new IDs. Records
+that the database already has are skipped, so an import can be run again.`
+
+// runExport runs the export subcommand on the database of a data source.
+func runExport(w io.Writer, dataSource string, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprintln(w, exportUsage) }
+	fs.StringVar(&dataSource, "datasource", dataSource, "MySQL datasource, postgres:// URL, or sqlite: followed by the `file` of a SQLite database")
+	format := fs.String("format", "jsonl", "format of the files, jsonl or csv")
+	since := fs.String("since", "", "export records created at or after `time`")
+	until := fs.String("until", "", "export records created before `time`")
+	dir, err := parseDumpArgs(fs, args, exportUsage)
+	if dir == "" || err != nil {
+		return err
+	}
+
+	f, err := dump.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	var filter dump.Filter
+	if filter.Since, err = parseDumpTime(*since); err != nil {
+		return fmt.Errorf("-since: %v", err)
+	}
+	if filter.Until, err = parseDumpTime(*until); err != nil {
+		return fmt.Errorf("-until: %v", err)
+	}
+
+	db, err := openStore(dataSource)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	results, err := dump.Export(db, dir, f, filter)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Fprintln(w, r)
+	}
+	return nil
+}
+
+// runImport runs the import subcommand on the database of a data source.
+func runImport(w io.Writer, dataSource string, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprintln(w, importUsage) }
+	fs.StringVar(&dataSource, "datasource", dataSource, "MySQL datasource, postgres:// URL, or sqlite: followed by the `file` of a SQLite database")
+	dir, err := parseDumpArgs(fs, args, importUsage)
+	if dir == "" || err != nil {
+		return err
+	}
+
+	db, err := openStore(dataSource)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	results, err := dump.Import(db, dir)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Fprintln(w, r)
+	}
+	return nil
+}
+
+// parseDumpArgs parses the flags and returns the directory argument, or an
+// empty directory and no error for -help.
+func parseDumpArgs(fs *flag.FlagSet, args []string, usage string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", nil
+		}
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("want one directory\n%s", usage)
+	}
+	if fs.Lookup("datasource").Value.String() == "" {
+		return "", fmt.Errorf("database datasource not provided\n%s", usage)
+	}
+	return fs.Arg(0), nil
+}
+
+// parseDumpTime parses a date or an RFC 3339 time. Dates are midnight UTC.
+func parseDumpTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
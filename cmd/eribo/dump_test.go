@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func TestRunExportImport(t *testing.T) {
+	dir := t.TempDir()
+	src := "sqlite:" + filepath.Join(dir, "src.db")
+	dst := "sqlite:" + filepath.Join(dir, "dst.db")
+	day := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+
+	db, err := openStore(src)
+	if err != nil {
+		t.Fatal("openStore failed:", err)
+	}
+	for i, url := range []string{"http://a.png", "http://b.png"} {
+		m := &eribo.Message{Player: "foo", Channel: "room", Message: url, Created: day.AddDate(0, 0, i)}
+		if err := db.AddMessageWithImages(m, []*eribo.Image{{URL: url}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AddFeedback(&eribo.Feedback{Player: "foo", Message: "hi", Created: day.AddDate(0, 0, i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			"export",
+			func() error {
+				return runExport(&buf, src, []string{"-format", "csv", "-since", "2018-05-02", filepath.Join(dir, "out")})
+			},
+			"messages: 1\nimages: 1\nfeedback: 1\nfeedback_replies: 0\ncmd_logs: 0\nloth_logs: 0\naudit_logs: 0\n",
+		},
+		{
+			"import",
+			func() error { return runImport(&buf, "", []string{"-datasource", dst, filepath.Join(dir, "out")}) },
+			"messages: 1\nimages: 1\nfeedback: 1\nfeedback_replies: 0\ncmd_logs: 0\nloth_logs: 0\naudit_logs: 0\n",
+		},
+		{
+			"import again",
+			func() error { return runImport(&buf, dst, []string{filepath.Join(dir, "out")}) },
+			"messages: 0 (1 skipped)\nimages: 0 (1 skipped)\nfeedback: 0 (1 skipped)\nfeedback_replies: 0\ncmd_logs: 0\nloth_logs: 0\naudit_logs: 0\n",
+		},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		if err := tt.run(); err != nil {
+			t.Fatalf("%s failed: %v", tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s output\nhave: %q\nwant: %q", tt.name, got, tt.want)
+		}
+	}
+
+	db, err = openStore(dst)
+	if err != nil {
+		t.Fatal("openStore failed:", err)
+	}
+	defer db.Close()
+	img, err := db.GetImage(1)
+	if err != nil {
+		t.Fatal("GetImage failed:", err)
+	}
+	if img.URL != "http://b.png" || img.Message.Message != "http://b.png" || !img.Created.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("imported image = %s of message %q created %v, want http://b.png of message \"http://b.png\" created %v", img.URL, img.Message.Message, img.Created, day.AddDate(0, 0, 1))
+	}
+}
+
+func TestRunExport_args(t *testing.T) {
+	dataSource := "sqlite:" + filepath.Join(t.TempDir(), "eribo.db")
+	var buf bytes.Buffer
+	tests := [][]string{
+		{},
+		{"a", "b"},
+		{"-format", "xml", "out"},
+		{"-since", "yesterday", "out"},
+		{"-until", "2018-13-01", "out"},
+	}
+	for _, args := range tests {
+		if err := runExport(&buf, dataSource, args); err == nil {
+			t.Errorf("export %q succeeded", args)
+		}
+	}
+	if err := runImport(&buf, "", []string{"out"}); err == nil {
+		t.Error("import without a datasource succeeded")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// priSender sends private messages.
+type priSender interface {
+	SendPRI(*flist.PRI) error
+}
+
+// feedbackReplies sends the owner's replies to feedback to the players who
+// sent it. Replies to players who are offline stay unsent in the store until
+// the players come online.
+type feedbackReplies struct {
+	sender priSender
+	store  eribo.Store
+	online func(name string) bool
+
+	mu sync.Mutex
+	// pending are the names of the players with unsent replies by their
+	// lowercase names, so that the many players who come online without
+	// replies cost no query.
+	pending map[string]string
+}
+
+// newFeedbackReplies returns the replies of a store, whose unsent replies are
+// sent to players when online reports them online.
+func newFeedbackReplies(sender priSender, store eribo.Store, online func(name string) bool) (*feedbackReplies, error) {
+	unsent, err := store.ListUnsentReplies("")
+	if err != nil {
+		return nil, fmt.Errorf("error getting unsent replies: %v", err)
+	}
+	q := &feedbackReplies{sender: sender, store: store, online: online, pending: make(map[string]string)}
+	for _, r := range unsent {
+		q.pending[strings.ToLower(r.Player)] = r.Player
+	}
+	return q, nil
+}
+
+// add stores a reply and sends it if its player is online. It reports whether
+// the reply was sent.
+func (q *feedbackReplies) add(r *eribo.FeedbackReply) (bool, error) {
+	if err := q.store.AddFeedbackReply(r); err != nil {
+		return false, fmt.Errorf("error storing reply: %v", err)
+	}
+	q.mu.Lock()
+	q.pending[strings.ToLower(r.Player)] = r.Player
+	q.mu.Unlock()
+	n, err := q.deliver(r.Player)
+	return n != 0, err
+}
+
+// deliver sends the unsent replies to a player who is online and returns how
+// many it sent.
+func (q *feedbackReplies) deliver(player string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := strings.ToLower(player)
+	if _, ok := q.pending[key]; !ok || !q.online(player) {
+		return 0, nil
+	}
+	replies, err := q.store.ListUnsentReplies(player)
+	if err != nil {
+		return 0, fmt.Errorf("error getting unsent replies: %v", err)
+	}
+	for i, r := range replies {
+		pri := &flist.PRI{Recipient: r.Player, Message: fmt.Sprintf("[b]Reply to your feedback #%d:[/b] %s", r.FeedbackID, r.Message)}
+		if err := q.sender.SendPRI(pri); err != nil {
+			return i, fmt.Errorf("error sending reply %d: %v", r.ID, err)
+		}
+		if err := q.store.MarkReplySent(r.ID, time.Now()); err != nil {
+			return i + 1, fmt.Errorf("error marking reply %d sent: %v", r.ID, err)
+		}
+	}
+	delete(q.pending, key)
+	return len(replies), nil
+}
+
+// deliverAll sends the unsent replies to the players who are online and
+// returns how many it sent.
+func (q *feedbackReplies) deliverAll() (int, error) {
+	q.mu.Lock()
+	var players []string
+	for _, p := range q.pending {
+		players = append(players, p)
+	}
+	q.mu.Unlock()
+	sort.Strings(players)
+
+	var sent int
+	var errs []error
+	for _, p := range players {
+		n, err := q.deliver(p)
+		sent += n
+		errs = append(errs, err)
+	}
+	return sent, errors.Join(errs...)
+}
+
+const feedUsage = `Usage: !feed [status=<status>] [limit] [offset]
+!feed show <id>
+!feed status <id> <status>
+!feed note <id> [note]
+!feed tag <id> [tags]
+!feed reply <id> <text>`
+
+// feedCommand runs the owner's !feed command, which lists the feedback or,
+// with a subcommand, shows, updates or replies to one.
+func feedCommand(logger *slog.Logger, store eribo.Store, replies *feedbackReplies, args []string) string {
+	sub := ""
+	if len(args) != 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "show", "status", "note", "tag", "reply":
+	case "--help", "help":
+		return feedUsage
+	default:
+		return listFeedback(logger, store, args)
+	}
+
+	a := eribo.NewArgSet("!feed " + sub)
+	id := a.Int("id", "the feedback id")
+	var status, text *string
+	switch sub {
+	case "status":
+		status = a.String("status", "open, acknowledged, resolved or wontfix")
+	case "note":
+		text = a.Text("note", "the owner's note, which is cleared when empty")
+	case "tag":
+		text = a.Text("tags", "tags separated by spaces or commas, which replace the current ones")
+	case "reply":
+		text = a.Text("text", "the reply sent to the player")
+	}
+	if msg := parseArgs(a, args[1:]); msg != "" {
+		return msg
+	}
+	f, err := store.GetFeedback(int64(*id))
+	if err != nil {
+		return fmt.Sprintf("error getting feedback %d: %v", *id, err)
+	}
+
+	switch sub {
+	case "show":
+		return showFeedback(store, f)
+	case "reply":
+		if strings.TrimSpace(*text) == "" {
+			return "no reply provided\n" + a.Usage()
+		}
+		r := &eribo.FeedbackReply{FeedbackID: f.ID, Player: f.Player, Message: strings.TrimSpace(*text)}
+		sent, err := replies.add(r)
+		switch {
+		case err != nil && r.ID == 0:
+			return fmt.Sprintf("error replying to feedback %d: %v", f.ID, err)
+		case err != nil:
+			logger.Error("sending feedback reply failed", "reply", r.ID, "err", err)
+			return fmt.Sprintf("reply %d to %s is stored but sending it failed: %v", r.ID, f.Player, err)
+		case sent:
+			return fmt.Sprintf("reply %d sent to %s", r.ID, f.Player)
+		}
+		return fmt.Sprintf("reply %d will be sent when %s is online", r.ID, f.Player)
+	case "status":
+		if f.Status, err = eribo.ParseFeedbackStatus(*status); err != nil {
+			return fmt.Sprintf("%v\n%s", err, a.Usage())
+		}
+	case "note":
+		f.Note = strings.TrimSpace(*text)
+	case "tag":
+		f.Tags = parseTags(*text)
+	}
+	if err := store.UpdateFeedback(f); err != nil {
+		return fmt.Sprintf("error updating feedback %d: %v", f.ID, err)
+	}
+	return f.String()
+}
+
+// listFeedback lists the most recent feedback, optionally only the feedback
+// with a status.
+func listFeedback(logger *slog.Logger, store eribo.Store, args []string) string {
+	a := eribo.NewArgSet("!feed")
+	status := a.StringOption("status", "", "only list the feedback with a status: open, acknowledged, resolved or wontfix")
+	limit, offset := limitOffsetArgs(a)
+	if msg := parseArgs(a, args); msg != "" {
+		return msg
+	}
+	var feedback []*eribo.Feedback
+	var err error
+	if *status == "" {
+		feedback, err = store.GetRecentFeedback(*limit, *offset)
+	} else {
+		var st eribo.FeedbackStatus
+		if st, err = eribo.ParseFeedbackStatus(*status); err != nil {
+			return fmt.Sprintf("%v\n%s", err, a.Usage())
+		}
+		feedback, err = feedbackWithStatus(store, st, *limit, *offset)
+	}
+	if err != nil {
+		logger.Error("getting feedback failed", "err", err)
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, fb := range feedback {
+		fmt.Fprintf(&b, "%v\n", fb)
+	}
+	return b.String()
+}
+
+// feedbackWithStatus returns a page of the feedback with a status, newest
+// first. The store selects the feedback by status but has no offset, so the
+// skipped feedback is read along with the page.
+func feedbackWithStatus(store eribo.Store, status eribo.FeedbackStatus, limit, offset int) ([]*eribo.Feedback, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	feedback, err := store.ListFeedback(eribo.Query{Status: status, Limit: offset + limit})
+	if err != nil || len(feedback) <= offset {
+		return nil, err
+	}
+	return feedback[offset:], nil
+}
+
+// showFeedback returns a feedback with its note and replies.
+func showFeedback(store eribo.Store, f *eribo.Feedback) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%v\n", f)
+	if f.Note != "" {
+		fmt.Fprintf(&b, "Note: %s\n", f.Note)
+	}
+	replies, err := store.ListFeedbackReplies(f.ID)
+	if err != nil {
+		fmt.Fprintf(&b, "error getting replies: %v\n", err)
+	}
+	for _, r := range replies {
+		fmt.Fprintf(&b, "%v\n", r)
+	}
+	return b.String()
+}
+
+// parseTags returns the lowercase tags in text separated by spaces or commas
+// without duplicates.
+func parseTags(text string) eribo.Tags {
+	var tags eribo.Tags
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ReplaceAll(text, `"`, ""), func(r rune) bool { return r == ',' || r == ' ' }) {
+		t = strings.ToLower(t)
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// myFeedback returns the reply to !myfeedback, which lists the status of the
+// feedback a player sent.
+func myFeedback(store privStore, player string, limit int) (string, error) {
+	feedback, err := store.ListFeedback(eribo.Query{Player: player, Limit: limit})
+	if err != nil {
+		return "", err
+	}
+	if len(feedback) == 0 {
+		return fmt.Sprintf("You have not sent any feedback. Send %v followed by your message to tell the owner something.", eribo.CmdFeedback), nil
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, f := range feedback {
+		fmt.Fprintf(&b, "#%d %s [b]%s[/b] - %q\n", f.ID, f.Created.Format("Jan _2"), f.Status, f.Message)
+	}
+	return b.String(), nil
+}
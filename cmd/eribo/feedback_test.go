@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// priRecorder records the private messages it is asked to send.
+type priRecorder struct {
+	sent []*flist.PRI
+}
+
+func (r *priRecorder) SendPRI(pri *flist.PRI) error {
+	r.sent = append(r.sent, pri)
+	return nil
+}
+
+func TestFeedbackReplies(t *testing.T) {
+	store := memstore.New()
+	for _, player := range []string{"Alice", "Bob"} {
+		if err := store.AddFeedback(&eribo.Feedback{Player: player, Message: "more tools"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Bob's reply was queued before the bot restarted.
+	if err := store.AddFeedbackReply(&eribo.FeedbackReply{FeedbackID: 2, Player: "Bob", Message: "soon"}); err != nil {
+		t.Fatal(err)
+	}
+	online := map[string]bool{"Alice": true}
+	sender := &priRecorder{}
+	replies, err := newFeedbackReplies(sender, store, func(name string) bool { return online[name] })
+	if err != nil {
+		t.Fatal("newFeedbackReplies failed:", err)
+	}
+
+	sent, err := replies.add(&eribo.FeedbackReply{FeedbackID: 1, Player: "Alice", Message: "added"})
+	if err != nil || !sent {
+		t.Errorf("reply to online player sent = %v, %v, want true", sent, err)
+	}
+	if n, err := replies.deliver("bob"); err != nil || n != 0 {
+		t.Errorf("deliver to offline player = %d, %v, want 0", n, err)
+	}
+	online["Bob"] = true
+	if n, err := replies.deliverAll(); err != nil || n != 1 {
+		t.Errorf("deliverAll = %d, %v, want 1", n, err)
+	}
+	if n, err := replies.deliver("Bob"); err != nil || n != 0 {
+		t.Errorf("deliver again = %d, %v, want 0", n, err)
+	}
+
+	want := []*flist.PRI{
+		{Recipient: "Alice", Message: "[b]Reply to your feedback #1:[/b] added"},
+		{Recipient: "Bob", Message: "[b]Reply to your feedback #2:[/b] soon"},
+	}
+	if !reflect.DeepEqual(sender.sent, want) {
+		t.Errorf("sent messages\nhave: %+v\nwant: %+v", sender.sent, want)
+	}
+	unsent, err := store.ListUnsentReplies("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsent) != 0 {
+		t.Errorf("unsent replies = %v, want none", unsent)
+	}
+}
+
+func TestFeedCommand(t *testing.T) {
+	store := memstore.New()
+	for _, m := range []string{"more tools", "less tools", "fix !tieup"} {
+		if err := store.AddFeedback(&eribo.Feedback{Player: "Alice", Message: m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sender := &priRecorder{}
+	replies, err := newFeedbackReplies(sender, store, func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	feed := func(args ...string) string { return feedCommand(logger, store, replies, args) }
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"status", "2", "wontfix"}, "[wontfix]"},
+		{[]string{"status", "2", "later"}, "unknown feedback status"},
+		{[]string{"tag", "3", "Bug,", "tieup", "bug"}, "[open bug,tieup]"},
+		{[]string{"note", "3", "next", "release"}, `fix !tieup`},
+		{[]string{"reply", "3", "fixed", "soon"}, "reply 1 will be sent when Alice is online"},
+		{[]string{"reply", "3"}, "no reply provided"},
+		{[]string{"reply", "9", "hi"}, "error getting feedback 9"},
+		{[]string{"show", "3"}, "Note: next release"},
+		{[]string{"show", "3"}, "fixed soon"},
+		{[]string{"status=wontfix"}, "less tools"},
+		{[]string{"--help"}, "!feed reply <id> <text>"},
+	}
+	for _, tt := range tests {
+		if got := feed(tt.args...); !strings.Contains(got, tt.want) {
+			t.Errorf("!feed %q = %q, want it to contain %q", tt.args, got, tt.want)
+		}
+	}
+
+	if got := feed("status=open", "1"); !strings.Contains(got, "fix !tieup") || strings.Contains(got, "more tools") {
+		t.Errorf("!feed status=open 1 = %q, want only the newest open feedback", got)
+	}
+	if got := feed("status=open", "1", "1"); !strings.Contains(got, "more tools") || strings.Contains(got, "fix !tieup") {
+		t.Errorf("!feed status=open 1 1 = %q, want only the oldest open feedback", got)
+	}
+	f, err := store.GetFeedback(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (eribo.Tags{"bug", "tieup"}); !reflect.DeepEqual(f.Tags, want) || f.Note != "next release" {
+		t.Errorf("feedback 3 tags and note = %q, %q, want %q, %q", f.Tags, f.Note, want, "next release")
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("replies sent to offline player: %v", sender.sent)
+	}
+}
+
+func TestMyFeedback(t *testing.T) {
+	store := memstore.New()
+	got, err := myFeedback(store, "Alice", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "You have not sent any feedback.") {
+		t.Errorf("myFeedback without feedback = %q", got)
+	}
+	if err := store.AddFeedback(&eribo.Feedback{Player: "Alice", Message: "more tools", Status: eribo.FeedbackResolved}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddFeedback(&eribo.Feedback{Player: "Bob", Message: "less tools"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = myFeedback(store, "alice", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, `#1`) || !strings.Contains(got, `[b]resolved[/b] - "more tools"`) || strings.Contains(got, "less tools") {
+		t.Errorf("myFeedback of alice = %q, want only her resolved feedback", got)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kusubooru/eribo/health"
+)
+
+// pingTimeout is how long the bot can go without a PIN from the server before
+// the connection is considered dead. The server pings every 30 seconds.
+const pingTimeout = 2 * time.Minute
+
+// chatState keeps what the health checks need to know about the connection
+// to F-Chat.
+type chatState struct {
+	now func() time.Time
+
+	mu          sync.Mutex
+	connected   bool
+	connectedAt time.Time
+	disconnect  error
+	identified  bool
+	lastPing    time.Time
+	joined      map[string]string // channel name to title
+}
+
+func newChatState() *chatState {
+	return &chatState{now: time.Now, joined: make(map[string]string)}
+}
+
+func (s *chatState) setConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = true
+	s.connectedAt = s.now()
+	s.disconnect = nil
+}
+
+// setDisconnected records that the connection was lost with err, or closed
+// by the bot if err is nil.
+func (s *chatState) setDisconnected(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = false
+	s.identified = false
+	s.disconnect = err
+	s.joined = make(map[string]string)
+}
+
+func (s *chatState) setIdentified() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identified = true
+}
+
+func (s *chatState) ping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastPing = s.now()
+}
+
+// join records that the bot joined a channel. Private rooms have a title
+// apart from their name.
+func (s *chatState) join(name, title string) {
+	if title == "" {
+		title = name
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.joined[name] = title
+}
+
+func (s *chatState) leave(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.joined, name)
+}
+
+func (s *chatState) checkWebsocket(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		if s.disconnect != nil {
+			return "", fmt.Errorf("disconnected: %v", s.disconnect)
+		}
+		return "", fmt.Errorf("not connected")
+	}
+	return fmt.Sprintf("connected since %s", s.connectedAt.UTC().Format(time.RFC3339)), nil
+}
+
+// checkPing fails if no PIN came from the server for pingTimeout, counting
+// from the connection until the first one.
+func (s *chatState) checkPing(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		return "", fmt.Errorf("not connected")
+	}
+	if s.lastPing.IsZero() {
+		if since := s.now().Sub(s.connectedAt); since > pingTimeout {
+			return "", fmt.Errorf("no ping in %v since connecting", since.Round(time.Second))
+		}
+		return "no ping yet", nil
+	}
+	detail := "last ping at " + s.lastPing.UTC().Format(time.RFC3339)
+	if since := s.now().Sub(s.lastPing); since > pingTimeout {
+		return detail, fmt.Errorf("no ping in %v", since.Round(time.Second))
+	}
+	return detail, nil
+}
+
+func (s *chatState) checkIdentified(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.identified {
+		return "", fmt.Errorf("not identified")
+	}
+	return "identified", nil
+}
+
+// checkChannels returns a check that fails if the bot is not in all the rooms
+// configured with -join, which are given by title.
+func (s *chatState) checkChannels(rooms []string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		s.mu.Lock()
+		joined := make(map[string]bool)
+		var titles []string
+		for _, title := range s.joined {
+			joined[title] = true
+			titles = append(titles, title)
+		}
+		s.mu.Unlock()
+		sort.Strings(titles)
+
+		var missing []string
+		for _, room := range rooms {
+			if !joined[room] {
+				missing = append(missing, room)
+			}
+		}
+		detail := fmt.Sprintf("joined %d: %s", len(titles), strings.Join(titles, ", "))
+		if len(missing) != 0 {
+			return detail, fmt.Errorf("not joined %d of %d configured rooms: %s", len(missing), len(rooms), strings.Join(missing, ", "))
+		}
+		return detail, nil
+	}
+}
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+func checkDatabase(db pinger) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		start := time.Now()
+		if err := db.PingContext(ctx); err != nil {
+			return "", fmt.Errorf("ping: %v", err)
+		}
+		return fmt.Sprintf("ping took %v", time.Since(start).Round(time.Millisecond)), nil
+	}
+}
+
+// liveness returns the checks of /healthz, which fail when the bot should be
+// restarted.
+func (s *chatState) liveness() []health.Check {
+	return []health.Check{
+		{Name: "websocket", Func: s.checkWebsocket},
+		{Name: "ping", Func: s.checkPing},
+	}
+}
+
+// readiness returns the checks of /readyz, which fail when the bot is not
+// fully working.
+func (s *chatState) readiness(rooms []string, db pinger) []health.Check {
+	return append(s.liveness(),
+		health.Check{Name: "identified", Func: s.checkIdentified},
+		health.Check{Name: "channels", Func: s.checkChannels(rooms)},
+		health.Check{Name: "database", Func: checkDatabase(db)},
+	)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) PingContext(context.Context) error { return p.err }
+
+func TestChatState(t *testing.T) {
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newChatState()
+	s.now = func() time.Time { return now }
+	rooms := []string{"Lab", "Milkshakes"}
+	db := fakePinger{}
+
+	failed := func() []string {
+		var names []string
+		for _, c := range s.readiness(rooms, db) {
+			if _, err := c.Func(context.Background()); err != nil {
+				names = append(names, c.Name)
+			}
+		}
+		return names
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		if got := failed(); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: failed checks = %v, want %v", step, got, want)
+		}
+	}
+
+	check("start", "websocket", "ping", "identified", "channels")
+	s.setConnected()
+	check("connected", "identified", "channels")
+	s.setIdentified()
+	s.join("ADH-1", "Lab")
+	check("joined one room", "channels")
+	s.join("ADH-2", "Milkshakes")
+	s.join("Frontpage", "")
+	check("joined all rooms")
+
+	now = now.Add(3 * time.Minute)
+	check("no ping since connecting", "ping")
+	s.ping()
+	check("pinged")
+	s.leave("ADH-2")
+	check("left a room", "channels")
+	s.join("ADH-2", "Milkshakes")
+
+	db.err = errors.New("connection refused")
+	check("database down", "database")
+	db.err = nil
+
+	s.setDisconnected(errors.New("EOF"))
+	check("disconnected", "websocket", "ping", "identified", "channels")
+	if _, err := s.checkWebsocket(context.Background()); err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("websocket check after disconnect = %v, want error with EOF", err)
+	}
+}
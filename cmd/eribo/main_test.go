@@ -1,8 +1,21 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/booru"
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+	"github.com/kusubooru/eribo/logging"
 )
 
 var splitRoomTitlesTests = []struct {
@@ -25,79 +38,210 @@ func TestSplitRoomTitles(t *testing.T) {
 	}
 }
 
-func TestArgsContain(t *testing.T) {
+func TestImagesArgs(t *testing.T) {
 	var tests = []struct {
-		inArgs  []string
-		in      string
-		outArgs []string
-		out     bool
+		args    []string
+		reverse bool
+		all     bool
+		limit   int
+		offset  int
+		msg     string
 	}{
-		{[]string{"10", "0", "desc"}, "desc", []string{"10", "0"}, true},
-		{[]string{"10", "0"}, "desc", []string{"10", "0"}, false},
-		{[]string{}, "desc", []string{}, false},
-		{nil, "desc", nil, false},
+		{[]string{"10", "0", "desc"}, true, false, 10, 0, ""},
+		{[]string{"desc", "all", "20", "5"}, true, true, 20, 5, ""},
+		{[]string{"offset=5", "--desc"}, true, false, 10, 5, ""},
+		{[]string{"desc"}, true, false, 10, 0, ""},
+		{[]string{}, false, false, 10, 0, ""},
+		{nil, false, false, 10, 0, ""},
+		{[]string{"ten"}, false, false, 10, 0, "!images: limit: \"ten\" is not a number\nUsage: !images [desc] [all] [limit] [offset]"},
+		{[]string{"1", "2", "3"}, false, false, 1, 2, "!images: unexpected 3\nUsage: !images [desc] [all] [limit] [offset]"},
 	}
 
 	for _, tt := range tests {
-		args, ok := argsContain(tt.inArgs, tt.in)
-		if got, want := ok, tt.out; got != want {
-			t.Errorf("popArgs(%v, %v) = %v, want %v", tt.inArgs, tt.in, got, want)
+		a := eribo.NewArgSet("!images")
+		reverse := a.Bool("desc", "")
+		all := a.Bool("all", "")
+		limit, offset := limitOffsetArgs(a)
+		msg := parseArgs(a, tt.args)
+		if msg != tt.msg {
+			t.Errorf("parseArgs(%q) message = %q, want %q", tt.args, msg, tt.msg)
+		}
+		if msg != "" {
+			continue
 		}
-		if got, want := args, tt.outArgs; !reflect.DeepEqual(got, want) {
-			t.Errorf("\nhave: %v\nwant: %v", got, want)
+		if got, want := []interface{}{*reverse, *all, *limit, *offset}, []interface{}{tt.reverse, tt.all, tt.limit, tt.offset}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parseArgs(%q) = %v, want %v", tt.args, got, want)
 		}
 	}
 }
 
-func TestArgsPopAtoi(t *testing.T) {
-	var tests = []struct {
-		inArgs  []string
-		n       int
-		outArgs []string
-		ok      bool
-	}{
-		{[]string{"10", "0", "desc"}, 10, []string{"0", "desc"}, true},
-		{[]string{"desc", "10", "0"}, 10, []string{"desc", "0"}, true},
-		{[]string{"desc"}, 0, []string{"desc"}, false},
-		{[]string{}, 0, []string{}, false},
-		{nil, 0, nil, false},
+// imageStore is a store that only holds images.
+type imageStore struct {
+	eribo.Store
+	images map[int64]*eribo.Image
+}
+
+func (s *imageStore) GetImage(id int64) (*eribo.Image, error) {
+	img, ok := s.images[id]
+	if !ok {
+		return nil, fmt.Errorf("no image %d", id)
+	}
+	return img, nil
+}
+
+func (s *imageStore) SetImageKuid(id int64, kuid int) error {
+	s.images[id].Kuid = kuid
+	s.images[id].Done = true
+	return nil
+}
+
+func TestUploadImage(t *testing.T) {
+	var description string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			fmt.Fprint(w, "png data")
+		case "/api/danbooru/add_post":
+			description = r.FormValue("description")
+			w.Header().Set("X-Danbooru-Location", "/post/view/42")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	uploader, err := booru.NewClient(ts.Client(), ts.URL, "bot", "secret")
+	if err != nil {
+		t.Fatal(err)
 	}
+	store := &imageStore{images: map[int64]*eribo.Image{
+		1: {ID: 1, URL: ts.URL + "/cat.png", Message: &eribo.Message{Player: "Alice", Channel: "Room", Message: "look"}},
+	}}
+
+	got := uploadImage(context.Background(), store, uploader, 1, []string{"cat"})
+	if want := fmt.Sprintf("image 1 uploaded as [url=%s/post/view/42]post 42[/url]", ts.URL); got != want {
+		t.Errorf("uploadImage = %q, want %q", got, want)
+	}
+	if img := store.images[1]; img.Kuid != 42 || !img.Done {
+		t.Errorf("uploaded image kuid, done = %d, %v, want 42, true", img.Kuid, img.Done)
+	}
+	if want := "Posted by Alice in Room: look"; description != want {
+		t.Errorf("uploaded description = %q, want %q", description, want)
+	}
+	if got := uploadImage(context.Background(), store, uploader, 2, []string{"cat"}); !strings.HasPrefix(got, "error getting image 2") {
+		t.Errorf("uploadImage of missing image = %q", got)
+	}
+}
+
+func TestBodySnippet(t *testing.T) {
+	body := []byte("<html>\n  <head>\n\t<title>Error</title>\n  </head>\n" + strings.Repeat("x", 300))
+	got := bodySnippet(body)
+	if want := "<html> <head> <title>Error</title> </head> xxx"; !strings.HasPrefix(got, want) {
+		t.Errorf("bodySnippet = %q, want prefix %q", got, want)
+	}
+	if len(got) != 203 || !strings.HasSuffix(got, "...") {
+		t.Errorf("bodySnippet length = %d, want 200 and ...", len(got))
+	}
+}
+
+func TestOwnerLogLevel(t *testing.T) {
+	loggers := logging.New(io.Discard, false, slog.LevelInfo)
+	loggers.Logger("bot")
+	loggers.Logger("flist")
 
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"!loglevel", "bot=INFO flist=INFO"},
+		{"!loglevel warn,flist=debug", "bot=WARN flist=DEBUG"},
+		{"!loglevel store=debug", `error setting log levels: unknown subsystem "store", want one of bot, flist`},
+		{"!loglevel", "bot=WARN flist=DEBUG"},
+	}
 	for _, tt := range tests {
-		n, args, ok := argsPopAtoi(tt.inArgs)
-		if got, want := n, tt.n; got != want {
-			t.Errorf("popArgs(%v) = %v, want %v", tt.inArgs, got, want)
+		got := ownerCommand(nil, loggers, nil, nil, nil, nil, nil, nil, nil, "owner", tt.message, "", "")
+		if got != tt.want {
+			t.Errorf("ownerCommand(%q) = %q, want %q", tt.message, got, tt.want)
 		}
-		if got, want := ok, tt.ok; got != want {
-			t.Errorf("popArgs(%v) = %v, want %v", tt.inArgs, got, want)
+	}
+}
+
+func TestOwnerCommand_store(t *testing.T) {
+	store := memstore.New()
+	day := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := &eribo.Message{Channel: "room", Player: "Alice", Message: "look", Created: day}
+	if err := store.AddMessageWithImages(m, []*eribo.Image{{URL: "http://cat.png"}, {URL: "http://dog.png"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i, player := range []string{"Alice", "Bob"} {
+		if err := store.AddFeedback(&eribo.Feedback{Player: player, Message: "more tools", Created: day.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
 		}
-		if got, want := args, tt.outArgs; !reflect.DeepEqual(got, want) {
-			t.Errorf("\nhave: %v\nwant: %v", got, want)
+	}
+	for _, cmd := range []eribo.Command{eribo.CmdTomato, eribo.CmdTieup, eribo.CmdTomato} {
+		if err := store.AddCmdLog(&eribo.CmdLog{Command: cmd, Player: "Alice", Channel: "room", Created: day}); err != nil {
+			t.Fatal(err)
 		}
 	}
-}
+	loggers := logging.New(io.Discard, false, slog.LevelInfo)
+	replies, err := newFeedbackReplies(&priRecorder{}, store, func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := func(message string) string {
+		return ownerCommand(nil, loggers, store, nil, nil, nil, nil, nil, replies, "owner", message, "", "")
+	}
 
-func TestArgsPopAtoiDefault(t *testing.T) {
-	var tests = []struct {
-		inArgs    []string
-		inDefault int
-		n         int
-		outArgs   []string
+	if got := owner("!done 1"); strings.Contains(got, "cat.png") || !strings.Contains(got, "dog.png") {
+		t.Errorf("!done 1 = %q, want only the image that is not done", got)
+	}
+	if img, err := store.GetImage(1); err != nil || !img.Done {
+		t.Errorf("image 1 after !done = %+v, %v, want done", img, err)
+	}
+	if got := owner("!done 3"); !strings.HasPrefix(got, "error toggling image done") {
+		t.Errorf("!done of a missing image = %q, want error", got)
+	}
+	if got, want := owner("!feed 1"), "\n"+(eribo.Feedback{ID: 2, Player: "Bob", Message: "more tools", Created: day.Add(time.Hour), Status: eribo.FeedbackOpen}).String()+"\n"; got != want {
+		t.Errorf("!feed 1 = %q, want %q", got, want)
+	}
+	want := fmt.Sprintf("\n%v: 2\n%v: 1\n", eribo.CmdTomato, eribo.CmdTieup)
+	if got := owner("!cmdstats"); got != want {
+		t.Errorf("!cmdstats = %q, want %q", got, want)
+	}
+	tests := []struct {
+		message string
+		want    string
 	}{
-		{[]string{"10", "0", "desc"}, 7, 10, []string{"0", "desc"}},
-		{[]string{"desc", "10", "0"}, 7, 10, []string{"desc", "0"}},
-		{[]string{"desc"}, 7, 7, []string{"desc"}},
-		{[]string{}, 0, 0, []string{}},
-		{nil, 7, 7, nil},
+		{"!cmdstats command 1", fmt.Sprintf("\n%v: 2\n", eribo.CmdTomato)},
+		{"!cmdstats day command=tomato", "\n2018-05-01: 2\n"},
+		{"!cmdstats player channel=room since=2018-05-01", "\nAlice: 3\n"},
+		{"!cmdstats hour until=2018-05-01", "\n"},
+		{"!cmdstats week", "unknown grouping \"week\"\nUsage: !cmdstats"},
+		{"!cmdstats command=nope", "unknown command \"nope\""},
+		{"!cmdstats arg", "counting by arg needs a command"},
+		{"!cmdstats since=yesterday", "since: "},
 	}
-
 	for _, tt := range tests {
-		n, args := argsPopAtoiDefault(tt.inArgs, tt.inDefault)
-		if got, want := n, tt.n; got != want {
-			t.Errorf("popArgs(%v) = %v, want %v", tt.inArgs, got, want)
-		}
-		if got, want := args, tt.outArgs; !reflect.DeepEqual(got, want) {
-			t.Errorf("\nhave: %v\nwant: %v", got, want)
+		if got := owner(tt.message); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s = %q, want prefix %q", tt.message, got, tt.want)
 		}
 	}
 }
+
+func TestRunConsole(t *testing.T) {
+	consolech := make(chan consoleCommand)
+	console := runConsole(consolech, time.Second)
+	go func() {
+		cmd := <-consolech
+		cmd.reply <- "ran " + cmd.command
+	}()
+	if got, want := console("!status"), "ran !status"; got != want {
+		t.Errorf("console = %q, want %q", got, want)
+	}
+
+	// Nobody serves the commands.
+	console = runConsole(consolech, 10*time.Millisecond)
+	if got, want := console("!status"), "the bot is busy, try again later"; got != want {
+		t.Errorf("console without the bot = %q, want %q", got, want)
+	}
+}
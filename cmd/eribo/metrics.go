@@ -0,0 +1,262 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/metrics"
+)
+
+var (
+	commandsHandled = metrics.NewCounter("eribo_commands_total", "Commands answered by command and channel, which is private for private messages.", "command", "channel")
+	commandDuration = metrics.NewHistogram("eribo_command_duration_seconds", "Time from receiving a command to sending its response by command.", nil, "command")
+	storeDuration   = metrics.NewHistogram("eribo_store_query_duration_seconds", "Store query latency by store method.", nil, "method")
+	storeErrors     = metrics.NewCounter("eribo_store_errors_total", "Store queries that failed by store method.", "method")
+)
+
+// observeCommand records a command answered in a channel, or in private if
+// channel is empty, that was received at start.
+func observeCommand(command, channel string, start time.Time) {
+	if channel == "" {
+		channel = "private"
+	}
+	commandsHandled.Inc(command, channel)
+	commandDuration.ObserveSince(start, command)
+}
+
+// registerChannelMetrics exposes the players of the channels the bot is in
+// in the registry r.
+func registerChannelMetrics(r *metrics.Registry, channelMap *eribo.ChannelMap) {
+	r.NewGaugeFunc("eribo_channel_players", "Players in each channel the bot is in by status.", func(set func(float64, ...string)) {
+		channelMap.ForEach(func(channel string, pm *eribo.PlayerMap) {
+			counts := make(map[string]int)
+			pm.ForEach(func(_ string, p *eribo.Player) {
+				counts[string(p.Status)]++
+			})
+			for status, n := range counts {
+				set(float64(n), channel, status)
+			}
+		})
+	}, "channel", "status")
+}
+
+// slowQuery is how long a store query takes before it is logged as slow.
+const slowQuery = time.Second
+
+// timedStore is a store that records the latency and errors of the queries
+// of the store it wraps and logs the ones that fail or are slow.
+type timedStore struct {
+	store eribo.Store
+	log   *slog.Logger
+}
+
+func (s timedStore) observe(method string, start time.Time, err error) {
+	d := time.Since(start)
+	storeDuration.Observe(d.Seconds(), method)
+	switch {
+	case err != nil:
+		storeErrors.Inc(method)
+		s.log.Warn("store query failed", "method", method, "duration", d, "err", err)
+	case d >= slowQuery:
+		s.log.Warn("slow store query", "method", method, "duration", d)
+	default:
+		s.log.Debug("store query", "method", method, "duration", d)
+	}
+}
+
+func (s timedStore) AddMessageWithImages(m *eribo.Message, images []*eribo.Image) error {
+	start := time.Now()
+	err := s.store.AddMessageWithImages(m, images)
+	s.observe("AddMessageWithImages", start, err)
+	return err
+}
+
+func (s timedStore) GetImages(limit, offset int, reverse, filterDone bool, contentType string) ([]*eribo.Image, error) {
+	start := time.Now()
+	images, err := s.store.GetImages(limit, offset, reverse, filterDone, contentType)
+	s.observe("GetImages", start, err)
+	return images, err
+}
+
+func (s timedStore) GetImage(id int64) (*eribo.Image, error) {
+	start := time.Now()
+	img, err := s.store.GetImage(id)
+	s.observe("GetImage", start, err)
+	return img, err
+}
+
+func (s timedStore) ListImages(q eribo.Query) ([]*eribo.Image, error) {
+	start := time.Now()
+	images, err := s.store.ListImages(q)
+	s.observe("ListImages", start, err)
+	return images, err
+}
+
+func (s timedStore) ToggleImageDone(id int64) error {
+	start := time.Now()
+	err := s.store.ToggleImageDone(id)
+	s.observe("ToggleImageDone", start, err)
+	return err
+}
+
+func (s timedStore) SetImageKuid(id int64, kuid int) error {
+	start := time.Now()
+	err := s.store.SetImageKuid(id, kuid)
+	s.observe("SetImageKuid", start, err)
+	return err
+}
+
+func (s timedStore) AddFeedback(f *eribo.Feedback) error {
+	start := time.Now()
+	err := s.store.AddFeedback(f)
+	s.observe("AddFeedback", start, err)
+	return err
+}
+
+func (s timedStore) GetAllFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	start := time.Now()
+	fb, err := s.store.GetAllFeedback(limit, offset)
+	s.observe("GetAllFeedback", start, err)
+	return fb, err
+}
+
+func (s timedStore) GetRecentFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	start := time.Now()
+	fb, err := s.store.GetRecentFeedback(limit, offset)
+	s.observe("GetRecentFeedback", start, err)
+	return fb, err
+}
+
+func (s timedStore) ListFeedback(q eribo.Query) ([]*eribo.Feedback, error) {
+	start := time.Now()
+	fb, err := s.store.ListFeedback(q)
+	s.observe("ListFeedback", start, err)
+	return fb, err
+}
+
+func (s timedStore) GetFeedback(id int64) (*eribo.Feedback, error) {
+	start := time.Now()
+	f, err := s.store.GetFeedback(id)
+	s.observe("GetFeedback", start, err)
+	return f, err
+}
+
+func (s timedStore) UpdateFeedback(f *eribo.Feedback) error {
+	start := time.Now()
+	err := s.store.UpdateFeedback(f)
+	s.observe("UpdateFeedback", start, err)
+	return err
+}
+
+func (s timedStore) AddFeedbackReply(r *eribo.FeedbackReply) error {
+	start := time.Now()
+	err := s.store.AddFeedbackReply(r)
+	s.observe("AddFeedbackReply", start, err)
+	return err
+}
+
+func (s timedStore) ListFeedbackReplies(feedbackID int64) ([]*eribo.FeedbackReply, error) {
+	start := time.Now()
+	replies, err := s.store.ListFeedbackReplies(feedbackID)
+	s.observe("ListFeedbackReplies", start, err)
+	return replies, err
+}
+
+func (s timedStore) ListUnsentReplies(player string) ([]*eribo.FeedbackReply, error) {
+	start := time.Now()
+	replies, err := s.store.ListUnsentReplies(player)
+	s.observe("ListUnsentReplies", start, err)
+	return replies, err
+}
+
+func (s timedStore) MarkReplySent(id int64, sent time.Time) error {
+	start := time.Now()
+	err := s.store.MarkReplySent(id, sent)
+	s.observe("MarkReplySent", start, err)
+	return err
+}
+
+func (s timedStore) AddCmdLog(l *eribo.CmdLog) error {
+	start := time.Now()
+	err := s.store.AddCmdLog(l)
+	s.observe("AddCmdLog", start, err)
+	return err
+}
+
+func (s timedStore) GetRecentCmdLogs(limit, offset int) ([]*eribo.CmdLog, error) {
+	start := time.Now()
+	logs, err := s.store.GetRecentCmdLogs(limit, offset)
+	s.observe("GetRecentCmdLogs", start, err)
+	return logs, err
+}
+
+func (s timedStore) ListCmdLogs(q eribo.Query) ([]*eribo.CmdLog, error) {
+	start := time.Now()
+	logs, err := s.store.ListCmdLogs(q)
+	s.observe("ListCmdLogs", start, err)
+	return logs, err
+}
+
+func (s timedStore) CmdStats() ([]*eribo.CmdStat, error) {
+	start := time.Now()
+	stats, err := s.store.CmdStats()
+	s.observe("CmdStats", start, err)
+	return stats, err
+}
+
+func (s timedStore) CmdUsage(q eribo.CmdUsageQuery) ([]*eribo.CmdCount, error) {
+	start := time.Now()
+	counts, err := s.store.CmdUsage(q)
+	s.observe("CmdUsage", start, err)
+	return counts, err
+}
+
+func (s timedStore) AddLothLog(l *eribo.LothLog) error {
+	start := time.Now()
+	err := s.store.AddLothLog(l)
+	s.observe("AddLothLog", start, err)
+	return err
+}
+
+func (s timedStore) GetRecentLothLogs(limit, offset int) ([]*eribo.LothLog, error) {
+	start := time.Now()
+	logs, err := s.store.GetRecentLothLogs(limit, offset)
+	s.observe("GetRecentLothLogs", start, err)
+	return logs, err
+}
+
+func (s timedStore) ListLothLogs(q eribo.Query) ([]*eribo.LothLog, error) {
+	start := time.Now()
+	logs, err := s.store.ListLothLogs(q)
+	s.observe("ListLothLogs", start, err)
+	return logs, err
+}
+
+func (s timedStore) DeleteBefore(table eribo.Table, before time.Time) (int64, error) {
+	start := time.Now()
+	n, err := s.store.DeleteBefore(table, before)
+	s.observe("DeleteBefore", start, err)
+	return n, err
+}
+
+func (s timedStore) ForgetPlayer(name string, audit *eribo.AuditLog) (*eribo.ForgetResult, error) {
+	start := time.Now()
+	r, err := s.store.ForgetPlayer(name, audit)
+	s.observe("ForgetPlayer", start, err)
+	return r, err
+}
+
+func (s timedStore) AddAuditLog(a *eribo.AuditLog) error {
+	start := time.Now()
+	err := s.store.AddAuditLog(a)
+	s.observe("AddAuditLog", start, err)
+	return err
+}
+
+func (s timedStore) ListAuditLogs(q eribo.Query) ([]*eribo.AuditLog, error) {
+	start := time.Now()
+	logs, err := s.store.ListAuditLogs(q)
+	s.observe("ListAuditLogs", start, err)
+	return logs, err
+}
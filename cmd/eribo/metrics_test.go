@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+	"github.com/kusubooru/eribo/logging"
+	"github.com/kusubooru/eribo/metrics"
+)
+
+// failingStore is a store whose feedback queries fail.
+type failingStore struct {
+	eribo.Store
+}
+
+func (failingStore) AddFeedback(f *eribo.Feedback) error { return errors.New("down") }
+
+func (failingStore) CmdStats() ([]*eribo.CmdStat, error) { return nil, nil }
+
+func TestTimedStore(t *testing.T) {
+	s := timedStore{failingStore{}, logging.Discard()}
+	errs, count := storeErrors.Value("AddFeedback"), storeDuration.Count("AddFeedback")
+	if err := s.AddFeedback(&eribo.Feedback{}); err == nil {
+		t.Fatal("AddFeedback expected error")
+	}
+	if got := storeErrors.Value("AddFeedback"); got != errs+1 {
+		t.Errorf("AddFeedback errors = %v, want %v", got, errs+1)
+	}
+	if got := storeDuration.Count("AddFeedback"); got != count+1 {
+		t.Errorf("AddFeedback observations = %v, want %v", got, count+1)
+	}
+	if _, err := s.CmdStats(); err != nil {
+		t.Fatal(err)
+	}
+	if got := storeErrors.Value("CmdStats"); got != 0 {
+		t.Errorf("CmdStats errors = %v, want 0", got)
+	}
+}
+
+func TestChannelMetrics(t *testing.T) {
+	channelMap := eribo.NewChannelMap()
+	channelMap.SetPlayer("Room", &eribo.Player{Name: "Alice", Status: flist.StatusOnline})
+	channelMap.SetPlayer("Room", &eribo.Player{Name: "Bob", Status: flist.StatusOnline})
+	channelMap.SetPlayer("Room", &eribo.Player{Name: "Carol", Status: flist.StatusAway})
+	r := metrics.NewRegistry()
+	registerChannelMetrics(r, channelMap)
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`eribo_channel_players{channel="Room",status="online"} 2`,
+		`eribo_channel_players{channel="Room",status="away"} 1`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestObserveCommand(t *testing.T) {
+	handled, count := commandsHandled.Value("!tomato", "private"), commandDuration.Count("!tomato")
+	observeCommand("!tomato", "", time.Now())
+	if got := commandsHandled.Value("!tomato", "private"); got != handled+1 {
+		t.Errorf("!tomato commands in private = %v, want %v", got, handled+1)
+	}
+	if got := commandDuration.Count("!tomato"); got != count+1 {
+		t.Errorf("!tomato observations = %v, want %v", got, count+1)
+	}
+}
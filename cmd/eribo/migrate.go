@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo/migrate"
+)
+
+const migrateUsage = `usage: eribo migrate [-datasource=<datasource>] [status | up | down | to <version>]
+
+  status        print the schema version and the migrations (default)
+  up            apply all pending migrations
+  down          roll back the last applied migration
+  to <version>  migrate up or down to a version, 0 rolls back everything`
+
+// runMigrate runs the migrate subcommand on the database of a data source.
+// The -datasource flag of the subcommand overrides the one of the bot.
+func runMigrate(w io.Writer, dataSource string, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { fmt.Fprintln(w, migrateUsage) }
+	fs.StringVar(&dataSource, "datasource", dataSource, "MySQL datasource, postgres:// URL, or sqlite: followed by the `file` of a SQLite database")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if dataSource == "" {
+		return fmt.Errorf("database datasource not provided\n%s", migrateUsage)
+	}
+
+	cmd := "status"
+	if fs.NArg() > 0 {
+		cmd = fs.Arg(0)
+	}
+	wantArgs := 1
+	if cmd == "to" {
+		wantArgs = 2
+	}
+	if fs.NArg() > wantArgs {
+		return fmt.Errorf("too many arguments for %s\n%s", cmd, migrateUsage)
+	}
+
+	db, err := openDatabase(dataSource)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	m := db.Migrator()
+
+	from, err := m.Version()
+	if err != nil {
+		return err
+	}
+	var to int
+	switch cmd {
+	case "status":
+		return migrateStatus(w, m)
+	case "up":
+		to = m.Latest()
+	case "down":
+		if from == 0 {
+			return fmt.Errorf("no migrations to roll back")
+		}
+		to = from - 1
+	case "to":
+		if fs.NArg() < 2 {
+			return fmt.Errorf("to needs a version\n%s", migrateUsage)
+		}
+		to, err = strconv.Atoi(fs.Arg(1))
+		if err != nil {
+			return fmt.Errorf("bad version %q: %v", fs.Arg(1), err)
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, migrateUsage)
+	}
+
+	if err := m.To(to); err != nil {
+		return err
+	}
+	if from == to {
+		fmt.Fprintf(w, "schema is at version %d\n", to)
+		return nil
+	}
+	fmt.Fprintf(w, "migrated schema from version %d to %d\n", from, to)
+	return nil
+}
+
+// migrateStatus prints the version of the database and the known migrations,
+// applied or pending, followed by any applied migrations that this version of
+// the bot does not know.
+func migrateStatus(w io.Writer, m *migrate.Migrator) error {
+	v, err := m.Version()
+	if err != nil {
+		return err
+	}
+	applied, err := m.Applied()
+	if err != nil {
+		return err
+	}
+	appliedAt := make(map[int]time.Time)
+	for _, a := range applied {
+		appliedAt[a.Version] = a.Applied
+	}
+
+	fmt.Fprintf(w, "schema version %d, latest %d\n", v, m.Latest())
+	for _, mig := range m.Migrations() {
+		status := "pending"
+		if at, ok := appliedAt[mig.Version]; ok {
+			status = "applied " + at.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%4d %s (%s)\n", mig.Version, mig.Name, status)
+	}
+	for _, a := range applied {
+		if a.Version > m.Latest() {
+			fmt.Fprintf(w, "%4d %s (applied %s, unknown to this version)\n", a.Version, a.Name, a.Applied.Format("2006-01-02 15:04:05"))
+		}
+	}
+	if v > m.Latest() {
+		return migrate.ErrNewerSchema
+	}
+	return nil
+}
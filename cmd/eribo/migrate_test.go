@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/eribo/eribo/migrate"
+	"github.com/kusubooru/eribo/eribo/sqlite"
+)
+
+var appliedAt = regexp.MustCompile(`applied \d{4}-\d\d-\d\d \d\d:\d\d:\d\d`)
+
+// sqliteMigrator returns the migrator of a new SQLite store, whose
+// migrations are the ones that migrate runs.
+func sqliteMigrator(t *testing.T) *migrate.Migrator {
+	db, err := sqlite.NewEriboStore(filepath.Join(t.TempDir(), "migrations.db"))
+	if err != nil {
+		t.Fatal("NewEriboStore failed:", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.Migrator()
+}
+
+func TestRunMigrate(t *testing.T) {
+	dataSource := "sqlite:" + filepath.Join(t.TempDir(), "eribo.db")
+	m := sqliteMigrator(t)
+	latest := m.Latest()
+	// status returns the output of migrate status when the schema is at
+	// version v.
+	status := func(v int) string {
+		out := fmt.Sprintf("schema version %d, latest %d\n", v, latest)
+		for _, mig := range m.Migrations() {
+			applied := "pending"
+			if mig.Version <= v {
+				applied = "applied"
+			}
+			out += fmt.Sprintf("%4d %s (%s)\n", mig.Version, mig.Name, applied)
+		}
+		return out
+	}
+
+	type test struct {
+		args    []string
+		want    string
+		wantErr bool
+	}
+	tests := []test{
+		{nil, status(0), false},
+		{[]string{"up"}, fmt.Sprintf("migrated schema from version 0 to %d\n", latest), false},
+		{[]string{"up"}, fmt.Sprintf("schema is at version %d\n", latest), false},
+		{[]string{"status"}, status(latest), false},
+	}
+	for v := latest; v > 0; v-- {
+		tests = append(tests, test{[]string{"down"}, fmt.Sprintf("migrated schema from version %d to %d\n", v, v-1), false})
+	}
+	tests = append(tests, []test{
+		{[]string{"down"}, "", true},
+		{[]string{"to", "1"}, "migrated schema from version 0 to 1\n", false},
+		{[]string{"to", strconv.Itoa(latest + 1)}, "", true},
+		{[]string{"to"}, "", true},
+		{[]string{"to", "one"}, "", true},
+		{[]string{"up", "1"}, "", true},
+		{[]string{"sideways"}, "", true},
+	}...)
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := runMigrate(&buf, dataSource, tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("migrate %q succeeded, want error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("migrate %q failed: %v", tt.args, err)
+			continue
+		}
+		if got := appliedAt.ReplaceAllString(buf.String(), "applied"); got != tt.want {
+			t.Errorf("migrate %q output\nhave: %q\nwant: %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunMigrate_datasourceFlag(t *testing.T) {
+	dataSource := "sqlite:" + filepath.Join(t.TempDir(), "eribo.db")
+	var buf bytes.Buffer
+	if err := runMigrate(&buf, "", []string{"-datasource", dataSource, "up"}); err != nil {
+		t.Fatalf("migrate -datasource up failed: %v", err)
+	}
+	if err := runMigrate(&buf, "", []string{"up"}); err == nil {
+		t.Error("migrate without a datasource succeeded")
+	}
+}
+
+func TestRunMigrate_newerSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "eribo.db")
+	db, err := sqlite.NewEriboStore(file)
+	if err != nil {
+		t.Fatal("NewEriboStore failed:", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations(version, name) VALUES (99, 'from the future')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	var buf bytes.Buffer
+	err = runMigrate(&buf, "sqlite:"+file, nil)
+	if !errors.Is(err, migrate.ErrNewerSchema) {
+		t.Errorf("migrate status on newer schema = %v, want %v", err, migrate.ErrNewerSchema)
+	}
+	if want := "  99 from the future (applied, unknown to this version)\n"; !strings.HasSuffix(appliedAt.ReplaceAllString(buf.String(), "applied"), want) {
+		t.Errorf("migrate status output %q does not end with %q", buf.String(), want)
+	}
+	if err := runMigrate(&buf, "sqlite:"+file, []string{"down"}); !errors.Is(err, migrate.ErrNewerSchema) {
+		t.Errorf("migrate down on newer schema = %v, want %v", err, migrate.ErrNewerSchema)
+	}
+	if _, err := openStore("sqlite:" + file); !errors.Is(err, migrate.ErrNewerSchema) {
+		t.Errorf("openStore on newer schema = %v, want %v", err, migrate.ErrNewerSchema)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// retention is how long the records of each table are kept. The records of
+// tables without a retention are kept forever.
+type retention map[eribo.Table]time.Duration
+
+// parseRetention parses comma separated table=duration pairs, e.g.
+// messages=90d,cmd_logs=180d. Durations are days like 90d or Go durations
+// like 36h.
+func parseRetention(s string) (retention, error) {
+	r := make(retention)
+	if strings.TrimSpace(s) == "" {
+		return r, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not table=duration", pair)
+		}
+		t, err := eribo.ParseTable(name)
+		if err != nil {
+			return nil, err
+		}
+		d, err := parseDays(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%s: retention %s is not positive", name, value)
+		}
+		r[t] = d
+	}
+	return r, nil
+}
+
+// parseDays parses a number of days like 90d or a Go duration.
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatDays formats a duration of whole days like 90d and others as Go
+// durations.
+func formatDays(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func (r retention) String() string {
+	var pairs []string
+	for _, t := range eribo.Tables {
+		if d, ok := r[t]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", t, formatDays(d)))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// purgeEvery is how often the records older than their retention are
+// deleted.
+const purgeEvery = time.Hour
+
+// purge deletes the records that are older than their retention at now and
+// adds an audit log of what it deleted. It returns the detail of the audit
+// log, which is empty when there was nothing to delete.
+func purge(store eribo.Store, r retention, now time.Time) (string, error) {
+	var deleted []string
+	var errs []error
+	for _, t := range eribo.Tables {
+		d, ok := r[t]
+		if !ok {
+			continue
+		}
+		n, err := store.DeleteBefore(t, now.Add(-d))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purging %s: %v", t, err))
+			continue
+		}
+		if n != 0 {
+			deleted = append(deleted, fmt.Sprintf("%d %s older than %s", n, t, formatDays(d)))
+		}
+	}
+	if len(deleted) == 0 {
+		return "", errors.Join(errs...)
+	}
+	detail := "deleted " + strings.Join(deleted, ", ")
+	if err := store.AddAuditLog(&eribo.AuditLog{Action: eribo.AuditPurge, Detail: detail}); err != nil {
+		errs = append(errs, fmt.Errorf("adding audit log: %v", err))
+	}
+	return detail, errors.Join(errs...)
+}
+
+// purgeLoop purges the store when it starts and then every interval. It is
+// meant to run in its own goroutine.
+func purgeLoop(logger *slog.Logger, store eribo.Store, r retention, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		detail, err := purge(store, r, time.Now())
+		if err != nil {
+			logger.Error("purging expired records failed", "retention", r.String(), "err", err)
+		}
+		if detail != "" {
+			logger.Info("purged expired records", "detail", detail)
+		}
+		<-ticker.C
+	}
+}
+
+const forgetMeWarning = `This deletes your feedback and command logs, removes your name and words from the messages with images you posted and removes you from the loth logs. It cannot be undone. Send [b]!forgetme confirm[/b] to proceed.`
+
+// forgetMe answers !forgetme in private. Without confirm it explains what
+// will be deleted. With confirm it forgets the player and tells the owner.
+// The command is not logged since that would keep a record of the player.
+func forgetMe(c *flist.Client, logger *slog.Logger, store eribo.Store, pri *flist.PRI, owner string) error {
+	cmd, args := eribo.ParseCommand(pri.Message)
+	if cmd != eribo.CmdForgetMe {
+		return nil
+	}
+	start := time.Now()
+	a := eribo.NewArgSet(cmd.String())
+	confirm := a.Bool("confirm", "delete your records")
+	msg := parseArgs(a, args)
+	var forgetErr error
+	switch {
+	case msg != "":
+	case !*confirm:
+		msg = forgetMeWarning
+	default:
+		audit, err := forgetPlayer(store, pri.Character)
+		if err != nil {
+			forgetErr = err
+			msg = "Something went wrong while forgetting you. Please try again later."
+			break
+		}
+		msg = fmt.Sprintf("You have been forgotten: %s.", audit.Detail)
+		note := &flist.PRI{Recipient: owner, Message: fmt.Sprintf("%s asked to be forgotten: %s", pri.Character, audit.Detail)}
+		if err := c.SendPRI(note); err != nil {
+			logger.Error("telling owner failed", "command", cmd.String(), "player", pri.Character, "err", err)
+		}
+	}
+
+	resp := &flist.PRI{Recipient: pri.Character, Message: msg}
+	if err := c.SendPRI(resp); err != nil {
+		return errors.Join(forgetErr, fmt.Errorf("error sending %v response: %v", cmd, err))
+	}
+	observeCommand(cmd.String(), "", start)
+	return forgetErr
+}
+
+// forgetPlayer deletes or anonymizes the records of a player along with
+// adding the audit log of the deletion, which keeps the name of the player as
+// the record of their request.
+func forgetPlayer(store eribo.Store, player string) (*eribo.AuditLog, error) {
+	a := &eribo.AuditLog{Action: eribo.AuditForgetMe, Actor: player}
+	if _, err := store.ForgetPlayer(player, a); err != nil {
+		return nil, fmt.Errorf("error forgetting player: %v", err)
+	}
+	return a, nil
+}
+
+// tableNames returns the names of the tables that can have a retention.
+func tableNames() []string {
+	names := make([]string, len(eribo.Tables))
+	for i, t := range eribo.Tables {
+		names[i] = string(t)
+	}
+	return names
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+)
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		in   string
+		want retention
+	}{
+		{"", retention{}},
+		{"messages=90d", retention{eribo.TableMessages: 90 * 24 * time.Hour}},
+		{" cmd_logs=36h, loth_logs=1d ", retention{eribo.TableCmdLogs: 36 * time.Hour, eribo.TableLothLogs: 24 * time.Hour}},
+	}
+	for _, tt := range tests {
+		got, err := parseRetention(tt.in)
+		if err != nil {
+			t.Errorf("parseRetention(%q) returned err: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRetention(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"messages", "images=90d", "messages=ninety", "messages=xd", "messages=0d", "feedback=-1h"} {
+		if _, err := parseRetention(in); err == nil {
+			t.Errorf("parseRetention(%q) succeeded", in)
+		}
+	}
+}
+
+func TestRetentionString(t *testing.T) {
+	r := retention{eribo.TableLothLogs: 36 * time.Hour, eribo.TableMessages: 90 * 24 * time.Hour}
+	if got, want := r.String(), "messages=90d,loth_logs=36h0m0s"; got != want {
+		t.Errorf("retention.String() = %q, want %q", got, want)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store := memstore.New()
+	now := time.Date(2018, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		created := now.AddDate(0, 0, -i*5)
+		if err := store.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo", Created: created}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddFeedback(&eribo.Feedback{Player: "foo", Message: "hi", Created: created}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := retention{eribo.TableCmdLogs: 7 * 24 * time.Hour, eribo.TableFeedback: 30 * 24 * time.Hour}
+
+	detail, err := purge(store, r, now)
+	if err != nil {
+		t.Fatal("purge failed:", err)
+	}
+	if want := "deleted 1 cmd_logs older than 7d"; detail != want {
+		t.Errorf("purge detail = %q, want %q", detail, want)
+	}
+	detail, err = purge(store, r, now)
+	if err != nil {
+		t.Fatal("purge again failed:", err)
+	}
+	if detail != "" {
+		t.Errorf("purge again detail = %q, want none", detail)
+	}
+
+	logs, err := store.ListAuditLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Action != eribo.AuditPurge || logs[0].Actor != "" {
+		t.Errorf("audit logs after purges = %v, want one purge by the bot", logs)
+	}
+}
+
+func TestForgetPlayer(t *testing.T) {
+	store := memstore.New()
+	if err := store.AddFeedback(&eribo.Feedback{Player: "Foo", Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdAstro, Player: "Foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err := forgetPlayer(store, "Foo")
+	if err != nil {
+		t.Fatal("forgetPlayer failed:", err)
+	}
+	want := eribo.ForgetResult{Feedback: 1, CmdLogs: 1}.String()
+	if audit.Action != eribo.AuditForgetMe || audit.Actor != "Foo" || audit.Detail != want {
+		t.Errorf("forgetPlayer audit log = %v, want %s by Foo - %s", audit, eribo.AuditForgetMe, want)
+	}
+	logs, err := store.ListAuditLogs(eribo.Query{Player: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Detail != want {
+		t.Errorf("audit logs of Foo = %v, want one with detail %q", logs, want)
+	}
+	feedback, err := store.ListFeedback(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedback) != 0 {
+		t.Errorf("feedback after forgetPlayer = %v, want none", feedback)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type tableDiff struct {
+	Old            string     `json:"old"`
+	New            string     `json:"new"`
+	OldTotalWeight int        `json:"old_total_weight"`
+	NewTotalWeight int        `json:"new_total_weight"`
+	Items          []itemDiff `json:"items"`
+	Qualities      []itemDiff `json:"qualities"`
+}
+
+// itemDiff compares an item, or all the items of a quality, between two
+// table definitions.
+type itemDiff struct {
+	Name       string  `json:"name"`
+	Change     string  `json:"change"`
+	OldQuality string  `json:"old_quality,omitempty"`
+	NewQuality string  `json:"new_quality,omitempty"`
+	OldWeight  int     `json:"old_weight"`
+	NewWeight  int     `json:"new_weight"`
+	OldPr      float64 `json:"old_pr"`
+	NewPr      float64 `json:"new_pr"`
+}
+
+const (
+	changeNone    = "unchanged"
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeWeight  = "changed"
+)
+
+func diffTables(old, new tableDef) *tableDiff {
+	d := &tableDiff{
+		Old:            old.Name,
+		New:            new.Name,
+		OldTotalWeight: old.totalWeight(),
+		NewTotalWeight: new.totalWeight(),
+	}
+	// Items are matched by name and quality first so that items that share
+	// a name stay apart, and then by name alone so that an item whose
+	// quality changed is reported as changed.
+	matched := make([]int, len(old.Items))
+	for i := range matched {
+		matched[i] = -1
+	}
+	taken := make([]bool, len(new.Items))
+	match := func(same func(o, n itemDef) bool) {
+		for i, it := range old.Items {
+			if matched[i] != -1 {
+				continue
+			}
+			for j, n := range new.Items {
+				if !taken[j] && same(it, n) {
+					matched[i], taken[j] = j, true
+					break
+				}
+			}
+		}
+	}
+	match(func(o, n itemDef) bool { return o.Name == n.Name && o.Quality == n.Quality })
+	match(func(o, n itemDef) bool { return o.Name == n.Name })
+
+	for i, it := range old.Items {
+		id := itemDiff{Name: it.Name, Change: changeRemoved, OldQuality: it.Quality, OldWeight: it.Weight, OldPr: old.pr(i)}
+		if j := matched[i]; j != -1 {
+			n := new.Items[j]
+			id.NewQuality, id.NewWeight, id.NewPr = n.Quality, n.Weight, new.pr(j)
+			id.Change = changeNone
+			if n.Weight != it.Weight || n.Quality != it.Quality {
+				id.Change = changeWeight
+			}
+		}
+		d.Items = append(d.Items, id)
+	}
+	for j, n := range new.Items {
+		if taken[j] {
+			continue
+		}
+		d.Items = append(d.Items, itemDiff{Name: n.Name, Change: changeAdded, NewQuality: n.Quality, NewWeight: n.Weight, NewPr: new.pr(j)})
+	}
+	d.Qualities = diffQualities(old, new)
+	return d
+}
+
+func diffQualities(old, new tableDef) []itemDiff {
+	var order []string
+	m := make(map[string]*itemDiff)
+	get := func(q string) *itemDiff {
+		if _, ok := m[q]; !ok {
+			m[q] = &itemDiff{Name: q}
+			order = append(order, q)
+		}
+		return m[q]
+	}
+	for i, it := range old.Items {
+		q := get(it.Quality)
+		q.OldWeight += it.Weight
+		q.OldPr += old.pr(i)
+	}
+	for j, n := range new.Items {
+		q := get(n.Quality)
+		q.NewWeight += n.Weight
+		q.NewPr += new.pr(j)
+	}
+	qualities := make([]itemDiff, 0, len(order))
+	for _, name := range order {
+		q := m[name]
+		switch {
+		case q.OldWeight == 0 && q.NewWeight != 0:
+			q.Change = changeAdded
+		case q.OldWeight != 0 && q.NewWeight == 0:
+			q.Change = changeRemoved
+		case q.OldWeight != q.NewWeight:
+			q.Change = changeWeight
+		default:
+			q.Change = changeNone
+		}
+		qualities = append(qualities, *q)
+	}
+	return qualities
+}
+
+func (d *tableDiff) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Old: %s (total weight %d)\nNew: %s (total weight %d)\n\n", d.Old, d.OldTotalWeight, d.New, d.NewTotalWeight)
+	for _, it := range d.Items {
+		if it.Change == changeNone && it.OldPr == it.NewPr {
+			continue
+		}
+		fmt.Fprintf(&b, "%9s %35s %5d -> %5d  %7.3f%% -> %7.3f%%\n", it.Change, it.Name, it.OldWeight, it.NewWeight, it.OldPr*100, it.NewPr*100)
+	}
+	fmt.Fprintln(&b)
+	for _, q := range d.Qualities {
+		fmt.Fprintf(&b, "%9s %35s %5d -> %5d  %7.3f%% -> %7.3f%%\n", q.Change, q.Name, q.OldWeight, q.NewWeight, q.OldPr*100, q.NewPr*100)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (d *tableDiff) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(d)
+}
+
+// writeCSV writes a row for each item and then for each quality. The kind
+// column tells them apart.
+func (d *tableDiff) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"kind", "name", "change", "old_quality", "new_quality", "old_weight", "new_weight", "old_pr", "new_pr"})
+	write := func(kind string, it itemDiff) {
+		cw.Write([]string{
+			kind,
+			it.Name,
+			it.Change,
+			it.OldQuality,
+			it.NewQuality,
+			strconv.Itoa(it.OldWeight),
+			strconv.Itoa(it.NewWeight),
+			formatFloat(it.OldPr),
+			formatFloat(it.NewPr),
+		})
+	}
+	for _, it := range d.Items {
+		write("item", it)
+	}
+	for _, q := range d.Qualities {
+		write("quality", q)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -25,28 +26,70 @@ func run() error {
 		tieSim  = flag.Bool("tie", false, "")
 		tiedSim = flag.Bool("tied", false, "")
 		tieHard = flag.Bool("tiehard", false, "")
+		report  = flag.Bool("report", false, "print a statistical report of the table selected by -tk, -tie or -def")
+		diff    = flag.Bool("diff", false, "compare two table definition `files` e.g. -diff old.json new.json")
+		dump    = flag.Bool("dump", false, "print the definition of the table selected by -tk or -tie")
+		defFile = flag.String("def", "", "table definition `file` to use for -report")
+		format  = flag.String("format", "text", "report output format: text, json or csv")
+		trials  = flag.Int("trials", 100, "number of times to exhaust the table with decreasing weight for -report")
+		seed    = flag.Int64("seed", time.Now().UnixNano(), "random seed to use for -report")
 	)
 	flag.Parse()
 
+	toolType := ""
+	if *tieHard {
+		toolType = "hard"
+	}
+
+	if *diff {
+		if flag.NArg() != 2 {
+			return fmt.Errorf("-diff needs two table definition files")
+		}
+		old, err := readTableDef(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		new, err := readTableDef(flag.Arg(1))
+		if err != nil {
+			return err
+		}
+		d := diffTables(old, new)
+		return writeFormat(*format, d.writeText, d.writeJSON, d.writeCSV)
+	}
+
+	if *report || *dump {
+		var def tableDef
+		switch {
+		case *defFile != "":
+			var err error
+			if def, err = readTableDef(*defFile); err != nil {
+				return err
+			}
+		case *tkSim:
+			def = tktoolsDef()
+		case *tieSim:
+			def = tietoolsDef(toolType)
+		default:
+			return fmt.Errorf("select a table with -tk, -tie or -def")
+		}
+		if *dump {
+			return writeTableDef(os.Stdout, def)
+		}
+		r := newReport(def, *rolls, *trials, *seed)
+		return writeFormat(*format, r.writeText, r.writeJSON, r.writeCSV)
+	}
+
 	if *tkSim {
 		fmt.Println(simTktools(*rolls))
 		return nil
 	}
 
 	if *tieSim {
-		toolType := ""
-		if *tieHard {
-			toolType = "hard"
-		}
 		fmt.Println(simTietools(*rolls, toolType))
 		return nil
 	}
 
 	if *tiedSim {
-		toolType := ""
-		if *tieHard {
-			toolType = "hard"
-		}
 		simTietoolsDecreasedWeight(*rolls, toolType)
 		return nil
 	}
@@ -54,8 +97,20 @@ func run() error {
 	return nil
 }
 
+func writeFormat(format string, text, json, csv func(io.Writer) error) error {
+	switch format {
+	case "text":
+		return text(os.Stdout)
+	case "json":
+		return json(os.Stdout)
+	case "csv":
+		return csv(os.Stdout)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
 func simTktools(rolls int) string {
-	table := &loot.Table{}
+	table := &loot.Table[rp.Tktool]{}
 	for _, t := range rp.Tktools() {
 		table.Add(t, t.Weight())
 	}
@@ -69,7 +124,7 @@ func simTktools(rolls int) string {
 }
 
 func simTietools(rolls int, toolType string) string {
-	table := &loot.Table{}
+	table := &loot.Table[rp.Tietool]{}
 
 	for _, t := range rp.Tietools(toolType) {
 		table.Add(t, t.Quality.Weight())
@@ -84,21 +139,16 @@ func simTietools(rolls int, toolType string) string {
 }
 
 func simTietoolsDecreasedWeight(rolls int, toolType string) {
-	table := &loot.Table{}
+	table := &loot.Table[rp.Tietool]{}
 
 	for _, t := range rp.Tietools(toolType) {
 		table.Add(t, t.Quality.Weight())
 	}
 
 	for i := 0; i < rolls; i++ {
-		seed := time.Now().UnixNano()
-		_, item := table.RollDecreaseWeight(seed)
-		if item == nil {
-			break
-		}
-		tool, ok := item.(rp.Tietool)
+		_, tool, ok := table.RollDecreaseWeight()
 		if !ok {
-			fmt.Println("item was not tietool")
+			break
 		}
 		fmt.Printf("Rolled %9s, %35s, total weight = %d\n", tool.Quality, tool.Name(), table.TotalWeight())
 	}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kusubooru/eribo/loot"
+)
+
+type report struct {
+	Table     string       `json:"table"`
+	Rolls     int          `json:"rolls"`
+	Trials    int          `json:"trials"`
+	Seed      int64        `json:"seed"`
+	Items     []itemReport `json:"items"`
+	ChiSquare float64      `json:"chi_square"`
+	DF        int          `json:"df"`
+	PValue    float64      `json:"p_value"`
+	// FirstLegendary is the expected number of rolls until the first
+	// legendary when the weights never change.
+	FirstLegendary float64 `json:"first_legendary"`
+	// FirstLegendaryDecrease describes the rolls until the first legendary
+	// under RollDecreaseWeight.
+	FirstLegendaryDecrease *rollStats `json:"first_legendary_decrease,omitempty"`
+	// Exhausted describes the rolls until the table is exhausted under
+	// RollDecreaseWeight.
+	Exhausted *rollStats `json:"exhausted,omitempty"`
+}
+
+type itemReport struct {
+	Name       string  `json:"name"`
+	Quality    string  `json:"quality"`
+	Weight     int     `json:"weight"`
+	ExpectedPr float64 `json:"expected_pr"`
+	ObservedPr float64 `json:"observed_pr"`
+	Expected   float64 `json:"expected"`
+	Observed   int     `json:"observed"`
+}
+
+type rollStats struct {
+	Mean      float64  `json:"mean"`
+	Median    int      `json:"median"`
+	P90       int      `json:"p90"`
+	Min       int      `json:"min"`
+	Max       int      `json:"max"`
+	Histogram []bucket `json:"histogram"`
+}
+
+type bucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+const legendary = "legendary"
+
+func newReport(def tableDef, rolls, trials int, seed int64) *report {
+	r := &report{Table: def.Name, Rolls: rolls, Trials: trials, Seed: seed}
+	rnd := rand.New(rand.NewSource(seed))
+
+	table := loot.NewTableSource(simDrops(def), rand.NewSource(rnd.Int63()))
+	drops, pr := table.Sim(rolls)
+	var observed []int
+	var expected []float64
+	legoWeight := 0
+	for i, it := range def.Items {
+		ir := itemReport{
+			Name:       it.Name,
+			Quality:    it.Quality,
+			Weight:     it.Weight,
+			ExpectedPr: def.pr(i),
+			ObservedPr: pr[i],
+			Expected:   def.pr(i) * float64(rolls),
+			Observed:   drops[i],
+		}
+		r.Items = append(r.Items, ir)
+		observed = append(observed, ir.Observed)
+		expected = append(expected, ir.Expected)
+		if it.Quality == legendary && it.Weight > 0 {
+			legoWeight += it.Weight
+		}
+	}
+	r.ChiSquare, r.DF = chiSquare(observed, expected)
+	r.PValue = chiSquarePValue(r.ChiSquare, r.DF)
+	if legoWeight != 0 {
+		r.FirstLegendary = float64(def.totalWeight()) / float64(legoWeight)
+	}
+
+	if trials > 0 {
+		var firsts, exhausted []int
+		for k := 0; k < trials; k++ {
+			first, total := simDecrease(def, rand.NewSource(rnd.Int63()))
+			if first != 0 {
+				firsts = append(firsts, first)
+			}
+			exhausted = append(exhausted, total)
+		}
+		r.FirstLegendaryDecrease = newRollStats(firsts)
+		r.Exhausted = newRollStats(exhausted)
+	}
+	return r
+}
+
+func simDrops(def tableDef) []loot.Drop[simItem] {
+	drops := make([]loot.Drop[simItem], len(def.Items))
+	for i, it := range def.Items {
+		drops[i] = loot.Drop[simItem]{Item: simItem{index: i, name: it.Name}, Weight: it.Weight}
+	}
+	return drops
+}
+
+// simDecrease rolls a table with RollDecreaseWeight until it is exhausted. It
+// returns the roll that dropped the first legendary, or 0 if no legendary
+// dropped, and the number of rolls until the table was exhausted.
+func simDecrease(def tableDef, src rand.Source) (first, total int) {
+	table := loot.NewTableSource(simDrops(def), src)
+	for {
+		_, item, ok := table.RollDecreaseWeight()
+		if !ok {
+			return first, total
+		}
+		total++
+		if first == 0 && def.Items[item.index].Quality == legendary {
+			first = total
+		}
+	}
+}
+
+func newRollStats(values []int) *rollStats {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+	sort.Ints(sorted)
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	return &rollStats{
+		Mean:      float64(sum) / float64(len(sorted)),
+		Median:    sorted[len(sorted)/2],
+		P90:       sorted[(len(sorted)*9)/10],
+		Min:       sorted[0],
+		Max:       sorted[len(sorted)-1],
+		Histogram: histogram(sorted, 10),
+	}
+}
+
+// histogram splits sorted values into n buckets of equal width.
+func histogram(sorted []int, n int) []bucket {
+	if len(sorted) == 0 {
+		return nil
+	}
+	min, max := sorted[0], sorted[len(sorted)-1]
+	width := (max - min + n) / n
+	if width < 1 {
+		width = 1
+	}
+	var buckets []bucket
+	for from := min; from <= max; from += width {
+		buckets = append(buckets, bucket{From: from, To: from + width - 1})
+	}
+	for _, v := range sorted {
+		buckets[(v-min)/width].Count++
+	}
+	return buckets
+}
+
+// chiSquare returns Pearson's chi-square statistic and its degrees of
+// freedom. Categories that are not expected to appear are ignored.
+func chiSquare(observed []int, expected []float64) (float64, int) {
+	x2 := 0.0
+	k := 0
+	for i := range observed {
+		if expected[i] <= 0 {
+			continue
+		}
+		d := float64(observed[i]) - expected[i]
+		x2 += d * d / expected[i]
+		k++
+	}
+	if k < 2 {
+		return x2, 0
+	}
+	return x2, k - 1
+}
+
+// chiSquarePValue returns the probability of a chi-square statistic at least
+// as large as x2 with df degrees of freedom.
+func chiSquarePValue(x2 float64, df int) float64 {
+	if df <= 0 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, x2/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x).
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	if x < a+1 {
+		// Series representation of P(a, x).
+		sum, del, ap := 1/a, 1/a, a
+		for n := 0; n < 1000; n++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lg)
+	}
+	// Continued fraction representation of Q(a, x).
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+func (r *report) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Table: %s, rolls: %d, seed: %d\n\n", r.Table, r.Rolls, r.Seed)
+	maxObserved := 0
+	for _, it := range r.Items {
+		if it.Observed > maxObserved {
+			maxObserved = it.Observed
+		}
+	}
+	for _, it := range r.Items {
+		fmt.Fprintf(&b, "%9s %35s %5d  expected %7.3f%%  observed %7.3f%%  %s\n",
+			it.Quality, it.Name, it.Weight, it.ExpectedPr*100, it.ObservedPr*100, bar(it.Observed, maxObserved, 30))
+	}
+	fmt.Fprintf(&b, "\nChi-square: %.3f, degrees of freedom: %d, p-value: %.4f\n", r.ChiSquare, r.DF, r.PValue)
+	if r.FirstLegendary != 0 {
+		fmt.Fprintf(&b, "Expected rolls until first legendary: %.1f\n", r.FirstLegendary)
+	}
+	if r.FirstLegendaryDecrease != nil {
+		fmt.Fprintf(&b, "\nRolls until first legendary with decreasing weight (%d trials):\n", r.Trials)
+		writeRollStats(&b, r.FirstLegendaryDecrease)
+	}
+	if r.Exhausted != nil {
+		fmt.Fprintf(&b, "\nRolls until table is exhausted with decreasing weight (%d trials):\n", r.Trials)
+		writeRollStats(&b, r.Exhausted)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRollStats(b *strings.Builder, s *rollStats) {
+	fmt.Fprintf(b, "mean: %.1f, median: %d, p90: %d, min: %d, max: %d\n", s.Mean, s.Median, s.P90, s.Min, s.Max)
+	maxCount := 0
+	for _, bk := range s.Histogram {
+		if bk.Count > maxCount {
+			maxCount = bk.Count
+		}
+	}
+	for _, bk := range s.Histogram {
+		fmt.Fprintf(b, "%6d - %6d %5d %s\n", bk.From, bk.To, bk.Count, bar(bk.Count, maxCount, 30))
+	}
+}
+
+func bar(n, max, width int) string {
+	if max == 0 {
+		return ""
+	}
+	return strings.Repeat("#", n*width/max)
+}
+
+func (r *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(r)
+}
+
+// writeCSV writes a row for each item followed by the summary of the report
+// as stat and value rows.
+func (r *report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "quality", "weight", "expected_pr", "observed_pr", "expected", "observed"})
+	for _, it := range r.Items {
+		cw.Write([]string{
+			it.Name,
+			it.Quality,
+			strconv.Itoa(it.Weight),
+			formatFloat(it.ExpectedPr),
+			formatFloat(it.ObservedPr),
+			formatFloat(it.Expected),
+			strconv.Itoa(it.Observed),
+		})
+	}
+	cw.Write([]string{"stat", "value"})
+	cw.Write([]string{"chi_square", formatFloat(r.ChiSquare)})
+	cw.Write([]string{"df", strconv.Itoa(r.DF)})
+	cw.Write([]string{"p_value", formatFloat(r.PValue)})
+	if r.FirstLegendary != 0 {
+		cw.Write([]string{"first_legendary", formatFloat(r.FirstLegendary)})
+	}
+	writeRollStatsCSV(cw, "first_legendary_decrease", r.FirstLegendaryDecrease)
+	writeRollStatsCSV(cw, "exhausted", r.Exhausted)
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeRollStatsCSV(cw *csv.Writer, prefix string, s *rollStats) {
+	if s == nil {
+		return
+	}
+	cw.Write([]string{prefix + "_mean", formatFloat(s.Mean)})
+	cw.Write([]string{prefix + "_median", strconv.Itoa(s.Median)})
+	cw.Write([]string{prefix + "_p90", strconv.Itoa(s.P90)})
+	cw.Write([]string{prefix + "_min", strconv.Itoa(s.Min)})
+	cw.Write([]string{prefix + "_max", strconv.Itoa(s.Max)})
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
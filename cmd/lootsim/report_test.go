@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChiSquarePValue(t *testing.T) {
+	var tests = []struct {
+		x2   float64
+		df   int
+		want float64
+	}{
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{18.307, 10, 0.05},
+		{0, 5, 1},
+		{43.773, 30, 0.05},
+	}
+	for _, tt := range tests {
+		if got := chiSquarePValue(tt.x2, tt.df); math.Abs(got-tt.want) > 0.0005 {
+			t.Errorf("chiSquarePValue(%v, %v) = %v, want %v", tt.x2, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestChiSquare(t *testing.T) {
+	x2, df := chiSquare([]int{10, 30, 0}, []float64{20, 20, 0})
+	if x2 != 10 || df != 1 {
+		t.Errorf("chiSquare = %v, %v, want 10, 1", x2, df)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	got := histogram([]int{1, 2, 2, 5, 10}, 3)
+	want := []bucket{{1, 4, 3}, {5, 8, 1}, {9, 12, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("histogram = %v, want %v", got, want)
+	}
+}
+
+func TestDiffTables(t *testing.T) {
+	old := tableDef{Name: "old", Items: []itemDef{
+		{Name: "a", Quality: "common", Weight: 3},
+		{Name: "b", Quality: "legendary", Weight: 1},
+	}}
+	new := tableDef{Name: "new", Items: []itemDef{
+		{Name: "a", Quality: "common", Weight: 1},
+		{Name: "c", Quality: "rare", Weight: 1},
+	}}
+	d := diffTables(old, new)
+	want := []itemDiff{
+		{Name: "a", Change: changeWeight, OldQuality: "common", NewQuality: "common", OldWeight: 3, NewWeight: 1, OldPr: 0.75, NewPr: 0.5},
+		{Name: "b", Change: changeRemoved, OldQuality: "legendary", OldWeight: 1, OldPr: 0.25},
+		{Name: "c", Change: changeAdded, NewQuality: "rare", NewWeight: 1, NewPr: 0.5},
+	}
+	if !reflect.DeepEqual(d.Items, want) {
+		t.Errorf("diffTables items = \nhave: %+v\nwant: %+v", d.Items, want)
+	}
+}
+
+func TestDiffTables_sameName(t *testing.T) {
+	old := tableDef{Name: "old", Items: []itemDef{
+		{Name: "rope", Quality: "common", Weight: 3},
+		{Name: "rope", Quality: "epic", Weight: 1},
+	}}
+	new := tableDef{Name: "new", Items: []itemDef{
+		{Name: "rope", Quality: "epic", Weight: 2},
+		{Name: "rope", Quality: "rare", Weight: 2},
+	}}
+	d := diffTables(old, new)
+	want := []itemDiff{
+		{Name: "rope", Change: changeWeight, OldQuality: "common", NewQuality: "rare", OldWeight: 3, NewWeight: 2, OldPr: 0.75, NewPr: 0.5},
+		{Name: "rope", Change: changeWeight, OldQuality: "epic", NewQuality: "epic", OldWeight: 1, NewWeight: 2, OldPr: 0.25, NewPr: 0.5},
+	}
+	if !reflect.DeepEqual(d.Items, want) {
+		t.Errorf("diffTables items = \nhave: %+v\nwant: %+v", d.Items, want)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	def := tableDef{Name: "test", Items: []itemDef{
+		{Name: "a", Quality: "common", Weight: 9},
+		{Name: "b", Quality: legendary, Weight: 1},
+	}}
+	r := newReport(def, 1000, 10, 1)
+	if got, want := r.FirstLegendary, 10.0; got != want {
+		t.Errorf("FirstLegendary = %v, want %v", got, want)
+	}
+	if got, want := r.Exhausted.Mean, 10.0; got != want {
+		t.Errorf("Exhausted.Mean = %v, want %v", got, want)
+	}
+	if r.FirstLegendaryDecrease == nil || r.FirstLegendaryDecrease.Max > 10 {
+		t.Errorf("FirstLegendaryDecrease = %+v, want max at most 10", r.FirstLegendaryDecrease)
+	}
+}
+
+func TestReportWriteCSV(t *testing.T) {
+	def := tableDef{Name: "test", Items: []itemDef{
+		{Name: "a", Quality: "common", Weight: 9},
+		{Name: "b", Quality: legendary, Weight: 1},
+	}}
+	var buf bytes.Buffer
+	if err := newReport(def, 100, 10, 1).writeCSV(&buf); err != nil {
+		t.Fatalf("writeCSV returned err: %v", err)
+	}
+	for _, stat := range []string{"\nchi_square,", "\np_value,", "\nfirst_legendary,10\n", "\nfirst_legendary_decrease_mean,", "\nexhausted_mean,10\n"} {
+		if !strings.Contains(buf.String(), stat) {
+			t.Errorf("writeCSV = %q, want it to contain %q", buf.String(), stat)
+		}
+	}
+}
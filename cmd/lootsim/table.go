@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kusubooru/eribo/rp"
+)
+
+// tableDef is the definition of a loot table as it is stored in a file so that
+// different versions of a table can be reviewed and compared.
+type tableDef struct {
+	Name  string    `json:"name"`
+	Items []itemDef `json:"items"`
+}
+
+type itemDef struct {
+	Name    string `json:"name"`
+	Quality string `json:"quality"`
+	Weight  int    `json:"weight"`
+}
+
+// simItem is the item used when simulating a table definition. It keeps the
+// index of the item in the definition and implements Name so that items with
+// the same name lose weight together like the tools do.
+type simItem struct {
+	index int
+	name  string
+}
+
+func (it simItem) Name() string { return it.name }
+
+func (d tableDef) totalWeight() int {
+	total := 0
+	for _, it := range d.Items {
+		if it.Weight > 0 {
+			total += it.Weight
+		}
+	}
+	return total
+}
+
+func (d tableDef) pr(i int) float64 {
+	total := d.totalWeight()
+	if total == 0 || d.Items[i].Weight <= 0 {
+		return 0
+	}
+	return float64(d.Items[i].Weight) / float64(total)
+}
+
+func tktoolsDef() tableDef {
+	def := tableDef{Name: "tktools"}
+	for _, t := range rp.Tktools() {
+		def.Items = append(def.Items, itemDef{Name: t.Name(), Quality: t.Quality.String(), Weight: t.Weight()})
+	}
+	return def
+}
+
+func tietoolsDef(toolType string) tableDef {
+	def := tableDef{Name: "tietools"}
+	if toolType != "" {
+		def.Name = "tietools " + toolType
+	}
+	for _, t := range rp.Tietools(toolType) {
+		def.Items = append(def.Items, itemDef{Name: t.Name(), Quality: t.Quality.String(), Weight: t.Quality.Weight()})
+	}
+	return def
+}
+
+func readTableDef(filename string) (tableDef, error) {
+	var def tableDef
+	f, err := os.Open(filename)
+	if err != nil {
+		return def, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&def); err != nil {
+		return def, fmt.Errorf("decoding table definition %s: %v", filename, err)
+	}
+	if def.Name == "" {
+		def.Name = filename
+	}
+	return def, nil
+}
+
+func writeTableDef(w io.Writer, def tableDef) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(def)
+}
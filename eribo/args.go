@@ -0,0 +1,359 @@
+package eribo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrHelp is returned by ArgSet.Parse when the arguments are --help.
+var ErrHelp = errors.New("help requested")
+
+// SplitArgs splits the arguments of a command into words separated by
+// whitespace. Text in double quotes is kept in one word together with its
+// quotes, e.g. `"Bob Doe" feet` is `"Bob Doe"` and `feet`, so that commands
+// which take names can tell an exact name from an abbreviated one. An
+// unterminated quote runs until the end of s.
+func SplitArgs(s string) []string {
+	args := []string{}
+	var b strings.Builder
+	quoted, inWord := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+			b.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if inWord {
+				args = append(args, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			inWord = true
+			b.WriteRune(r)
+		}
+	}
+	if inWord {
+		args = append(args, b.String())
+	}
+	return args
+}
+
+// Unquote removes the double quotes around an argument, if it starts and ends
+// with one. Quotes inside it are kept.
+func Unquote(arg string) string {
+	if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+		return arg[1 : len(arg)-1]
+	}
+	return arg
+}
+
+type argKind int
+
+const (
+	argBool argKind = iota
+	argInt
+	argString
+)
+
+type argDef struct {
+	name     string
+	usage    string
+	kind     argKind
+	optional bool
+	def      string
+	set      func(string) error
+}
+
+func (a *argDef) parse(s string) error {
+	if err := a.set(s); err != nil {
+		return fmt.Errorf("%s: %q %v", a.name, s, err)
+	}
+	return nil
+}
+
+// ArgSet defines the arguments of a command, in the spirit of flag.FlagSet.
+//
+// Options are given as name=value or --name value, in any position before
+// the text argument. Bool options are also given by their bare name, e.g.
+// "!images desc 20". The rest of the arguments are positional and are
+// assigned in the order they were defined, with the text argument, if any,
+// taking whatever remains as it was typed, options included, once it has a
+// word. Positional arguments can also be given by name like options, e.g.
+// "!images offset=10". The argument "--" ends the options and a quoted word
+// is never an option, so "!tieup -- hard" and `!tieup "hard"` both name a
+// player called hard.
+type ArgSet struct {
+	name       string
+	options    []*argDef
+	positional []*argDef
+	text       *argDef
+}
+
+// NewArgSet returns an empty argument set for the named command.
+func NewArgSet(name string) *ArgSet {
+	return &ArgSet{name: name}
+}
+
+func (s *ArgSet) option(a *argDef) {
+	a.optional = true
+	s.options = append(s.options, a)
+}
+
+func (s *ArgSet) arg(a *argDef) {
+	if s.text != nil {
+		panic(fmt.Sprintf("%s: argument %s defined after text argument %s", s.name, a.name, s.text.name))
+	}
+	if !a.optional && len(s.positional) != 0 && s.positional[len(s.positional)-1].optional {
+		panic(fmt.Sprintf("%s: required argument %s defined after optional argument", s.name, a.name))
+	}
+	s.positional = append(s.positional, a)
+}
+
+// Bool defines a bool option which is set by its name.
+func (s *ArgSet) Bool(name, usage string) *bool {
+	p := new(bool)
+	s.option(&argDef{name: name, usage: usage, kind: argBool, set: func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("is not true or false")
+		}
+		*p = b
+		return nil
+	}})
+	return p
+}
+
+// IntOption defines an int option with a default value.
+func (s *ArgSet) IntOption(name string, value int, usage string) *int {
+	p := &value
+	s.option(&argDef{name: name, usage: usage, kind: argInt, def: strconv.Itoa(value), set: setInt(p)})
+	return p
+}
+
+// StringOption defines a string option with a default value.
+func (s *ArgSet) StringOption(name, value, usage string) *string {
+	p := &value
+	s.option(&argDef{name: name, usage: usage, kind: argString, def: value, set: setString(p)})
+	return p
+}
+
+// Int defines a required int argument.
+func (s *ArgSet) Int(name, usage string) *int {
+	p := new(int)
+	s.arg(&argDef{name: name, usage: usage, kind: argInt, set: setInt(p)})
+	return p
+}
+
+// OptionalInt defines an int argument with a default value.
+func (s *ArgSet) OptionalInt(name string, value int, usage string) *int {
+	p := &value
+	s.arg(&argDef{name: name, usage: usage, kind: argInt, optional: true, def: strconv.Itoa(value), set: setInt(p)})
+	return p
+}
+
+// String defines a required string argument.
+func (s *ArgSet) String(name, usage string) *string {
+	p := new(string)
+	s.arg(&argDef{name: name, usage: usage, kind: argString, set: setString(p)})
+	return p
+}
+
+// OptionalString defines a string argument with a default value.
+func (s *ArgSet) OptionalString(name, value, usage string) *string {
+	p := &value
+	s.arg(&argDef{name: name, usage: usage, kind: argString, optional: true, def: value, set: setString(p)})
+	return p
+}
+
+// Text defines an optional argument that takes the rest of the arguments as
+// they were typed, quotes included. It must be the last argument defined.
+func (s *ArgSet) Text(name, usage string) *string {
+	p := new(string)
+	s.text = &argDef{name: name, usage: usage, kind: argString, optional: true, set: func(v string) error {
+		*p = v
+		return nil
+	}}
+	return p
+}
+
+func setInt(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(Unquote(v))
+		if err != nil {
+			return errors.New("is not a number")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func setString(p *string) func(string) error {
+	return func(v string) error {
+		*p = Unquote(v)
+		return nil
+	}
+}
+
+// lookup returns the option or positional argument with the name.
+func (s *ArgSet) lookup(name string) *argDef {
+	for _, a := range s.options {
+		if a.name == name {
+			return a
+		}
+	}
+	for _, a := range s.positional {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// unnamed returns how many positional arguments were not given by name.
+func (s *ArgSet) unnamed(named map[*argDef]bool) int {
+	n := 0
+	for _, a := range s.positional {
+		if !named[a] {
+			n++
+		}
+	}
+	return n
+}
+
+// Parse parses the arguments of the command, as returned by ParseCommand.
+func (s *ArgSet) Parse(args []string) error {
+	var positional []string
+	named := make(map[*argDef]bool)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if s.text != nil && len(positional) > s.unnamed(named) {
+			// The text argument has started and takes the rest.
+			positional = append(positional, args[i:]...)
+			break
+		}
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if arg == "--help" {
+			return ErrHelp
+		}
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			o := s.lookup(name)
+			if o == nil {
+				return fmt.Errorf("%s: unknown option %s", s.name, arg)
+			}
+			switch {
+			case hasValue:
+			case o.kind == argBool:
+				value = "true"
+			case i+1 < len(args):
+				i++
+				value = args[i]
+			default:
+				return fmt.Errorf("%s: option %s needs a value", s.name, arg)
+			}
+			if err := o.parse(value); err != nil {
+				return fmt.Errorf("%s: %v", s.name, err)
+			}
+			named[o] = true
+			continue
+		}
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			if o := s.lookup(name); o != nil {
+				if err := o.parse(value); err != nil {
+					return fmt.Errorf("%s: %v", s.name, err)
+				}
+				named[o] = true
+				continue
+			}
+		}
+		if o := s.lookup(arg); o != nil && o.kind == argBool {
+			if err := o.parse("true"); err != nil {
+				return fmt.Errorf("%s: %v", s.name, err)
+			}
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	for _, a := range s.positional {
+		if named[a] {
+			continue
+		}
+		if len(positional) == 0 {
+			if !a.optional {
+				return fmt.Errorf("%s: missing %s", s.name, a.name)
+			}
+			continue
+		}
+		if err := a.parse(positional[0]); err != nil {
+			return fmt.Errorf("%s: %v", s.name, err)
+		}
+		positional = positional[1:]
+	}
+	if len(positional) == 0 {
+		return nil
+	}
+	if s.text == nil {
+		return fmt.Errorf("%s: unexpected %s", s.name, strings.Join(positional, " "))
+	}
+	return s.text.parse(strings.Join(positional, " "))
+}
+
+// Usage returns a one line summary of the arguments of the command, e.g.
+// "Usage: !images [desc] [all] [limit] [offset]".
+func (s *ArgSet) Usage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s", s.name)
+	for _, o := range s.options {
+		if o.kind == argBool {
+			fmt.Fprintf(&b, " [%s]", o.name)
+			continue
+		}
+		fmt.Fprintf(&b, " [%s=%s]", o.name, o.kindName())
+	}
+	for _, a := range s.positional {
+		if a.optional {
+			fmt.Fprintf(&b, " [%s]", a.name)
+			continue
+		}
+		fmt.Fprintf(&b, " <%s>", a.name)
+	}
+	if s.text != nil {
+		fmt.Fprintf(&b, " [%s...]", s.text.name)
+	}
+	return b.String()
+}
+
+// Help returns the usage followed by a line for each argument.
+func (s *ArgSet) Help() string {
+	var b strings.Builder
+	b.WriteString(s.Usage())
+	args := append(append([]*argDef{}, s.options...), s.positional...)
+	if s.text != nil {
+		args = append(args, s.text)
+	}
+	for _, a := range args {
+		fmt.Fprintf(&b, "\n  %s: %s", a.name, a.usage)
+		if a.def != "" {
+			fmt.Fprintf(&b, " (default %s)", a.def)
+		}
+	}
+	return b.String()
+}
+
+func (a *argDef) kindName() string {
+	switch a.kind {
+	case argInt:
+		return "number"
+	case argBool:
+		return "bool"
+	}
+	return "text"
+}
@@ -0,0 +1,121 @@
+package eribo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{"  a  b\tc ", []string{"a", "b", "c"}},
+		{`"Bob Doe" feet`, []string{`"Bob Doe"`, "feet"}},
+		{`"Bob Doe", Ann`, []string{`"Bob Doe",`, "Ann"}},
+		{`name="Bob Doe"`, []string{`name="Bob Doe"`}},
+		{`don't "stop`, []string{"don't", `"stop`}},
+	}
+	for _, tt := range tests {
+		if got := SplitArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArgSetParse(t *testing.T) {
+	var tests = []struct {
+		args []string
+		want string
+		err  string
+	}{
+		{[]string{"3"}, `3 "" 0 false 10 ""`, ""},
+		{[]string{"3", `"Bob Doe"`, "7"}, `3 "Bob Doe" 7 false 10 ""`, ""},
+		{[]string{"hard", "3", "x", "count=5"}, `3 "x" 0 true 5 ""`, ""},
+		{[]string{"--count", "5", "--hard", "3"}, `3 "" 0 true 5 ""`, ""},
+		{[]string{"--hard=false", "id=4", "name=x"}, `4 "x" 0 false 10 ""`, ""},
+		{[]string{"3", "x", "7", "and", `"more text"`}, `3 "x" 7 false 10 "and \"more text\""`, ""},
+		{[]string{"--", "3", "hard"}, `3 "hard" 0 false 10 ""`, ""},
+		{[]string{"3", `"hard"`}, `3 "hard" 0 false 10 ""`, ""},
+		{[]string{"3", "x", "7", "more", "hard", "--count", "5"}, `3 "x" 7 false 10 "more hard --count 5"`, ""},
+		{[]string{"hard", "3", "x", "7", "more", "--help"}, `3 "x" 7 true 10 "more --help"`, ""},
+		{[]string{"3", `"say "hi""`}, `3 "say \"hi\"" 0 false 10 ""`, ""},
+		{[]string{}, "", "!cmd: missing id"},
+		{[]string{"three"}, "", `!cmd: id: "three" is not a number`},
+		{[]string{"3", "count=x"}, "", `!cmd: count: "x" is not a number`},
+		{[]string{"3", "--count"}, "", "!cmd: option --count needs a value"},
+		{[]string{"3", "--color"}, "", "!cmd: unknown option --color"},
+		{[]string{"3", "hard=maybe"}, "", `!cmd: hard: "maybe" is not true or false`},
+	}
+	for _, tt := range tests {
+		a := NewArgSet("!cmd")
+		hard := a.Bool("hard", "")
+		count := a.IntOption("count", 10, "")
+		id := a.Int("id", "")
+		name := a.OptionalString("name", "", "")
+		n := a.OptionalInt("n", 0, "")
+		text := a.Text("text", "")
+		err := a.Parse(tt.args)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.args, err)
+			continue
+		}
+		got := fmt.Sprintf("%d %q %d %v %d %q", *id, *name, *n, *hard, *count, *text)
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want string
+	}{
+		{`"Bob Doe"`, "Bob Doe"},
+		{`"say "hi""`, `say "hi"`},
+		{`"Bob Doe",`, `"Bob Doe",`},
+		{`don't`, `don't`},
+		{`"`, `"`},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		if got := Unquote(tt.in); got != tt.want {
+			t.Errorf("Unquote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArgSetUsage(t *testing.T) {
+	a := NewArgSet("!cmd")
+	a.Bool("hard", "use the hard tools")
+	a.StringOption("color", "red", "the color")
+	a.Int("id", "the id")
+	a.OptionalInt("limit", 10, "how many")
+	a.Text("message", "the message")
+
+	if got, want := a.Usage(), "Usage: !cmd [hard] [color=text] <id> [limit] [message...]"; got != want {
+		t.Errorf("Usage = %q, want %q", got, want)
+	}
+	want := `Usage: !cmd [hard] [color=text] <id> [limit] [message...]
+  hard: use the hard tools
+  color: the color (default red)
+  id: the id
+  limit: how many (default 10)
+  message: the message`
+	if got := a.Help(); got != want {
+		t.Errorf("Help =\nhave: %s\nwant: %s", got, want)
+	}
+	if err := a.Parse([]string{"1", "--help"}); !errors.Is(err, ErrHelp) {
+		t.Errorf("Parse with --help error = %v, want %v", err, ErrHelp)
+	}
+}
@@ -0,0 +1,104 @@
+package eribo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CmdGrouping is what the uses of commands are counted by.
+type CmdGrouping string
+
+const (
+	ByCommand CmdGrouping = "command"
+	ByChannel CmdGrouping = "channel"
+	ByPlayer  CmdGrouping = "player"
+	// ByDay counts the uses of each UTC day, keyed like 2018-05-01.
+	ByDay CmdGrouping = "day"
+	// ByHour counts the uses of each UTC hour of the day, keyed 00 to 23.
+	ByHour CmdGrouping = "hour"
+	// ByArg counts the lowercase words of the arguments, e.g. the tieup
+	// tags or ticklizer filters. It needs a command.
+	ByArg CmdGrouping = "arg"
+)
+
+// ErrArgNeedsCommand is returned when the arguments are counted without
+// selecting a command. The arguments are read from every selected log, so
+// the logs of all the commands would be too many, and the words of different
+// commands mean little together anyway.
+var ErrArgNeedsCommand = errors.New("counting by arg needs a command")
+
+// CmdGroupings are the groupings of command uses.
+var CmdGroupings = []CmdGrouping{ByCommand, ByChannel, ByPlayer, ByDay, ByHour, ByArg}
+
+// ParseCmdGrouping returns the grouping with a name.
+func ParseCmdGrouping(name string) (CmdGrouping, error) {
+	for _, g := range CmdGroupings {
+		if string(g) == strings.ToLower(name) {
+			return g, nil
+		}
+	}
+	return "", fmt.Errorf("unknown grouping %q", name)
+}
+
+// Histogram reports whether the counts of the grouping are ordered by key
+// instead of by uses.
+func (g CmdGrouping) Histogram() bool {
+	return g == ByDay || g == ByHour
+}
+
+// CmdUsageQuery selects the command logs whose uses are counted and how they
+// are grouped.
+type CmdUsageQuery struct {
+	// Query selects the logs by player, channel, command and time. Its Limit is the
+	// most counts returned, or all of them if it is 0, and BeforeID is
+	// ignored. Counting by arg needs its Command.
+	Query
+	By CmdGrouping
+}
+
+// CmdCount is the number of uses of the commands with a key, e.g. the uses in
+// a channel or on a day.
+type CmdCount struct {
+	Key  string
+	Uses int
+}
+
+func (c CmdCount) String() string {
+	return fmt.Sprintf("%s: %d", c.Key, c.Uses)
+}
+
+// SortCmdCounts orders counts like the stores return them: histograms by key
+// and the others most used first and by key when the uses are equal. It
+// returns at most limit counts, or all of them if limit is 0.
+func SortCmdCounts(counts []*CmdCount, by CmdGrouping, limit int) []*CmdCount {
+	sort.Slice(counts, func(i, j int) bool {
+		if !by.Histogram() && counts[i].Uses != counts[j].Uses {
+			return counts[i].Uses > counts[j].Uses
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
+
+// CountArgs counts the lowercase words of the arguments of command logs, most
+// used first, and returns at most limit counts, or all of them if limit is 0.
+// Every store selects the arguments and counts them with it, since the
+// databases would split the text into words in different ways.
+func CountArgs(args []string, limit int) []*CmdCount {
+	uses := make(map[string]int)
+	for _, a := range args {
+		for _, w := range strings.Fields(a) {
+			uses[strings.ToLower(w)]++
+		}
+	}
+	counts := []*CmdCount{}
+	for w, n := range uses {
+		counts = append(counts, &CmdCount{Key: w, Uses: n})
+	}
+	return SortCmdCounts(counts, ByArg, limit)
+}
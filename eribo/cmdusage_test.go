@@ -0,0 +1,49 @@
+package eribo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCmdGrouping(t *testing.T) {
+	for _, g := range CmdGroupings {
+		if got, err := ParseCmdGrouping(string(g)); err != nil || got != g {
+			t.Errorf("ParseCmdGrouping(%q) = %q, %v", g, got, err)
+		}
+	}
+	if got, err := ParseCmdGrouping("Day"); err != nil || got != ByDay {
+		t.Errorf("ParseCmdGrouping(%q) = %q, %v, want %q", "Day", got, err, ByDay)
+	}
+	if _, err := ParseCmdGrouping("week"); err == nil {
+		t.Errorf("ParseCmdGrouping(%q) succeeded", "week")
+	}
+}
+
+func TestSortCmdCounts(t *testing.T) {
+	counts := func() []*CmdCount {
+		return []*CmdCount{{"b", 1}, {"c", 2}, {"a", 1}}
+	}
+	var tests = []struct {
+		by    CmdGrouping
+		limit int
+		want  []*CmdCount
+	}{
+		{ByPlayer, 0, []*CmdCount{{"c", 2}, {"a", 1}, {"b", 1}}},
+		{ByPlayer, 2, []*CmdCount{{"c", 2}, {"a", 1}}},
+		{ByHour, 0, []*CmdCount{{"a", 1}, {"b", 1}, {"c", 2}}},
+		{ByDay, 5, []*CmdCount{{"a", 1}, {"b", 1}, {"c", 2}}},
+	}
+	for _, tt := range tests {
+		if got := SortCmdCounts(counts(), tt.by, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortCmdCounts(%s, %d) = %v, want %v", tt.by, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestCountArgs(t *testing.T) {
+	got := CountArgs([]string{"Bob feet", "", "bob  ARMS feet"}, 0)
+	want := []*CmdCount{{"bob", 2}, {"feet", 2}, {"arms", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CountArgs = %v, want %v", got, want)
+	}
+}
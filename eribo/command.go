@@ -23,6 +23,8 @@ const (
 	CmdTicklizer
 	CmdAdvice
 	CmdAstro
+	CmdForgetMe
+	CmdMyFeedback
 )
 
 func (c Command) String() string {
@@ -55,6 +57,10 @@ func (c Command) String() string {
 		return "!advice"
 	case CmdAstro:
 		return "!astro"
+	case CmdForgetMe:
+		return "!forgetme"
+	case CmdMyFeedback:
+		return "!myfeedback"
 	}
 }
 
@@ -88,12 +94,18 @@ func makeCommand(s string) Command {
 		return CmdAdvice
 	case "!astro":
 		return CmdAstro
+	case "!forgetme":
+		return CmdForgetMe
+	case "!myfeedback":
+		return CmdMyFeedback
 	}
 }
 
+// ParseCommand returns the command of a message and its arguments as split by
+// SplitArgs.
 func ParseCommand(s string) (cmd Command, args []string) {
 	args = []string{}
-	f := strings.Fields(s)
+	f := SplitArgs(s)
 	if len(f) == 0 {
 		return CmdUnknown, args
 	}
@@ -104,9 +116,11 @@ func ParseCommand(s string) (cmd Command, args []string) {
 	return cmd, args
 }
 
+// ParseCustomCommand is like ParseCommand for commands that are not a
+// Command, such as the owner commands.
 func ParseCustomCommand(s string) (cmd string, args []string) {
 	args = []string{}
-	f := strings.Fields(s)
+	f := SplitArgs(s)
 	if len(f) == 0 {
 		return
 	}
@@ -18,6 +18,7 @@ var parseCommandTests = []struct {
 	{"!tomato			1 2		3", CmdTomato, []string{"1", "2", "3"}},
 	{" !tomato", CmdTomato, []string{}},
 	{"foo !tomato", CmdUnknown, []string{"!tomato"}},
+	{`!tieup "Bob Doe", Ann feet`, CmdTieup, []string{`"Bob Doe",`, "Ann", "feet"}},
 }
 
 func TestParseCommand(t *testing.T) {
@@ -45,6 +46,7 @@ var parseCustomCommandTests = []struct {
 	{"!tomato			1 2		3", "!tomato", []string{"1", "2", "3"}},
 	{" !tomato", "!tomato", []string{}},
 	{"foo !tomato", "foo", []string{"!tomato"}},
+	{`!status "away  for now" 'ok'`, "!status", []string{`"away  for now"`, "'ok'"}},
 }
 
 func TestParseCustomCommand(t *testing.T) {
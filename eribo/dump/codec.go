@@ -0,0 +1,341 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// writers writes the records of each table to its file.
+type writers struct {
+	dir    string
+	format Format
+	files  map[string]*tableWriter
+}
+
+type tableWriter struct {
+	f      *os.File
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	header bool
+}
+
+func newWriters(dir string, format Format) (*writers, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	w := &writers{dir: dir, format: format, files: make(map[string]*tableWriter)}
+	for _, t := range Tables {
+		f, err := os.Create(filepath.Join(dir, t+"."+string(format)))
+		if err != nil {
+			w.close()
+			return nil, err
+		}
+		tw := &tableWriter{f: f, buf: bufio.NewWriter(f)}
+		tw.csv = csv.NewWriter(tw.buf)
+		w.files[t] = tw
+	}
+	return w, nil
+}
+
+func (w *writers) write(table string, record interface{}) error {
+	tw := w.files[table]
+	if w.format == JSONL {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = tw.buf.Write(append(b, '\n'))
+		return err
+	}
+	if !tw.header {
+		tw.header = true
+		if err := tw.csv.Write(csvHeader(reflect.TypeOf(record))); err != nil {
+			return err
+		}
+	}
+	row, err := csvRow(record)
+	if err != nil {
+		return err
+	}
+	return tw.csv.Write(row)
+}
+
+// close flushes and closes the files. A CSV file of a table without records
+// still gets its header.
+func (w *writers) close() error {
+	var errs []error
+	for t, tw := range w.files {
+		if w.format == CSV && !tw.header {
+			tw.csv.Write(csvHeader(recordType(t)))
+		}
+		tw.csv.Flush()
+		errs = append(errs, tw.csv.Error(), tw.buf.Flush(), tw.f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func recordType(table string) reflect.Type {
+	switch table {
+	case "messages":
+		return reflect.TypeOf(message{})
+	case "images":
+		return reflect.TypeOf(image{})
+	case "feedback":
+		return reflect.TypeOf(feedback{})
+	case "feedback_replies":
+		return reflect.TypeOf(feedbackReply{})
+	case "cmd_logs":
+		return reflect.TypeOf(cmdLog{})
+	case "loth_logs":
+		return reflect.TypeOf(lothLog{})
+	case "audit_logs":
+		return reflect.TypeOf(auditLog{})
+	}
+	panic("dump: unknown table " + table)
+}
+
+// detectFormat returns the format of the files of an export.
+func detectFormat(dir string) (Format, error) {
+	var found []Format
+	for _, f := range []Format{JSONL, CSV} {
+		matches, err := filepath.Glob(filepath.Join(dir, "*."+string(f)))
+		if err != nil {
+			return "", err
+		}
+		for _, m := range matches {
+			if isTableFile(filepath.Base(m), f) {
+				found = append(found, f)
+				break
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no export files in %s", dir)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("both jsonl and csv export files in %s", dir)
+}
+
+func isTableFile(name string, f Format) bool {
+	for _, t := range Tables {
+		if name == t+"."+string(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableReader reads the records of a table file one at a time in any order.
+// It keeps the offsets of the records in the file but not the records.
+type tableReader[T any] struct {
+	f      *os.File
+	name   string
+	format Format
+	// offsets are the offsets of the records followed by the end of the
+	// last one.
+	offsets []int64
+}
+
+// openTable opens the file of a table and reads it once to check that its
+// records are valid, calling check with each of them in file order. A
+// missing file has no records.
+func openTable[T any](dir, table string, format Format, check func(i int, rec T) error) (*tableReader[T], error) {
+	t := &tableReader[T]{name: filepath.Join(dir, table+"."+string(format)), format: format}
+	f, err := os.Open(t.name)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.f = f
+	if format == JSONL {
+		err = t.indexJSONL(check)
+	} else {
+		err = t.indexCSV(check)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// count returns the number of records.
+func (t *tableReader[T]) count() int {
+	if len(t.offsets) == 0 {
+		return 0
+	}
+	return len(t.offsets) - 1
+}
+
+// read returns the record at index i.
+func (t *tableReader[T]) read(i int) (T, error) {
+	var rec T
+	b := make([]byte, t.offsets[i+1]-t.offsets[i])
+	if _, err := t.f.ReadAt(b, t.offsets[i]); err != nil {
+		return rec, fmt.Errorf("%s: %v", t.name, err)
+	}
+	var err error
+	if t.format == JSONL {
+		err = json.Unmarshal(b, &rec)
+	} else {
+		var row []string
+		if row, err = csv.NewReader(bytes.NewReader(b)).Read(); err == nil {
+			err = parseCSVRow(row, &rec)
+		}
+	}
+	if err != nil {
+		return rec, fmt.Errorf("%s: record %d: %v", t.name, i+1, err)
+	}
+	return rec, nil
+}
+
+func (t *tableReader[T]) close() error {
+	if t.f == nil {
+		return nil
+	}
+	return t.f.Close()
+}
+
+func (t *tableReader[T]) indexJSONL(check func(int, T) error) error {
+	r := bufio.NewReader(t.f)
+	var off int64
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if len(b) > 0 && len(bytes.TrimSpace(b)) > 0 {
+			var rec T
+			if err := json.Unmarshal(b, &rec); err != nil {
+				return fmt.Errorf("%s:%d: %v", t.name, line, err)
+			}
+			if err := check(len(t.offsets), rec); err != nil {
+				return fmt.Errorf("%s:%d: %v", t.name, line, err)
+			}
+			t.offsets = append(t.offsets, off)
+		}
+		off += int64(len(b))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.name, err)
+		}
+	}
+	if len(t.offsets) > 0 {
+		t.offsets = append(t.offsets, off)
+	}
+	return nil
+}
+
+func (t *tableReader[T]) indexCSV(check func(int, T) error) error {
+	cr := csv.NewReader(bufio.NewReader(t.f))
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.name, err)
+	}
+	want := csvHeader(reflect.TypeOf(*new(T)))
+	if !reflect.DeepEqual(header, want) {
+		return fmt.Errorf("%s: header %q, want %q", t.name, header, want)
+	}
+	for {
+		off := cr.InputOffset()
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.name, err)
+		}
+		line, _ := cr.FieldPos(0)
+		var rec T
+		if err := parseCSVRow(row, &rec); err != nil {
+			return fmt.Errorf("%s:%d: %v", t.name, line, err)
+		}
+		if err := check(len(t.offsets), rec); err != nil {
+			return fmt.Errorf("%s:%d: %v", t.name, line, err)
+		}
+		t.offsets = append(t.offsets, off)
+	}
+	if len(t.offsets) > 0 {
+		t.offsets = append(t.offsets, cr.InputOffset())
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvHeader returns the JSON names of the fields of a record.
+func csvHeader(t reflect.Type) []string {
+	header := make([]string, t.NumField())
+	for i := range header {
+		header[i] = strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+	}
+	return header
+}
+
+// csvRow returns the fields of a record as text: strings as they are, times
+// in RFC 3339 and the rest, e.g. numbers, booleans and targets, as JSON.
+func csvRow(record interface{}) ([]string, error) {
+	v := reflect.ValueOf(record)
+	row := make([]string, v.NumField())
+	for i := range row {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			row[i] = field.String()
+		case field.Type() == timeType:
+			row[i] = field.Interface().(time.Time).Format(time.RFC3339Nano)
+		default:
+			b, err := json.Marshal(field.Interface())
+			if err != nil {
+				return nil, err
+			}
+			row[i] = string(b)
+		}
+	}
+	return row, nil
+}
+
+// parseCSVRow sets the fields of the record that rec points to from a row
+// written by csvRow.
+func parseCSVRow(row []string, rec interface{}) error {
+	v := reflect.ValueOf(rec).Elem()
+	if len(row) != v.NumField() {
+		return fmt.Errorf("%d fields, want %d", len(row), v.NumField())
+	}
+	for i, s := range row {
+		field := v.Field(i)
+		name := csvHeader(v.Type())[i]
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(s)
+		case field.Type() == timeType:
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			field.Set(reflect.ValueOf(t.UTC()))
+		default:
+			if err := json.Unmarshal([]byte(s), field.Addr().Interface()); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
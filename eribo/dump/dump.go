@@ -0,0 +1,631 @@
+// Package dump exports the records of an eribo.Store to files and imports
+// them into another store, which may use a different backend.
+//
+// An export is a directory with a file per table, e.g. images.jsonl or
+// images.csv. Records are written newest first as the store lists them and
+// keep their IDs, while an import reads the files from the end, a record at a
+// time, and stores them oldest first with the new IDs of the destination
+// store. Images are imported with their messages and replies with their
+// feedback so that they refer to the new IDs.
+//
+// Images whose URL the store already has are skipped like when they are
+// posted again, and so are other records that the store has, with the same
+// fields and created at about the same time. Importing an export again, e.g.
+// after an import that failed halfway, only adds what is missing.
+package dump
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// Format is the format of the files of an export.
+type Format string
+
+const (
+	// JSONL writes a JSON object per line.
+	JSONL Format = "jsonl"
+	// CSV writes a header line with the field names followed by a line per
+	// record. Targets of loth logs are written as JSON.
+	CSV Format = "csv"
+)
+
+// ParseFormat returns the format of a name, jsonl or csv.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case JSONL, CSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, want jsonl or csv", name)
+}
+
+// Tables are the tables of an export in the order they are exported and
+// imported.
+var Tables = []string{"messages", "images", "feedback", "feedback_replies", "cmd_logs", "loth_logs", "audit_logs"}
+
+// Filter selects the records created in [Since, Until). A zero time does not
+// limit the range on its side.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Result is the number of records of a table that were exported or imported.
+// Skipped counts the records that the destination store already had,
+// including messages whose images it had.
+type Result struct {
+	Table   string
+	Records int
+	Skipped int
+}
+
+func (r Result) String() string {
+	if r.Skipped != 0 {
+		return fmt.Sprintf("%s: %d (%d skipped)", r.Table, r.Records, r.Skipped)
+	}
+	return fmt.Sprintf("%s: %d", r.Table, r.Records)
+}
+
+// The records of an export. They have their own field names so that the
+// files do not change with the Go types of the store.
+
+type message struct {
+	ID      int64     `json:"id"`
+	Player  string    `json:"player"`
+	Channel string    `json:"channel"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+}
+
+type image struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	NormURL     string    `json:"norm_url,omitempty"`
+	Done        bool      `json:"done"`
+	Kuid        int       `json:"kuid"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Created     time.Time `json:"created"`
+	MessageID   int64     `json:"message_id"`
+}
+
+type feedback struct {
+	ID      int64                `json:"id"`
+	Player  string               `json:"player"`
+	Message string               `json:"message"`
+	Created time.Time            `json:"created"`
+	Status  eribo.FeedbackStatus `json:"status"`
+	Note    string               `json:"note"`
+	Tags    eribo.Tags           `json:"tags"`
+}
+
+type feedbackReply struct {
+	ID         int64      `json:"id"`
+	FeedbackID int64      `json:"feedback_id"`
+	Player     string     `json:"player"`
+	Message    string     `json:"message"`
+	Created    time.Time  `json:"created"`
+	Sent       *time.Time `json:"sent"`
+}
+
+type cmdLog struct {
+	ID      int64     `json:"id"`
+	Command string    `json:"command"`
+	Args    string    `json:"args"`
+	Player  string    `json:"player"`
+	Channel string    `json:"channel"`
+	Created time.Time `json:"created"`
+}
+
+type lothLog struct {
+	ID      int64         `json:"id"`
+	Issuer  string        `json:"issuer"`
+	Channel string        `json:"channel"`
+	Created time.Time     `json:"created"`
+	Name    string        `json:"name"`
+	Role    flist.Role    `json:"role"`
+	Status  flist.Status  `json:"status"`
+	Expires time.Time     `json:"expires"`
+	IsNew   bool          `json:"is_new"`
+	Targets eribo.Targets `json:"targets"`
+}
+
+type auditLog struct {
+	ID      int64     `json:"id"`
+	Action  string    `json:"action"`
+	Actor   string    `json:"actor"`
+	Detail  string    `json:"detail"`
+	Created time.Time `json:"created"`
+}
+
+// pageSize is the number of records read from the store at a time.
+const pageSize = 500
+
+// Export writes the records of a store that the filter selects to files in
+// dir, which is created if needed. The replies to the selected feedback are
+// exported whenever they were created.
+func Export(s eribo.Store, dir string, format Format, f Filter) (results []Result, err error) {
+	w, err := newWriters(dir, format)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := w.close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	counts := make(map[string]int)
+	seen := make(map[int64]bool)
+	err = each(s.ListImages, func(img *eribo.Image) int64 { return img.ID }, f, func(img *eribo.Image) error {
+		if m := img.Message; m != nil && !seen[m.ID] {
+			seen[m.ID] = true
+			counts["messages"]++
+			if err := w.write("messages", message{m.ID, m.Player, m.Channel, m.Message, m.Created.UTC()}); err != nil {
+				return err
+			}
+		}
+		counts["images"]++
+		return w.write("images", image{img.ID, img.URL, img.NormURL, img.Done, img.Kuid, img.ContentType, img.Size, img.Created.UTC(), img.MessageID})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exporting images: %v", err)
+	}
+
+	err = each(s.ListFeedback, func(fb *eribo.Feedback) int64 { return fb.ID }, f, func(fb *eribo.Feedback) error {
+		counts["feedback"]++
+		if err := w.write("feedback", feedback{fb.ID, fb.Player, fb.Message, fb.Created.UTC(), fb.Status, fb.Note, fb.Tags}); err != nil {
+			return err
+		}
+		replies, err := s.ListFeedbackReplies(fb.ID)
+		if err != nil {
+			return err
+		}
+		// Replies are listed oldest first.
+		for i := len(replies) - 1; i >= 0; i-- {
+			r := replies[i]
+			rec := feedbackReply{ID: r.ID, FeedbackID: r.FeedbackID, Player: r.Player, Message: r.Message, Created: r.Created.UTC()}
+			if r.Sent != nil {
+				sent := r.Sent.UTC()
+				rec.Sent = &sent
+			}
+			counts["feedback_replies"]++
+			if err := w.write("feedback_replies", rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exporting feedback: %v", err)
+	}
+
+	err = each(s.ListCmdLogs, func(l *eribo.CmdLog) int64 { return l.ID }, f, func(l *eribo.CmdLog) error {
+		counts["cmd_logs"]++
+		return w.write("cmd_logs", cmdLog{l.ID, l.Command.String(), l.Args, l.Player, l.Channel, l.Created.UTC()})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exporting cmd logs: %v", err)
+	}
+
+	err = each(s.ListLothLogs, func(l *eribo.LothLog) int64 { return l.ID }, f, func(l *eribo.LothLog) error {
+		r := lothLog{ID: l.ID, Issuer: l.Issuer, Channel: l.Channel, Created: l.Created.UTC(), IsNew: l.IsNew, Targets: l.Targets}
+		if l.Loth != nil {
+			r.Expires = l.Expires.UTC()
+			if l.Player != nil {
+				r.Name, r.Role, r.Status = l.Name, l.Role, l.Status
+			}
+		}
+		counts["loth_logs"]++
+		return w.write("loth_logs", r)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exporting loth logs: %v", err)
+	}
+
+	err = each(s.ListAuditLogs, func(a *eribo.AuditLog) int64 { return a.ID }, f, func(a *eribo.AuditLog) error {
+		counts["audit_logs"]++
+		return w.write("audit_logs", auditLog{a.ID, a.Action, a.Actor, a.Detail, a.Created.UTC()})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exporting audit logs: %v", err)
+	}
+
+	for _, t := range Tables {
+		results = append(results, Result{Table: t, Records: counts[t]})
+	}
+	return results, nil
+}
+
+// each calls fn for the records that a list method of the store selects
+// with the filter, reading them a page at a time.
+func each[T any](list func(eribo.Query) ([]T, error), id func(T) int64, f Filter, fn func(T) error) error {
+	q := eribo.Query{Since: f.Since, Until: f.Until, Limit: pageSize}
+	for {
+		records, err := list(q)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		if len(records) < pageSize {
+			return nil
+		}
+		q.BeforeID = id(records[len(records)-1])
+	}
+}
+
+// Import adds the records of an export in dir to a store. The format is the
+// one of the files in dir and tables without a file are skipped. All the
+// files are checked before anything is added, so a malformed export adds
+// nothing.
+func Import(s eribo.Store, dir string) (results []Result, err error) {
+	format, err := detectFormat(dir)
+	if err != nil {
+		return nil, err
+	}
+	var opened []interface{ close() error }
+	defer func() {
+		for _, t := range opened {
+			t.close()
+		}
+	}()
+
+	// The messages and feedback are looked up by their exported IDs, which
+	// are kept with the index of their record.
+	msgIndex := make(map[int64]int)
+	messages, err := openTable(dir, "messages", format, func(i int, m message) error {
+		msgIndex[m.ID] = i
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, messages)
+	posted := make(map[int64][]int)
+	images, err := openTable(dir, "images", format, func(i int, img image) error {
+		if _, ok := msgIndex[img.MessageID]; !ok {
+			return fmt.Errorf("image %d: message %d is not in the export", img.ID, img.MessageID)
+		}
+		posted[img.MessageID] = append(posted[img.MessageID], i)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, images)
+	exported := make(map[int64]bool)
+	feedbacks, err := openTable(dir, "feedback", format, func(_ int, f feedback) error {
+		exported[f.ID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, feedbacks)
+	replies, err := openTable(dir, "feedback_replies", format, func(_ int, r feedbackReply) error {
+		if !exported[r.FeedbackID] {
+			return fmt.Errorf("reply %d: feedback %d is not in the export", r.ID, r.FeedbackID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, replies)
+	cmdLogs, err := openTable(dir, "cmd_logs", format, func(int, cmdLog) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, cmdLogs)
+	lothLogs, err := openTable(dir, "loth_logs", format, func(int, lothLog) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, lothLogs)
+	auditLogs, err := openTable(dir, "audit_logs", format, func(int, auditLog) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, auditLogs)
+
+	msgResult, imgResult, err := importImages(s, messages, msgIndex, images, posted)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, msgResult, imgResult)
+
+	newIDs := make(map[int64]int64)
+	res := Result{Table: "feedback"}
+	known := newKnown(s.ListFeedback, func(f *eribo.Feedback) int64 { return f.ID })
+	err = eachOldest(feedbacks, func(r feedback) error {
+		old, ok, err := known.find(eribo.Query{Player: r.Player}, r.Created, func(f *eribo.Feedback) bool {
+			return f.Player == r.Player && f.Message == r.Message
+		})
+		if err != nil {
+			return fmt.Errorf("importing feedback %d: %v", r.ID, err)
+		}
+		if ok {
+			newIDs[r.ID] = old.ID
+			res.Skipped++
+			return nil
+		}
+		f := &eribo.Feedback{Message: r.Message, Player: r.Player, Created: r.Created, Status: r.Status, Note: r.Note, Tags: r.Tags}
+		if err := s.AddFeedback(f); err != nil {
+			return fmt.Errorf("importing feedback %d: %v", r.ID, err)
+		}
+		known.add(f.ID)
+		newIDs[r.ID] = f.ID
+		res.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, res)
+
+	res = Result{Table: "feedback_replies"}
+	claimed := make(map[int64]bool)
+	err = eachOldest(replies, func(r feedbackReply) error {
+		feedbackID := newIDs[r.FeedbackID]
+		have, err := s.ListFeedbackReplies(feedbackID)
+		if err != nil {
+			return fmt.Errorf("importing reply %d: %v", r.ID, err)
+		}
+		for _, h := range have {
+			if !claimed[h.ID] && h.Player == r.Player && h.Message == r.Message && near(h.Created, r.Created) {
+				claimed[h.ID] = true
+				res.Skipped++
+				return nil
+			}
+		}
+		reply := &eribo.FeedbackReply{FeedbackID: feedbackID, Player: r.Player, Message: r.Message, Created: r.Created}
+		if err := s.AddFeedbackReply(reply); err != nil {
+			return fmt.Errorf("importing reply %d: %v", r.ID, err)
+		}
+		claimed[reply.ID] = true
+		if r.Sent != nil {
+			if err := s.MarkReplySent(reply.ID, *r.Sent); err != nil {
+				return fmt.Errorf("importing reply %d: %v", r.ID, err)
+			}
+		}
+		res.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, res)
+
+	res = Result{Table: "cmd_logs"}
+	knownCmdLogs := newKnown(s.ListCmdLogs, func(l *eribo.CmdLog) int64 { return l.ID })
+	err = eachOldest(cmdLogs, func(r cmdLog) error {
+		var cmd eribo.Command
+		if err := cmd.Scan(r.Command); err != nil {
+			return fmt.Errorf("importing cmd log %d: %v", r.ID, err)
+		}
+		_, ok, err := knownCmdLogs.find(eribo.Query{Player: r.Player, Channel: r.Channel, Command: cmd}, r.Created, func(l *eribo.CmdLog) bool {
+			return l.Command == cmd && l.Args == r.Args && l.Player == r.Player && l.Channel == r.Channel
+		})
+		if err != nil {
+			return fmt.Errorf("importing cmd log %d: %v", r.ID, err)
+		}
+		if ok {
+			res.Skipped++
+			return nil
+		}
+		l := &eribo.CmdLog{Command: cmd, Args: r.Args, Player: r.Player, Channel: r.Channel, Created: r.Created}
+		if err := s.AddCmdLog(l); err != nil {
+			return fmt.Errorf("importing cmd log %d: %v", r.ID, err)
+		}
+		knownCmdLogs.add(l.ID)
+		res.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, res)
+
+	res = Result{Table: "loth_logs"}
+	knownLothLogs := newKnown(s.ListLothLogs, func(l *eribo.LothLog) int64 { return l.ID })
+	err = eachOldest(lothLogs, func(r lothLog) error {
+		_, ok, err := knownLothLogs.find(eribo.Query{Player: r.Issuer, Channel: r.Channel}, r.Created, func(l *eribo.LothLog) bool {
+			return l.Issuer == r.Issuer && l.Channel == r.Channel && l.Loth != nil && l.Player != nil && l.Name == r.Name
+		})
+		if err != nil {
+			return fmt.Errorf("importing loth log %d: %v", r.ID, err)
+		}
+		if ok {
+			res.Skipped++
+			return nil
+		}
+		l := &eribo.LothLog{
+			Issuer:  r.Issuer,
+			Channel: r.Channel,
+			Created: r.Created,
+			Loth: &eribo.Loth{
+				Player:  &eribo.Player{Name: r.Name, Role: r.Role, Status: r.Status},
+				Expires: r.Expires,
+			},
+			IsNew:   r.IsNew,
+			Targets: r.Targets,
+		}
+		if err := s.AddLothLog(l); err != nil {
+			return fmt.Errorf("importing loth log %d: %v", r.ID, err)
+		}
+		knownLothLogs.add(l.ID)
+		res.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, res)
+
+	res = Result{Table: "audit_logs"}
+	knownAuditLogs := newKnown(s.ListAuditLogs, func(a *eribo.AuditLog) int64 { return a.ID })
+	err = eachOldest(auditLogs, func(r auditLog) error {
+		_, ok, err := knownAuditLogs.find(eribo.Query{Player: r.Actor}, r.Created, func(a *eribo.AuditLog) bool {
+			return a.Action == r.Action && a.Actor == r.Actor && a.Detail == r.Detail
+		})
+		if err != nil {
+			return fmt.Errorf("importing audit log %d: %v", r.ID, err)
+		}
+		if ok {
+			res.Skipped++
+			return nil
+		}
+		a := &eribo.AuditLog{Action: r.Action, Actor: r.Actor, Detail: r.Detail, Created: r.Created}
+		if err := s.AddAuditLog(a); err != nil {
+			return fmt.Errorf("importing audit log %d: %v", r.ID, err)
+		}
+		knownAuditLogs.add(a.ID)
+		res.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, res)
+	return results, nil
+}
+
+// eachOldest calls fn with the records of a table from the last one to the
+// first, which is oldest first for the files of an export.
+func eachOldest[T any](t *tableReader[T], fn func(T) error) error {
+	for i := t.count() - 1; i >= 0; i-- {
+		rec, err := t.read(i)
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTime is how far apart the creation times of two records can be for an
+// import to take them for the same record. Stores keep times at different
+// precisions, e.g. MySQL rounds them to the second.
+const sameTime = time.Second
+
+// near reports whether two creation times are within sameTime.
+func near(a, b time.Time) bool {
+	d := a.Sub(b)
+	return -sameTime < d && d < sameTime
+}
+
+// known finds the records of a table that the store already has. Each
+// record of the store is matched once, and the ones the import adds are not
+// matched at all, so that equal records of an export are all kept.
+type known[T any] struct {
+	list    func(eribo.Query) ([]T, error)
+	id      func(T) int64
+	claimed map[int64]bool
+}
+
+func newKnown[T any](list func(eribo.Query) ([]T, error), id func(T) int64) *known[T] {
+	return &known[T]{list: list, id: id, claimed: make(map[int64]bool)}
+}
+
+// find returns a record that the query selects, created near created, for
+// which same is true and claims it.
+func (k *known[T]) find(q eribo.Query, created time.Time, same func(T) bool) (T, bool, error) {
+	q.Since, q.Until = created.Add(-sameTime), created.Add(sameTime)
+	records, err := k.list(q)
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	for _, r := range records {
+		if id := k.id(r); !k.claimed[id] && same(r) {
+			k.claimed[id] = true
+			return r, true, nil
+		}
+	}
+	var zero T
+	return zero, false, nil
+}
+
+// add claims a record that the import added.
+func (k *known[T]) add(id int64) {
+	k.claimed[id] = true
+}
+
+// importImages adds the images with their messages, by message ID, and
+// restores whether they are done and their kuid, which the store sets
+// separately. posted holds the indexes of the images of each message.
+func importImages(s eribo.Store, messages *tableReader[message], msgIndex map[int64]int, images *tableReader[image], posted map[int64][]int) (msgResult, imgResult Result, err error) {
+	msgResult = Result{Table: "messages"}
+	imgResult = Result{Table: "images"}
+
+	var ids []int64
+	for id := range posted {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		r, err := messages.read(msgIndex[id])
+		if err != nil {
+			return msgResult, imgResult, err
+		}
+		m := &eribo.Message{Message: r.Message, Player: r.Player, Channel: r.Channel, Created: r.Created}
+		records := make([]image, len(posted[id]))
+		for i, n := range posted[id] {
+			if records[i], err = images.read(n); err != nil {
+				return msgResult, imgResult, err
+			}
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+		imgs := make([]*eribo.Image, len(records))
+		for i, img := range records {
+			imgs[i] = &eribo.Image{URL: img.URL, NormURL: img.NormURL, ContentType: img.ContentType, Size: img.Size}
+		}
+		if err := s.AddMessageWithImages(m, imgs); err != nil {
+			return msgResult, imgResult, fmt.Errorf("importing message %d: %v", id, err)
+		}
+		if m.ID == 0 {
+			msgResult.Skipped++
+		} else {
+			msgResult.Records++
+		}
+
+		for i, img := range imgs {
+			if img.ID == 0 {
+				imgResult.Skipped++
+				continue
+			}
+			imgResult.Records++
+			if err := restoreImage(s, img.ID, records[i]); err != nil {
+				return msgResult, imgResult, fmt.Errorf("importing image %d: %v", records[i].ID, err)
+			}
+		}
+	}
+	return msgResult, imgResult, nil
+}
+
+// restoreImage sets the kuid and done of a new image to the ones of the
+// record.
+func restoreImage(s eribo.Store, id int64, r image) error {
+	done := false
+	if r.Kuid != 0 {
+		if err := s.SetImageKuid(id, r.Kuid); err != nil {
+			return err
+		}
+		done = true
+	}
+	if r.Done != done {
+		return s.ToggleImageDone(id)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package dump
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+	"github.com/kusubooru/eribo/flist"
+)
+
+var day = time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fill adds records created an hour apart starting at day.
+func fill(t *testing.T, s eribo.Store) {
+	t.Helper()
+	posts := []struct {
+		m    *eribo.Message
+		urls []string
+	}{
+		{&eribo.Message{Player: "foo", Channel: "room", Message: "first, with \"quotes\""}, []string{"http://a.png", "http://b.png"}},
+		{&eribo.Message{Player: "bar", Channel: "room", Message: "repost"}, []string{"http://a.png"}},
+		{&eribo.Message{Player: "baz", Channel: "other", Message: "line\nbreak"}, []string{"http://b.png", "http://c.gif"}},
+	}
+	for i, p := range posts {
+		p.m.Created = day.Add(time.Duration(i) * time.Hour)
+		images := make([]*eribo.Image, len(p.urls))
+		for j, u := range p.urls {
+			images[j] = &eribo.Image{URL: u, ContentType: "image/" + filepath.Ext(u)[1:], Size: int64(10 * (j + 1))}
+		}
+		if err := s.AddMessageWithImages(p.m, images); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+	if err := s.SetImageKuid(1, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ToggleImageDone(3); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		created := day.Add(time.Duration(i) * time.Hour)
+		f := &eribo.Feedback{Player: "foo", Message: "thanks, bot", Created: created}
+		if i == 1 {
+			f.Status, f.Note, f.Tags = eribo.FeedbackWontfix, "it is a feature", eribo.Tags{"bot", "tomato"}
+		}
+		if err := s.AddFeedback(f); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Args: "x,y", Player: "bar", Channel: "room", Created: created}); err != nil {
+			t.Fatal(err)
+		}
+		l := &eribo.LothLog{
+			Issuer:  "foo",
+			Channel: "room",
+			Created: created,
+			Loth:    &eribo.Loth{Player: &eribo.Player{Name: "bar", Role: flist.RoleFullDom, Status: flist.StatusLooking}, Expires: created.Add(time.Hour)},
+			IsNew:   i%2 == 0,
+			Targets: eribo.Targets{{Name: "bar", Role: flist.RoleFullDom}, {Name: "baz"}},
+		}
+		if err := s.AddLothLog(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, msg := range []string{"it is, really", "promise"} {
+		r := &eribo.FeedbackReply{FeedbackID: 2, Player: "foo", Message: msg, Created: day.Add(time.Duration(2+i) * time.Hour)}
+		if err := s.AddFeedbackReply(r); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := s.MarkReplySent(r.ID, day.Add(5*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := s.AddAuditLog(&eribo.AuditLog{Action: eribo.AuditForgetMe, Actor: "qux", Detail: "deleted 1 feedback", Created: day}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// contents are the records of a store without their IDs, with images by
+// URL and the message they were posted in.
+type contents struct {
+	Images    []string
+	Feedback  []eribo.Feedback
+	Replies   []string
+	CmdLogs   []eribo.CmdLog
+	LothLogs  []string
+	AuditLogs []eribo.AuditLog
+}
+
+func contentsOf(t *testing.T, s eribo.Store) contents {
+	t.Helper()
+	var c contents
+	images, err := s.GetImages(100, 0, false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range images {
+		c.Images = append(c.Images, fmt.Sprintf("%s %s size=%d done=%v kuid=%d %v by %s in %s: %q",
+			img.URL, img.ContentType, img.Size, img.Done, img.Kuid, img.Created, img.Message.Player, img.Message.Channel, img.Message.Message))
+	}
+	feedback, err := s.GetAllFeedback(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range feedback {
+		replies, err := s.ListFeedbackReplies(f.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range replies {
+			sent := "not sent"
+			if r.Sent != nil {
+				sent = "sent " + r.Sent.String()
+			}
+			c.Replies = append(c.Replies, fmt.Sprintf("%q to %s of %q at %v, %s", r.Message, r.Player, f.Message, r.Created, sent))
+		}
+		f.ID = 0
+		c.Feedback = append(c.Feedback, *f)
+	}
+	logs, err := s.ListCmdLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range logs {
+		l.ID = 0
+		c.CmdLogs = append(c.CmdLogs, *l)
+	}
+	lothLogs, err := s.ListLothLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range lothLogs {
+		l.ID = 0
+		c.LothLogs = append(c.LothLogs, fmt.Sprintf("%s expires %v targets %s", l, l.Expires, targetNames(l.Targets)))
+	}
+	auditLogs, err := s.ListAuditLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range auditLogs {
+		a.ID = 0
+		c.AuditLogs = append(c.AuditLogs, *a)
+	}
+	return c
+}
+
+func targetNames(targets eribo.Targets) string {
+	s := ""
+	for _, p := range targets {
+		s += p.Name + "/" + string(p.Role) + ","
+	}
+	return s
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []Format{JSONL, CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			src := memstore.New()
+			fill(t, src)
+			dir := t.TempDir()
+			results, err := Export(src, dir, format, Filter{})
+			if err != nil {
+				t.Fatal("Export failed:", err)
+			}
+			want := []Result{{"messages", 2, 0}, {"images", 3, 0}, {"feedback", 3, 0}, {"feedback_replies", 2, 0}, {"cmd_logs", 3, 0}, {"loth_logs", 3, 0}, {"audit_logs", 1, 0}}
+			if !reflect.DeepEqual(results, want) {
+				t.Errorf("Export results\nhave: %v\nwant: %v", results, want)
+			}
+
+			// The destination already has a message so the IDs of the
+			// imported messages differ from the exported ones.
+			dst := memstore.New()
+			other := []*eribo.Image{{URL: "http://other.png"}}
+			if err := dst.AddMessageWithImages(&eribo.Message{Player: "qux", Channel: "room", Message: "other", Created: day.Add(-time.Hour)}, other); err != nil {
+				t.Fatal(err)
+			}
+			results, err = Import(dst, dir)
+			if err != nil {
+				t.Fatal("Import failed:", err)
+			}
+			if !reflect.DeepEqual(results, want) {
+				t.Errorf("Import results\nhave: %v\nwant: %v", results, want)
+			}
+
+			img, err := dst.GetImage(4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.URL != "http://c.gif" || img.MessageID != 3 || img.Message.Message != "line\nbreak" {
+				t.Errorf("imported image 4 = %s of message %d %q, want http://c.gif of message 3 \"line\\nbreak\"", img.URL, img.MessageID, img.Message.Message)
+			}
+
+			have := contentsOf(t, dst)
+			have.Images = have.Images[1:]
+			if want := contentsOf(t, src); !reflect.DeepEqual(have, want) {
+				t.Errorf("imported contents\nhave: %+v\nwant: %+v", have, want)
+			}
+		})
+	}
+}
+
+func TestImport_twice(t *testing.T) {
+	src := memstore.New()
+	fill(t, src)
+	dir := t.TempDir()
+	if _, err := Export(src, dir, JSONL, Filter{}); err != nil {
+		t.Fatal("Export failed:", err)
+	}
+	if _, err := Import(src, dir); err != nil {
+		t.Fatal("Import failed:", err)
+	}
+	results, err := Import(src, dir)
+	if err != nil {
+		t.Fatal("Import failed:", err)
+	}
+	want := []Result{{"messages", 0, 2}, {"images", 0, 3}, {"feedback", 0, 3}, {"feedback_replies", 0, 2}, {"cmd_logs", 0, 3}, {"loth_logs", 0, 3}, {"audit_logs", 0, 1}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Import of known records\nhave: %v\nwant: %v", results, want)
+	}
+}
+
+func TestImport_equalRecords(t *testing.T) {
+	src := memstore.New()
+	for i := 0; i < 2; i++ {
+		if err := src.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo", Channel: "room", Created: day}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	if _, err := Export(src, dir, CSV, Filter{}); err != nil {
+		t.Fatal("Export failed:", err)
+	}
+
+	// The destination has one of the logs, so the import adds the other.
+	dst := memstore.New()
+	if err := dst.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo", Channel: "room", Created: day.Add(400 * time.Millisecond)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []Result{{"cmd_logs", 1, 1}, {"cmd_logs", 0, 2}} {
+		results, err := Import(dst, dir)
+		if err != nil {
+			t.Fatal("Import failed:", err)
+		}
+		if got := results[4]; got != want {
+			t.Errorf("Import cmd logs = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExport_filter(t *testing.T) {
+	s := memstore.New()
+	fill(t, s)
+	dir := t.TempDir()
+	results, err := Export(s, dir, CSV, Filter{Since: day.Add(time.Hour), Until: day.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal("Export failed:", err)
+	}
+	// The repost of the second hour stored no message.
+	want := []Result{{"messages", 0, 0}, {"images", 0, 0}, {"feedback", 1, 0}, {"feedback_replies", 2, 0}, {"cmd_logs", 1, 0}, {"loth_logs", 1, 0}, {"audit_logs", 0, 0}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Export results\nhave: %v\nwant: %v", results, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "images.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "id,url,norm_url,done,kuid,content_type,size,created,message_id\n"; got != want {
+		t.Errorf("images.csv without images = %q, want %q", got, want)
+	}
+}
+
+func TestImport_errors(t *testing.T) {
+	write := func(files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no files", map[string]string{"notes.txt": "hi"}},
+		{"both formats", map[string]string{"feedback.csv": "", "feedback.jsonl": ""}},
+		{"missing message", map[string]string{"images.jsonl": `{"id":1,"url":"http://a","message_id":7}`}},
+		{"missing feedback", map[string]string{"feedback_replies.jsonl": `{"id":1,"feedback_id":7,"message":"hi"}`}},
+		{"bad json", map[string]string{"feedback.jsonl": "{"}},
+		{"bad csv header", map[string]string{"feedback.csv": "id,who\n1,foo\n"}},
+		{"bad csv time", map[string]string{"feedback.csv": "id,player,message,created,status,note,tags\n1,foo,hi,yesterday,open,,[]\n"}},
+	}
+	for _, tt := range tests {
+		if _, err := Import(memstore.New(), write(tt.files)); err == nil {
+			t.Errorf("%s: Import succeeded", tt.name)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"jsonl", "csv"} {
+		if f, err := ParseFormat(name); err != nil || string(f) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, f, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(\"xml\") succeeded")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -30,16 +31,31 @@ func (l Loth) Expired() bool {
 // Store
 
 type Image struct {
-	ID        int64
-	URL       string
-	Done      bool
-	Kuid      int
+	ID  int64
+	URL string
+	// NormURL is the normalized URL that tells reposts of the image apart.
+	// The URL is used when it is empty.
+	NormURL string `db:"norm_url"`
+	Done    bool
+	Kuid    int
+	// ContentType is the content type of the image, e.g. "image/png", or
+	// empty if it is not known.
+	ContentType string `db:"content_type"`
+	// Size is the size of the image in bytes or 0 if it is not known.
+	Size      int64
 	Created   time.Time
 	MessageID int64    `db:"message_id"`
 	Message   *Message `db:"message"`
 }
 
 func (i Image) String() string {
+	return i.Line(nil)
+}
+
+// Line returns the image as a line of the owner's list of images. postURL
+// returns the URL of the booru post with an id, which links the kuid. Without
+// it the kuid is shown as a number.
+func (i Image) Line(postURL func(id int) string) string {
 	done := "0"
 	if i.Done {
 		done = "1"
@@ -49,11 +65,18 @@ func (i Image) String() string {
 		player = i.Message.Player
 	}
 	kuid := ""
-	if i.Kuid != 0 {
-		kuid = fmt.Sprintf(" [url=https://kusubooru.com/post/view/%d]done[/url]", i.Kuid)
+	switch {
+	case i.Kuid != 0 && postURL != nil:
+		kuid = fmt.Sprintf(" [url=%s]done[/url]", postURL(i.Kuid))
+	case i.Kuid != 0:
+		kuid = fmt.Sprintf(" done as post %d", i.Kuid)
+	}
+	typ := ""
+	if i.ContentType != "" {
+		typ = " " + strings.TrimPrefix(i.ContentType, "image/")
 	}
-	return fmt.Sprintf("%6d: %v> %s by %s: [url=%s]link[/url]%s",
-		i.ID, i.Created.Format(time.Stamp), done, player, i.URL, kuid)
+	return fmt.Sprintf("%6d: %v> %s by %s: [url=%s]link[/url]%s%s",
+		i.ID, i.Created.Format(time.Stamp), done, player, i.URL, typ, kuid)
 }
 
 type Message struct {
@@ -69,10 +92,20 @@ type Feedback struct {
 	Message string
 	Player  string
 	Created time.Time
+	// Status is where the owner is with the feedback. It is FeedbackOpen
+	// when the feedback is added without one.
+	Status FeedbackStatus
+	// Note and Tags are the owner's and are not shown to the player.
+	Note string
+	Tags Tags
 }
 
 func (f Feedback) String() string {
-	return fmt.Sprintf("%4d: %v by %s - %q", f.ID, f.Created.Format(time.Stamp), f.Player, f.Message)
+	tags := ""
+	if len(f.Tags) != 0 {
+		tags = " " + strings.Join(f.Tags, ",")
+	}
+	return fmt.Sprintf("%4d: %v by %s [%s%s] - %q", f.ID, f.Created.Format(time.Stamp), f.Player, f.Status, tags, f.Message)
 }
 
 type CmdLog struct {
@@ -145,19 +178,73 @@ func (t *Targets) Scan(value interface{}) error {
 }
 
 type Store interface {
-	AddMessageWithURLs(m *Message, urls []string) error
-	GetImages(limit, offset int, reverse, filterDone bool) ([]*Image, error)
+	// AddMessageWithImages stores a message with the images posted in it.
+	// Images whose URL is already stored are skipped and the message is only
+	// stored if at least one of its images is new. The IDs of the stored
+	// message and images are set on them; skipped images keep theirs.
+	AddMessageWithImages(m *Message, images []*Image) error
+	// GetImages returns images, optionally only the ones that are not done
+	// or, with a non empty contentType, only the ones of that content type.
+	GetImages(limit, offset int, reverse, filterDone bool, contentType string) ([]*Image, error)
+	GetImage(id int64) (*Image, error)
+	// ListImages returns the images selected by the query, whose player and
+	// channel are the ones of the message the image was posted in.
+	ListImages(q Query) ([]*Image, error)
 	ToggleImageDone(id int64) error
 	SetImageKuid(id int64, kuid int) error
 
+	// AddFeedback stores feedback and sets its ID.
 	AddFeedback(f *Feedback) error
 	GetAllFeedback(limit, offset int) ([]*Feedback, error)
 	GetRecentFeedback(limit, offset int) ([]*Feedback, error)
-
+	// ListFeedback returns the feedback selected by the query. Feedback has
+	// no channel so a query for a channel selects none.
+	ListFeedback(q Query) ([]*Feedback, error)
+	GetFeedback(id int64) (*Feedback, error)
+	// UpdateFeedback stores the status, note and tags of f in the feedback
+	// with the ID of f.
+	UpdateFeedback(f *Feedback) error
+
+	// AddFeedbackReply stores a reply to feedback, to be sent to its player,
+	// and sets its ID.
+	AddFeedbackReply(r *FeedbackReply) error
+	// ListFeedbackReplies returns the replies to feedback, oldest first.
+	ListFeedbackReplies(feedbackID int64) ([]*FeedbackReply, error)
+	// ListUnsentReplies returns the replies to a player that were not sent
+	// yet, oldest first. An empty player selects the replies to everyone.
+	ListUnsentReplies(player string) ([]*FeedbackReply, error)
+	MarkReplySent(id int64, sent time.Time) error
+
+	// AddCmdLog stores a command log and sets its ID.
 	AddCmdLog(e *CmdLog) error
 	GetRecentCmdLogs(limit, offset int) ([]*CmdLog, error)
+	ListCmdLogs(q Query) ([]*CmdLog, error)
 	CmdStats() ([]*CmdStat, error)
+	// CmdUsage counts the uses of the command logs selected by the query
+	// grouped as it asks. The counts are ordered as by SortCmdCounts.
+	CmdUsage(q CmdUsageQuery) ([]*CmdCount, error)
 
+	// AddLothLog stores a loth log and sets its ID.
 	AddLothLog(*LothLog) error
 	GetRecentLothLogs(limit, offset int) ([]*LothLog, error)
+	// ListLothLogs returns the loth logs selected by the query. A loth log
+	// matches a player if they issued the command or were chosen as loth.
+	ListLothLogs(q Query) ([]*LothLog, error)
+
+	// DeleteBefore deletes the records of a table that were created before
+	// a time and returns how many it deleted. Deleting messages deletes the
+	// images posted in them and deleting feedback deletes its replies.
+	DeleteBefore(table Table, before time.Time) (int64, error)
+	// ForgetPlayer deletes the feedback, with its replies, and command logs
+	// of a player and anonymizes their messages and loth logs, all or
+	// nothing. Names are compared case insensitively. Unless audit is nil,
+	// it is stored along with the changes, with the result as its detail,
+	// and its ID is set.
+	ForgetPlayer(name string, audit *AuditLog) (*ForgetResult, error)
+
+	// AddAuditLog stores an audit log and sets its ID.
+	AddAuditLog(a *AuditLog) error
+	// ListAuditLogs returns the audit logs selected by the query. Audit
+	// logs have no channel and match a player who is their actor.
+	ListAuditLogs(q Query) ([]*AuditLog, error)
 }
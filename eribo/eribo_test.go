@@ -0,0 +1,18 @@
+package eribo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestImage_Line(t *testing.T) {
+	img := Image{ID: 1, URL: "http://url", Kuid: 5}
+	postURL := func(id int) string { return fmt.Sprintf("https://booru.example.com/post/view/%d", id) }
+	if got, want := img.Line(postURL), " [url=https://booru.example.com/post/view/5]done[/url]"; !strings.HasSuffix(got, want) {
+		t.Errorf("Line = %q, want suffix %q", got, want)
+	}
+	if got, want := img.String(), " done as post 5"; !strings.HasSuffix(got, want) {
+		t.Errorf("String = %q, want suffix %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package eribo
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FeedbackStatus is where the owner is with a feedback.
+type FeedbackStatus string
+
+const (
+	FeedbackOpen         FeedbackStatus = "open"
+	FeedbackAcknowledged FeedbackStatus = "acknowledged"
+	FeedbackResolved     FeedbackStatus = "resolved"
+	FeedbackWontfix      FeedbackStatus = "wontfix"
+)
+
+// FeedbackStatuses are the statuses of feedback in the order it usually goes
+// through them.
+var FeedbackStatuses = []FeedbackStatus{FeedbackOpen, FeedbackAcknowledged, FeedbackResolved, FeedbackWontfix}
+
+// ParseFeedbackStatus returns the status of a name, compared case
+// insensitively.
+func ParseFeedbackStatus(name string) (FeedbackStatus, error) {
+	for _, s := range FeedbackStatuses {
+		if strings.EqualFold(string(s), name) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown feedback status %q", name)
+}
+
+// Tags are the tags of a feedback. They are stored as a JSON array and no
+// tags are nil.
+type Tags []string
+
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		t = Tags{}
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (t *Tags) Scan(value interface{}) error {
+	var err error
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		err = json.Unmarshal([]byte(v), t)
+	case []byte:
+		err = json.Unmarshal(v, t)
+	default:
+		return fmt.Errorf("cannot scan tags value")
+	}
+	if len(*t) == 0 {
+		*t = nil
+	}
+	return err
+}
+
+// FeedbackReply is the owner's reply to a feedback. It is sent to the player
+// who sent the feedback when they are online.
+type FeedbackReply struct {
+	ID         int64
+	FeedbackID int64 `db:"feedback_id"`
+	// Player is who the reply is sent to.
+	Player  string
+	Message string
+	Created time.Time
+	// Sent is when the reply was sent to the player, or nil until then.
+	Sent *time.Time
+}
+
+func (r FeedbackReply) String() string {
+	sent := "not sent"
+	if r.Sent != nil {
+		sent = "sent " + r.Sent.Format(time.Stamp)
+	}
+	return fmt.Sprintf("%4d: %v to %s (%s) - %q", r.ID, r.Created.Format(time.Stamp), r.Player, sent, r.Message)
+}
@@ -0,0 +1,49 @@
+package eribo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeedbackStatus(t *testing.T) {
+	var tests = []struct {
+		in      string
+		want    FeedbackStatus
+		wantErr bool
+	}{
+		{"open", FeedbackOpen, false},
+		{"WontFix", FeedbackWontfix, false},
+		{"done", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFeedbackStatus(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFeedbackStatus(%q) = %q, %v, want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	var tests = []struct {
+		tags  Tags
+		value string
+	}{
+		{nil, "[]"},
+		{Tags{"bug", "rp"}, `["bug","rp"]`},
+	}
+	for _, tt := range tests {
+		v, err := tt.tags.Value()
+		if err != nil || v != tt.value {
+			t.Errorf("%q.Value() = %v, %v, want %v", tt.tags, v, err, tt.value)
+		}
+		var got Tags
+		if err := got.Scan([]byte(tt.value)); err != nil || !reflect.DeepEqual(got, tt.tags) {
+			t.Errorf("Scan(%v) = %q, %v, want %q", tt.value, got, err, tt.tags)
+		}
+	}
+	var got Tags
+	if err := got.Scan(42); err == nil {
+		t.Errorf("Scan(42) = %q, want error", got)
+	}
+}
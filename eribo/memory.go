@@ -14,11 +14,32 @@ type Player struct {
 	Name   string
 	Role   flist.Role
 	Status flist.Status
+	Gender flist.Gender
 	Fave   bool
 }
 
+// LothEligible reports whether the player can be chosen as loth: an active
+// player with a role other than always dominant who has tickling as a
+// favorite kink.
+func (p Player) LothEligible() bool {
+	if !p.Status.IsActive() {
+		return false
+	}
+	if p.Role == flist.RoleFullDom || p.Role == "" {
+		return false
+	}
+	return p.Fave
+}
+
+// OptsOut reports whether the player must not be the target of rp commands
+// even when they are named. Always dominant players and players who do not
+// want to be disturbed opt out.
+func (p Player) OptsOut() bool {
+	return p.Role == flist.RoleFullDom || p.Status == flist.StatusDND
+}
+
 func (p Player) String() string {
-	return fmt.Sprintf("Name: %q, Status: %q, Role: %q, Fave: %v", p.Name, p.Status, p.Role, p.Fave)
+	return fmt.Sprintf("Name: %q, Status: %q, Role: %q, Gender: %q, Fave: %v", p.Name, p.Status, p.Role, p.Gender, p.Fave)
 }
 
 type PlayerMap struct {
@@ -211,10 +232,7 @@ func (c *ChannelMap) ChooseLoth(playerName, channel, botName string, d time.Dura
 	pm.ForEach(func(name string, p *Player) {
 		c.RLock()
 		defer c.RUnlock()
-		if p.Role == flist.RoleFullDom || p.Role == "" {
-			return
-		}
-		if !p.Fave {
+		if !p.LothEligible() {
 			return
 		}
 		if p.Name == botName {
@@ -242,7 +260,7 @@ func (c *ChannelMap) ChooseLoth(playerName, channel, botName string, d time.Dura
 }
 
 func randTarget(playerName string, targets []*Player, lowNames []string) *Player {
-	t := &loot.Table{}
+	t := &loot.Table[*Player]{}
 	for _, p := range targets {
 		var weight int
 		switch p.Role {
@@ -275,9 +293,7 @@ func randTarget(playerName string, targets []*Player, lowNames []string) *Player
 		}
 		t.Add(p, weight)
 	}
-	seed := time.Now().UnixNano()
-	_, loth := t.Roll(seed)
-	p, ok := loth.(*Player)
+	_, p, ok := t.Roll()
 	if !ok {
 		return nil
 	}
@@ -291,18 +307,101 @@ func (c *ChannelMap) GetChannel(channel string) (*PlayerMap, bool) {
 	return pm, ok
 }
 
+// Find returns the players of the channel that the name refers to. A player
+// whose name matches exactly, ignoring case, is preferred over the ones whose
+// name starts with it, so "Ann" finds Ann even when Annabelle is also in the
+// channel. Quoted names and [user] references only match exactly. See
+// ParseName.
 func (c *ChannelMap) Find(playerName, channelName string) []*Player {
 	targets := make([]*Player, 0)
 	pm, ok := c.PlayerMap(channelName)
 	if !ok {
 		return targets
 	}
-	pm.ForEach(func(name string, p *Player) {
+	name, exact := ParseName(playerName)
+	prefix := strings.ToLower(name)
+	var match *Player
+	pm.ForEach(func(_ string, p *Player) {
 		c.RLock()
 		defer c.RUnlock()
-		if strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(playerName)) {
+		if strings.EqualFold(p.Name, name) {
+			match = p
+			return
+		}
+		if !exact && strings.HasPrefix(strings.ToLower(p.Name), prefix) {
 			targets = append(targets, p)
 		}
 	})
+	if match != nil {
+		return []*Player{match}
+	}
 	return targets
 }
+
+// Suggest returns the names of up to three players of the channel whose names
+// are close to the name, closest first. It is meant for names that Find does
+// not find, to ask "did you mean" instead.
+func (c *ChannelMap) Suggest(playerName, channelName string) []string {
+	pm, ok := c.PlayerMap(channelName)
+	if !ok {
+		return nil
+	}
+	var names []string
+	pm.ForEach(func(_ string, p *Player) {
+		c.RLock()
+		defer c.RUnlock()
+		names = append(names, p.Name)
+	})
+	name, _ := ParseName(playerName)
+	return suggest(name, names)
+}
+
+// FindAll finds each of the names in the channel. Names that match no player
+// are returned as missing and names that match more than one as ambiguous.
+// A player that matches more than one name is only returned once.
+func (c *ChannelMap) FindAll(names []string, channelName string) (players []*Player, missing, ambiguous []string) {
+	seen := make(map[string]bool)
+	for _, name := range names {
+		found := c.Find(name, channelName)
+		switch len(found) {
+		case 0:
+			missing = append(missing, name)
+		case 1:
+			if !seen[found[0].Name] {
+				seen[found[0].Name] = true
+				players = append(players, found[0])
+			}
+		default:
+			ambiguous = append(ambiguous, name)
+		}
+	}
+	return players, missing, ambiguous
+}
+
+// RandomTarget returns a random player of the channel who could be chosen as
+// loth, excluding the players with the given names. It returns nil if there
+// is no such player. Random rp targets, e.g. of !tieup random, use the loth
+// criteria because the tickling favorite is the only opt-in to rp that the bot
+// can read from a profile.
+func (c *ChannelMap) RandomTarget(channelName string, exclude ...string) *Player {
+	pm, ok := c.PlayerMap(channelName)
+	if !ok {
+		return nil
+	}
+	t := &loot.Table[*Player]{}
+	pm.ForEach(func(name string, p *Player) {
+		for _, ex := range exclude {
+			if p.Name == ex {
+				return
+			}
+		}
+		if p.LothEligible() {
+			t.Add(p, 1)
+		}
+	})
+	_, p, ok := t.Roll()
+	if !ok {
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,134 @@
+package eribo
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kusubooru/eribo/flist"
+)
+
+func testChannelMap(channel string, players ...*Player) *ChannelMap {
+	c := NewChannelMap()
+	for _, p := range players {
+		c.SetPlayer(channel, p)
+	}
+	return c
+}
+
+func TestChannelMapFindAll(t *testing.T) {
+	c := testChannelMap("room",
+		&Player{Name: "Alice"},
+		&Player{Name: "Bob"},
+		&Player{Name: "Bobby"},
+		&Player{Name: "Carol"},
+	)
+	players, missing, ambiguous := c.FindAll([]string{"alice", "Bo", "Dave", "car", "Alice"}, "room")
+	var names []string
+	for _, p := range players {
+		names = append(names, p.Name)
+	}
+	if got, want := names, []string{"Alice", "Carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll players = %q, want %q", got, want)
+	}
+	if got, want := missing, []string{"Dave"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll missing = %q, want %q", got, want)
+	}
+	if got, want := ambiguous, []string{"Bo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll ambiguous = %q, want %q", got, want)
+	}
+}
+
+func TestChannelMapRandomTarget(t *testing.T) {
+	consenting := func(name string) *Player {
+		return &Player{Name: name, Status: flist.StatusOnline, Role: flist.RoleSwitch, Fave: true}
+	}
+	c := testChannelMap("room",
+		consenting("Alice"),
+		consenting("Bot"),
+		consenting("Issuer"),
+		&Player{Name: "Busy", Status: flist.StatusBusy, Role: flist.RoleSwitch, Fave: true},
+		&Player{Name: "Dom", Status: flist.StatusOnline, Role: flist.RoleFullDom, Fave: true},
+		&Player{Name: "NoFave", Status: flist.StatusOnline, Role: flist.RoleSwitch},
+	)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		p := c.RandomTarget("room", "Bot", "Issuer")
+		if p == nil {
+			t.Fatal("RandomTarget returned nil")
+		}
+		seen[p.Name] = true
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if got, want := names, []string{"Alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RandomTarget chose %q, want %q", got, want)
+	}
+	if p := c.RandomTarget("room", "Alice", "Bot", "Issuer"); p != nil {
+		t.Errorf("RandomTarget = %v, want nil", p)
+	}
+	if p := c.RandomTarget("other"); p != nil {
+		t.Errorf("RandomTarget in unknown channel = %v, want nil", p)
+	}
+}
+
+func TestChannelMapFind(t *testing.T) {
+	c := testChannelMap("room",
+		&Player{Name: "Ann"},
+		&Player{Name: "Annabelle"},
+		&Player{Name: "Anna"},
+		&Player{Name: "Bob Doe"},
+	)
+	var tests = []struct {
+		name string
+		want []string
+	}{
+		{"ann", []string{"Ann"}},
+		{"Annab", []string{"Annabelle"}},
+		{"An", []string{"Ann", "Anna", "Annabelle"}},
+		{`"Anna"`, []string{"Anna"}},
+		{`"Annab"`, nil},
+		{"[user]bob doe[/user]", []string{"Bob Doe"}},
+		{"[user]Bob[/user]", nil},
+		{"Bob", []string{"Bob Doe"}},
+		{"Carol", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range c.Find(tt.name, "room") {
+			got = append(got, p.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChannelMapSuggest(t *testing.T) {
+	c := testChannelMap("room",
+		&Player{Name: "Annabelle"},
+		&Player{Name: "Bob Doe"},
+		&Player{Name: "Carol"},
+	)
+	var tests = []struct {
+		name string
+		want []string
+	}{
+		{"Anabelle", []string{"Annabelle"}},
+		{"Crol", []string{"Carol"}},
+		{"Bbo", nil},
+		{"Bob Dao", []string{"Bob Doe"}},
+		{"Bov", []string{"Bob Doe"}},
+		{"Dave", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := c.Suggest(tt.name, "room"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,688 @@
+// Package memstore implements eribo.Store in memory for tests that need a
+// store but no database. It behaves like the SQL stores: records get
+// increasing IDs from 1 that are not reused after deletes, lists are ordered
+// the same way and names are compared case insensitively. The records it
+// returns are copies.
+package memstore
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+const timeTruncate = 1 * time.Second
+
+// Store is an eribo.Store that is safe for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	messages []*eribo.Message
+	images   []*eribo.Image
+	feedback []*eribo.Feedback
+	replies  []*eribo.FeedbackReply
+	cmdLogs  []*eribo.CmdLog
+	lothLogs []*eribo.LothLog
+	audit    []*eribo.AuditLog
+
+	// The last IDs given to the records of each table.
+	lastMessage, lastImage, lastFeedback, lastReply, lastCmdLog, lastLothLog, lastAudit int64
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{}
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(timeTruncate)
+}
+
+// page returns the part of a list of n records that limit and offset select.
+func page(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if limit < 0 || end > n {
+		end = n
+	}
+	return offset, end
+}
+
+func (s *Store) AddMessageWithImages(m *eribo.Message, images []*eribo.Image) error {
+	if (m.Created == time.Time{}) {
+		m.Created = now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var newImages []*eribo.Image
+	for _, img := range images {
+		if img.NormURL == "" {
+			img.NormURL = img.URL
+		}
+		if !s.hasImage(img.NormURL) {
+			newImages = append(newImages, img)
+		}
+	}
+	if len(newImages) == 0 {
+		return nil
+	}
+
+	s.lastMessage++
+	msg := &eribo.Message{
+		ID:      s.lastMessage,
+		Message: m.Message,
+		Player:  m.Player,
+		Channel: m.Channel,
+		Created: m.Created.UTC(),
+	}
+	s.messages = append(s.messages, msg)
+	m.ID = msg.ID
+	for _, img := range newImages {
+		s.lastImage++
+		img.ID = s.lastImage
+		img.MessageID = msg.ID
+		s.images = append(s.images, &eribo.Image{
+			ID:          img.ID,
+			URL:         img.URL,
+			NormURL:     img.NormURL,
+			ContentType: img.ContentType,
+			Size:        img.Size,
+			Created:     msg.Created,
+			MessageID:   msg.ID,
+		})
+	}
+	return nil
+}
+
+func (s *Store) hasImage(normURL string) bool {
+	for _, img := range s.images {
+		if img.NormURL == normURL {
+			return true
+		}
+	}
+	return false
+}
+
+// image returns a copy of an image with its message.
+func (s *Store) image(img *eribo.Image) *eribo.Image {
+	c := *img
+	m := *s.messages[find(s.messages, img.MessageID, messageID)]
+	c.Message = &m
+	return &c
+}
+
+// find returns the index of the record with an ID in records, which are
+// ordered by ID, or -1.
+func find[T any](records []*T, id int64, idOf func(*T) int64) int {
+	i := sort.Search(len(records), func(i int) bool { return idOf(records[i]) >= id })
+	if i == len(records) || idOf(records[i]) != id {
+		return -1
+	}
+	return i
+}
+
+func messageID(m *eribo.Message) int64     { return m.ID }
+func imageID(img *eribo.Image) int64       { return img.ID }
+func feedbackID(f *eribo.Feedback) int64   { return f.ID }
+func replyID(r *eribo.FeedbackReply) int64 { return r.ID }
+
+func (s *Store) GetImages(limit, offset int, reverse, filterDone bool, contentType string) ([]*eribo.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	images := []*eribo.Image{}
+	for _, img := range s.images {
+		if filterDone && img.Done {
+			continue
+		}
+		if contentType != "" && img.ContentType != contentType {
+			continue
+		}
+		images = append(images, img)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if reverse {
+			return images[i].Created.After(images[j].Created)
+		}
+		return images[i].Created.Before(images[j].Created)
+	})
+	start, end := page(len(images), limit, offset)
+	images = images[start:end]
+	for i, img := range images {
+		images[i] = s.image(img)
+	}
+	return images, nil
+}
+
+func (s *Store) GetImage(id int64) (*eribo.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.images, id, imageID)
+	if i < 0 {
+		return nil, fmt.Errorf("image %d: %w", id, sql.ErrNoRows)
+	}
+	return s.image(s.images[i]), nil
+}
+
+func (s *Store) ListImages(q eribo.Query) ([]*eribo.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	images := []*eribo.Image{}
+	for i := len(s.images) - 1; i >= 0 && (q.Limit == 0 || len(images) < q.Limit); i-- {
+		img := s.image(s.images[i])
+		if matchChannel(q, img.ID, img.Created, img.Message.Channel, img.Message.Player) {
+			images = append(images, img)
+		}
+	}
+	return images, nil
+}
+
+func (s *Store) ToggleImageDone(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.images, id, imageID)
+	if i < 0 {
+		return fmt.Errorf("image %d: %w", id, sql.ErrNoRows)
+	}
+	s.images[i].Done = !s.images[i].Done
+	return nil
+}
+
+// SetImageKuid sets the kuid of an image and marks it done unless the kuid
+// is 0. Like an SQL update, it does nothing for a missing image.
+func (s *Store) SetImageKuid(id int64, kuid int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.images, id, imageID)
+	if i < 0 {
+		return nil
+	}
+	s.images[i].Kuid = kuid
+	s.images[i].Done = kuid != 0
+	return nil
+}
+
+func (s *Store) AddFeedback(f *eribo.Feedback) error {
+	if (f.Created == time.Time{}) {
+		f.Created = now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := f.Status
+	if status == "" {
+		status = eribo.FeedbackOpen
+	}
+	s.lastFeedback++
+	f.ID = s.lastFeedback
+	s.feedback = append(s.feedback, &eribo.Feedback{
+		ID:      s.lastFeedback,
+		Message: f.Message,
+		Player:  f.Player,
+		Created: f.Created.UTC(),
+		Status:  status,
+		Note:    f.Note,
+		Tags:    copyTags(f.Tags),
+	})
+	return nil
+}
+
+func (s *Store) GetAllFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start, end := page(len(s.feedback), limit, offset)
+	return copyFeedback(s.feedback[start:end]), nil
+}
+
+func (s *Store) GetRecentFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	feedback := recent(s.feedback, func(f *eribo.Feedback) time.Time { return f.Created })
+	start, end := page(len(feedback), limit, offset)
+	return copyFeedback(feedback[start:end]), nil
+}
+
+func (s *Store) ListFeedback(q eribo.Query) ([]*eribo.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyFeedback(list(s.feedback, q, func(f *eribo.Feedback) bool {
+		return q.Channel == "" && (q.Status == "" || f.Status == q.Status) && q.Match(f.ID, f.Created, "", f.Player)
+	})), nil
+}
+
+func (s *Store) GetFeedback(id int64) (*eribo.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.feedback, id, feedbackID)
+	if i < 0 {
+		return nil, fmt.Errorf("feedback %d: %w", id, sql.ErrNoRows)
+	}
+	return copyFeedback(s.feedback[i : i+1])[0], nil
+}
+
+func (s *Store) UpdateFeedback(f *eribo.Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.feedback, f.ID, feedbackID)
+	if i < 0 {
+		return fmt.Errorf("feedback %d: %w", f.ID, sql.ErrNoRows)
+	}
+	s.feedback[i].Status = f.Status
+	s.feedback[i].Note = f.Note
+	s.feedback[i].Tags = copyTags(f.Tags)
+	return nil
+}
+
+func (s *Store) AddFeedbackReply(r *eribo.FeedbackReply) error {
+	if (r.Created == time.Time{}) {
+		r.Created = now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.feedback, r.FeedbackID, feedbackID) < 0 {
+		return fmt.Errorf("feedback %d: %w", r.FeedbackID, sql.ErrNoRows)
+	}
+	s.lastReply++
+	r.ID = s.lastReply
+	c := *r
+	c.Created = r.Created.UTC()
+	s.replies = append(s.replies, &c)
+	return nil
+}
+
+func (s *Store) ListFeedbackReplies(feedbackID int64) ([]*eribo.FeedbackReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	replies := []*eribo.FeedbackReply{}
+	for _, r := range s.replies {
+		if r.FeedbackID == feedbackID {
+			c := *r
+			replies = append(replies, &c)
+		}
+	}
+	return replies, nil
+}
+
+func (s *Store) ListUnsentReplies(player string) ([]*eribo.FeedbackReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	replies := []*eribo.FeedbackReply{}
+	for _, r := range s.replies {
+		if r.Sent == nil && (player == "" || strings.EqualFold(r.Player, player)) {
+			c := *r
+			replies = append(replies, &c)
+		}
+	}
+	return replies, nil
+}
+
+// MarkReplySent sets when a reply was sent. Like an SQL update, it does
+// nothing for a missing reply.
+func (s *Store) MarkReplySent(id int64, sent time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := find(s.replies, id, replyID); i >= 0 {
+		t := sent.UTC().Truncate(timeTruncate)
+		s.replies[i].Sent = &t
+	}
+	return nil
+}
+
+func (s *Store) AddCmdLog(l *eribo.CmdLog) error {
+	if (l.Created == time.Time{}) {
+		l.Created = now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCmdLog++
+	l.ID = s.lastCmdLog
+	s.cmdLogs = append(s.cmdLogs, &eribo.CmdLog{
+		ID:      s.lastCmdLog,
+		Command: l.Command,
+		Args:    l.Args,
+		Player:  l.Player,
+		Channel: l.Channel,
+		Created: l.Created.UTC(),
+	})
+	return nil
+}
+
+func (s *Store) GetRecentCmdLogs(limit, offset int) ([]*eribo.CmdLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logs := recent(s.cmdLogs, func(l *eribo.CmdLog) time.Time { return l.Created })
+	start, end := page(len(logs), limit, offset)
+	return copyAll(logs[start:end]), nil
+}
+
+func (s *Store) ListCmdLogs(q eribo.Query) ([]*eribo.CmdLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return list(s.cmdLogs, q, func(l *eribo.CmdLog) bool {
+		if q.Command != eribo.CmdUnknown && l.Command != q.Command {
+			return false
+		}
+		return matchChannel(q, l.ID, l.Created, l.Channel, l.Player)
+	}), nil
+}
+
+// CmdStats returns the uses of each command, most used first and by command
+// name when the uses are equal.
+func (s *Store) CmdStats() ([]*eribo.CmdStat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uses := make(map[eribo.Command]int)
+	for _, l := range s.cmdLogs {
+		uses[l.Command]++
+	}
+	stats := []*eribo.CmdStat{}
+	for cmd, n := range uses {
+		stats = append(stats, &eribo.CmdStat{Command: cmd, Uses: n})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Uses != stats[j].Uses {
+			return stats[i].Uses > stats[j].Uses
+		}
+		return stats[i].Command.String() < stats[j].Command.String()
+	})
+	return stats, nil
+}
+
+// CmdUsage groups players and channels case insensitively like the SQL
+// stores, keyed by the first name seen.
+func (s *Store) CmdUsage(q eribo.CmdUsageQuery) ([]*eribo.CmdCount, error) {
+	var key func(l *eribo.CmdLog) string
+	switch q.By {
+	case eribo.ByCommand:
+		key = func(l *eribo.CmdLog) string { return l.Command.String() }
+	case eribo.ByChannel:
+		key = func(l *eribo.CmdLog) string { return l.Channel }
+	case eribo.ByPlayer:
+		key = func(l *eribo.CmdLog) string { return l.Player }
+	case eribo.ByDay:
+		key = func(l *eribo.CmdLog) string { return l.Created.UTC().Format("2006-01-02") }
+	case eribo.ByHour:
+		key = func(l *eribo.CmdLog) string { return l.Created.UTC().Format("15") }
+	case eribo.ByArg:
+		if q.Command == eribo.CmdUnknown {
+			return nil, eribo.ErrArgNeedsCommand
+		}
+	default:
+		return nil, fmt.Errorf("unknown grouping %q", q.By)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sel := q.Query
+	sel.BeforeID, sel.Limit = 0, 0
+	logs := list(s.cmdLogs, sel, func(l *eribo.CmdLog) bool {
+		if sel.Command != eribo.CmdUnknown && l.Command != sel.Command {
+			return false
+		}
+		return matchChannel(sel, l.ID, l.Created, l.Channel, l.Player)
+	})
+	if q.By == eribo.ByArg {
+		var args []string
+		for _, l := range logs {
+			args = append(args, l.Args)
+		}
+		return eribo.CountArgs(args, q.Limit), nil
+	}
+
+	// The logs are newest first so they are counted from the oldest to key
+	// the groups by the first name seen.
+	counts := []*eribo.CmdCount{}
+	groups := make(map[string]*eribo.CmdCount)
+	for i := len(logs) - 1; i >= 0; i-- {
+		k := key(logs[i])
+		c, ok := groups[strings.ToLower(k)]
+		if !ok {
+			c = &eribo.CmdCount{Key: k}
+			groups[strings.ToLower(k)] = c
+			counts = append(counts, c)
+		}
+		c.Uses++
+	}
+	return eribo.SortCmdCounts(counts, q.By, q.Limit), nil
+}
+
+// AddLothLog stores a loth log. Like the SQL stores, it keeps only the name,
+// role and status of the loth, and a log without a loth gets an empty one
+// that expires when the log was created.
+func (s *Store) AddLothLog(l *eribo.LothLog) error {
+	if (l.Created == time.Time{}) {
+		l.Created = now()
+	}
+	loth := &eribo.Loth{Player: &eribo.Player{}, Expires: l.Created.UTC()}
+	if l.Loth != nil {
+		if l.Loth.Player != nil {
+			loth.Player = &eribo.Player{Name: l.Loth.Name, Role: l.Loth.Role, Status: l.Loth.Status}
+		}
+		loth.Expires = l.Loth.Expires.UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastLothLog++
+	l.ID = s.lastLothLog
+	s.lothLogs = append(s.lothLogs, &eribo.LothLog{
+		ID:      s.lastLothLog,
+		Issuer:  l.Issuer,
+		Channel: l.Channel,
+		Created: l.Created.UTC(),
+		Loth:    loth,
+		IsNew:   l.IsNew,
+		Targets: copyTargets(l.Targets),
+	})
+	return nil
+}
+
+func (s *Store) GetRecentLothLogs(limit, offset int) ([]*eribo.LothLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logs := recent(s.lothLogs, func(l *eribo.LothLog) time.Time { return l.Created })
+	start, end := page(len(logs), limit, offset)
+	return copyLothLogs(logs[start:end]), nil
+}
+
+func (s *Store) ListLothLogs(q eribo.Query) ([]*eribo.LothLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyLothLogs(list(s.lothLogs, q, func(l *eribo.LothLog) bool {
+		return matchChannel(q, l.ID, l.Created, l.Channel, l.Issuer, l.Name)
+	})), nil
+}
+
+func (s *Store) DeleteBefore(table eribo.Table, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	switch table {
+	case eribo.TableMessages:
+		s.messages, n = remove(s.messages, func(m *eribo.Message) bool { return m.Created.Before(before) })
+		s.images, _ = remove(s.images, func(img *eribo.Image) bool { return find(s.messages, img.MessageID, messageID) < 0 })
+	case eribo.TableFeedback:
+		s.feedback, n = remove(s.feedback, func(f *eribo.Feedback) bool { return f.Created.Before(before) })
+		s.removeOrphanReplies()
+	case eribo.TableCmdLogs:
+		s.cmdLogs, n = remove(s.cmdLogs, func(l *eribo.CmdLog) bool { return l.Created.Before(before) })
+	case eribo.TableLothLogs:
+		s.lothLogs, n = remove(s.lothLogs, func(l *eribo.LothLog) bool { return l.Created.Before(before) })
+	default:
+		return 0, fmt.Errorf("unknown table %q", table)
+	}
+	return n, nil
+}
+
+func (s *Store) ForgetPlayer(name string, audit *eribo.AuditLog) (*eribo.ForgetResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &eribo.ForgetResult{}
+	is := func(player string) bool { return strings.EqualFold(player, name) }
+	for _, m := range s.messages {
+		if is(m.Player) {
+			m.Player = eribo.Forgotten
+			m.Message = ""
+			r.Messages++
+		}
+	}
+	s.feedback, r.Feedback = remove(s.feedback, func(f *eribo.Feedback) bool { return is(f.Player) })
+	s.removeOrphanReplies()
+	s.cmdLogs, r.CmdLogs = remove(s.cmdLogs, func(l *eribo.CmdLog) bool { return is(l.Player) })
+	for _, l := range s.lothLogs {
+		issuer, loth := is(l.Issuer), is(l.Name)
+		if issuer {
+			l.Issuer = eribo.Forgotten
+		}
+		if loth {
+			l.Loth.Player = &eribo.Player{Name: eribo.Forgotten}
+		}
+		if issuer || loth {
+			r.LothLogs++
+		}
+		if targets, ok := l.Targets.RemoveTarget(name); ok {
+			l.Targets = targets
+			r.Targets++
+		}
+	}
+	if audit != nil {
+		audit.Detail = r.String()
+		s.addAuditLog(audit)
+	}
+	return r, nil
+}
+
+func (s *Store) AddAuditLog(a *eribo.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addAuditLog(a)
+	return nil
+}
+
+func (s *Store) addAuditLog(a *eribo.AuditLog) {
+	if (a.Created == time.Time{}) {
+		a.Created = now()
+	}
+	s.lastAudit++
+	a.ID = s.lastAudit
+	s.audit = append(s.audit, &eribo.AuditLog{
+		ID:      s.lastAudit,
+		Action:  a.Action,
+		Actor:   a.Actor,
+		Detail:  a.Detail,
+		Created: a.Created.UTC(),
+	})
+}
+
+func (s *Store) ListAuditLogs(q eribo.Query) ([]*eribo.AuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return list(s.audit, q, func(a *eribo.AuditLog) bool {
+		return q.Channel == "" && q.Match(a.ID, a.Created, "", a.Actor)
+	}), nil
+}
+
+// removeOrphanReplies removes the replies to feedback that was removed.
+func (s *Store) removeOrphanReplies() {
+	s.replies, _ = remove(s.replies, func(r *eribo.FeedbackReply) bool { return find(s.feedback, r.FeedbackID, feedbackID) < 0 })
+}
+
+// remove returns the records without the ones to remove and how many it
+// removed.
+func remove[T any](records []*T, removed func(*T) bool) ([]*T, int64) {
+	kept := records[:0]
+	for _, r := range records {
+		if !removed(r) {
+			kept = append(kept, r)
+		}
+	}
+	n := int64(len(records) - len(kept))
+	clear(records[len(kept):])
+	return kept, n
+}
+
+// matchChannel is Query.Match with channels compared case insensitively, as
+// they are by the SQL stores.
+func matchChannel(q eribo.Query, id int64, created time.Time, channel string, players ...string) bool {
+	if q.Channel != "" && !strings.EqualFold(channel, q.Channel) {
+		return false
+	}
+	q.Channel = ""
+	return q.Match(id, created, channel, players...)
+}
+
+// recent returns the records newest first and by descending ID when they
+// were created at the same time.
+func recent[T any](records []*T, created func(*T) time.Time) []*T {
+	sorted := make([]*T, len(records))
+	for i := range records {
+		sorted[len(records)-1-i] = records[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return created(sorted[i]).After(created(sorted[j])) })
+	return sorted
+}
+
+// list returns copies of the records that match, by descending ID and up to
+// the limit of the query.
+func list[T any](records []*T, q eribo.Query, match func(*T) bool) []*T {
+	matched := []*T{}
+	for i := len(records) - 1; i >= 0 && (q.Limit == 0 || len(matched) < q.Limit); i-- {
+		if match(records[i]) {
+			c := *records[i]
+			matched = append(matched, &c)
+		}
+	}
+	return matched
+}
+
+func copyAll[T any](records []*T) []*T {
+	copies := make([]*T, len(records))
+	for i, r := range records {
+		c := *r
+		copies[i] = &c
+	}
+	return copies
+}
+
+func copyFeedback(feedback []*eribo.Feedback) []*eribo.Feedback {
+	copies := copyAll(feedback)
+	for _, f := range copies {
+		f.Tags = copyTags(f.Tags)
+	}
+	return copies
+}
+
+// copyTags copies tags, which are nil when there are none like the SQL stores
+// return them.
+func copyTags(tags eribo.Tags) eribo.Tags {
+	if len(tags) == 0 {
+		return nil
+	}
+	return append(eribo.Tags{}, tags...)
+}
+
+func copyLothLogs(logs []*eribo.LothLog) []*eribo.LothLog {
+	copies := copyAll(logs)
+	for _, l := range copies {
+		p := *l.Loth.Player
+		l.Loth = &eribo.Loth{Player: &p, Expires: l.Loth.Expires}
+		l.Targets = copyTargets(l.Targets)
+	}
+	return copies
+}
+
+func copyTargets(targets eribo.Targets) eribo.Targets {
+	if targets == nil {
+		return nil
+	}
+	copies := make(eribo.Targets, len(targets))
+	for i, p := range targets {
+		c := *p
+		copies[i] = &c
+	}
+	return copies
+}
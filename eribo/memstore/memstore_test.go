@@ -0,0 +1,65 @@
+package memstore
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) eribo.Store { return New() })
+}
+
+func TestStore_concurrent(t *testing.T) {
+	s := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m := &eribo.Message{Channel: "room", Player: "foo"}
+			if err := s.AddMessageWithImages(m, []*eribo.Image{{URL: fmt.Sprintf("http://%d", i)}}); err != nil {
+				t.Error(err)
+			}
+			if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo"}); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.GetImages(10, 0, true, false, ""); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	images, err := s.ListImages(eribo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 10 {
+		t.Errorf("ListImages = %d images, want 10", len(images))
+	}
+	for i, img := range images {
+		if want := int64(10 - i); img.ID != want || img.Message.ID != img.MessageID {
+			t.Errorf("image %d has ID %d and message %d of %d, want ID %d", i, img.ID, img.Message.ID, img.MessageID, want)
+		}
+	}
+}
+
+func TestStore_copies(t *testing.T) {
+	s := New()
+	if err := s.AddMessageWithImages(&eribo.Message{Player: "foo"}, []*eribo.Image{{URL: "http://1"}}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := s.GetImage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img.Done = true
+	img.Message.Player = "bar"
+	if img, _ = s.GetImage(1); img.Done || img.Message.Player != "foo" {
+		t.Errorf("changing a returned image changed the stored one: %+v", img)
+	}
+}
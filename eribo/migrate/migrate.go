@@ -0,0 +1,202 @@
+// Package migrate applies and rolls back the numbered schema migrations of
+// the SQL stores. The versions applied to a database are recorded in its
+// schema_migrations table and the version of the database is the highest of
+// them, 0 for a database without migrations.
+//
+// Each migration runs in a transaction with the record of its version. Where
+// the database commits schema changes implicitly, as MySQL does, a migration
+// that fails half way has to be fixed by hand.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration changes the schema from the previous version to Version with Up
+// and back with Down.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sqlx.Tx) error
+	Down    func(tx *sqlx.Tx) error
+}
+
+// SQL returns a migration step that executes the statements in order.
+func SQL(statements ...string) func(tx *sqlx.Tx) error {
+	return func(tx *sqlx.Tx) error {
+		for _, s := range statements {
+			if _, err := tx.Exec(s); err != nil {
+				return fmt.Errorf("%v: %s", err, strings.TrimSpace(s))
+			}
+		}
+		return nil
+	}
+}
+
+// ErrNewerSchema is returned when the database has a version that the
+// migrations do not know, i.e. it was migrated by a newer version of the bot.
+var ErrNewerSchema = errors.New("database schema is newer than this version of eribo")
+
+// Applied is a migration version recorded in schema_migrations.
+type Applied struct {
+	Version int       `db:"version"`
+	Name    string    `db:"name"`
+	Applied time.Time `db:"applied"`
+}
+
+const tableSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migrator migrates a database with a list of migrations.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// New returns a migrator for the migrations, whose versions must be 1, 2, 3
+// and so on.
+func New(db *sqlx.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) validate() error {
+	for i, mig := range m.migrations {
+		if mig.Version != i+1 {
+			return fmt.Errorf("migration %q has version %d, want %d", mig.Name, mig.Version, i+1)
+		}
+		if mig.Up == nil || mig.Down == nil {
+			return fmt.Errorf("migration %d %q needs both up and down", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Latest returns the version of the last migration.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Migrations returns the migrations of the migrator.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+func (m *Migrator) init() error {
+	if _, err := m.db.Exec(tableSchemaMigrations); err != nil {
+		return fmt.Errorf("creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// Version returns the version of the database.
+func (m *Migrator) Version() (int, error) {
+	if err := m.init(); err != nil {
+		return 0, err
+	}
+	var v int
+	if err := m.db.Get(&v, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+		return 0, fmt.Errorf("getting schema version: %v", err)
+	}
+	return v, nil
+}
+
+// Applied returns the migrations recorded in the database by version.
+func (m *Migrator) Applied() ([]Applied, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+	applied := []Applied{}
+	if err := m.db.Select(&applied, `SELECT version, name, applied FROM schema_migrations ORDER BY version`); err != nil {
+		return nil, fmt.Errorf("getting applied migrations: %v", err)
+	}
+	for i := range applied {
+		applied[i].Applied = applied[i].Applied.UTC()
+	}
+	return applied, nil
+}
+
+// Check returns ErrNewerSchema if the database is newer than the latest
+// migration.
+func (m *Migrator) Check() error {
+	v, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if v > m.Latest() {
+		return fmt.Errorf("%w: version %d, latest known %d", ErrNewerSchema, v, m.Latest())
+	}
+	return nil
+}
+
+// Up applies all the migrations that the database does not have yet.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// To migrates the database up or down to a version. Migrating down to 0 rolls
+// back all migrations.
+func (m *Migrator) To(version int) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		return err
+	}
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown version %d, want 0 to %d", version, m.Latest())
+	}
+	v, err := m.Version()
+	if err != nil {
+		return err
+	}
+	for ; v < version; v++ {
+		if err := m.apply(m.migrations[v], true); err != nil {
+			return err
+		}
+	}
+	for ; v > version; v-- {
+		if err := m.apply(m.migrations[v-1], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(mig Migration, up bool) (err error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				err = fmt.Errorf("rollback failed: %v: %v", rerr, err)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if up {
+		if err := mig.Up(tx); err != nil {
+			return fmt.Errorf("migration %d %q up: %v", mig.Version, mig.Name, err)
+		}
+		const query = `INSERT INTO schema_migrations(version, name) VALUES (?, ?)`
+		_, err = tx.Exec(tx.Rebind(query), mig.Version, mig.Name)
+		return err
+	}
+	if err := mig.Down(tx); err != nil {
+		return fmt.Errorf("migration %d %q down: %v", mig.Version, mig.Name, err)
+	}
+	_, err = tx.Exec(tx.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), mig.Version)
+	return err
+}
@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func setup(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var testMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create foo",
+		Up:      SQL(`CREATE TABLE foo (id INTEGER PRIMARY KEY)`),
+		Down:    SQL(`DROP TABLE foo`),
+	},
+	{
+		Version: 2,
+		Name:    "create bar",
+		Up:      SQL(`CREATE TABLE bar (id INTEGER PRIMARY KEY)`, `CREATE INDEX bar_id ON bar(id)`),
+		Down:    SQL(`DROP TABLE bar`),
+	},
+}
+
+func tables(t *testing.T, db *sqlx.DB) []string {
+	t.Helper()
+	names := []string{}
+	const query = `SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' ORDER BY name`
+	if err := db.Select(&names, query); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func version(t *testing.T, m *Migrator) int {
+	t.Helper()
+	v, err := m.Version()
+	if err != nil {
+		t.Fatal("Version failed:", err)
+	}
+	return v
+}
+
+func TestMigrator_To(t *testing.T) {
+	db := setup(t)
+	m := New(db, testMigrations)
+	if got, want := version(t, m), 0; got != want {
+		t.Fatalf("new database version = %d, want %d", got, want)
+	}
+
+	tests := []struct {
+		to         int
+		wantTables []string
+	}{
+		{1, []string{"foo"}},
+		{2, []string{"bar", "foo"}},
+		{2, []string{"bar", "foo"}},
+		{1, []string{"foo"}},
+		{0, []string{}},
+		{2, []string{"bar", "foo"}},
+	}
+	for _, tt := range tests {
+		if err := m.To(tt.to); err != nil {
+			t.Fatalf("To(%d) failed: %v", tt.to, err)
+		}
+		if got := version(t, m); got != tt.to {
+			t.Errorf("To(%d) -> version %d, want %d", tt.to, got, tt.to)
+		}
+		if got := tables(t, db); !reflect.DeepEqual(got, tt.wantTables) {
+			t.Errorf("To(%d) -> tables %q, want %q", tt.to, got, tt.wantTables)
+		}
+	}
+
+	applied, err := m.Applied()
+	if err != nil {
+		t.Fatal("Applied failed:", err)
+	}
+	var names []string
+	for _, a := range applied {
+		names = append(names, a.Name)
+		if a.Applied.IsZero() {
+			t.Errorf("migration %d has no applied time", a.Version)
+		}
+	}
+	if want := []string{"create foo", "create bar"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Applied names = %q, want %q", names, want)
+	}
+}
+
+func TestMigrator_Up_failure(t *testing.T) {
+	db := setup(t)
+	migrations := append(testMigrations[:1:1], Migration{
+		Version: 2,
+		Name:    "broken",
+		Up:      SQL(`CREATE TABLE bar (id INTEGER PRIMARY KEY)`, `NOT SQL`),
+		Down:    SQL(`DROP TABLE bar`),
+	})
+	m := New(db, migrations)
+	if err := m.Up(); err == nil {
+		t.Fatal("Up with a broken migration succeeded")
+	}
+	if got, want := version(t, m), 1; got != want {
+		t.Errorf("version after broken migration = %d, want %d", got, want)
+	}
+	if got, want := tables(t, db), []string{"foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tables after broken migration = %q, want %q", got, want)
+	}
+}
+
+func TestMigrator_newerSchema(t *testing.T) {
+	db := setup(t)
+	if err := New(db, testMigrations).Up(); err != nil {
+		t.Fatal("Up failed:", err)
+	}
+
+	m := New(db, testMigrations[:1])
+	if err := m.Check(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Check on newer schema = %v, want %v", err, ErrNewerSchema)
+	}
+	if err := m.Up(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Up on newer schema = %v, want %v", err, ErrNewerSchema)
+	}
+	if err := m.To(0); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("To(0) on newer schema = %v, want %v", err, ErrNewerSchema)
+	}
+	if got, want := version(t, m), 2; got != want {
+		t.Errorf("version = %d, want %d", got, want)
+	}
+}
+
+func TestMigrator_To_invalid(t *testing.T) {
+	db := setup(t)
+	noDown := []Migration{{Version: 1, Name: "no down", Up: SQL()}}
+	gap := []Migration{testMigrations[1]}
+	tests := []struct {
+		name       string
+		migrations []Migration
+		to         int
+	}{
+		{"negative version", testMigrations, -1},
+		{"unknown version", testMigrations, 3},
+		{"missing down", noDown, 1},
+		{"versions not from 1", gap, 1},
+	}
+	for _, tt := range tests {
+		if err := New(db, tt.migrations).To(tt.to); err == nil {
+			t.Errorf("%s: To(%d) succeeded", tt.name, tt.to)
+		}
+	}
+	if got, want := tables(t, db), []string{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tables after invalid migrations = %q, want %q", got, want)
+	}
+}
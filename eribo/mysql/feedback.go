@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func (db *EriboStore) GetFeedback(id int64) (*eribo.Feedback, error) {
+	f := &eribo.Feedback{}
+	if err := db.Get(f, `SELECT * FROM feedback WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// UpdateFeedback returns sql.ErrNoRows for missing feedback like the other
+// stores. MySQL reports only the rows that changed as affected so the
+// feedback is looked up first.
+func (db *EriboStore) UpdateFeedback(f *eribo.Feedback) error {
+	return db.Tx(func(tx *sqlx.Tx) error {
+		var n int
+		if err := tx.Get(&n, `SELECT COUNT(*) FROM feedback WHERE id = ?`, f.ID); err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("feedback %d: %w", f.ID, sql.ErrNoRows)
+		}
+		const query = `UPDATE feedback SET status = ?, note = ?, tags = ? WHERE id = ?`
+		_, err := tx.Exec(query, f.Status, f.Note, f.Tags, f.ID)
+		return err
+	})
+}
+
+func (db *EriboStore) AddFeedbackReply(r *eribo.FeedbackReply) error {
+	if (r.Created == time.Time{}) {
+		r.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	const query = `INSERT INTO feedback_replies(feedback_id, player, message, created) VALUES (?, ?, ?, ?)`
+	res, err := db.Exec(query, r.FeedbackID, r.Player, r.Message, r.Created.UTC())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = id
+	return nil
+}
+
+func (db *EriboStore) ListFeedbackReplies(feedbackID int64) ([]*eribo.FeedbackReply, error) {
+	replies := []*eribo.FeedbackReply{}
+	const query = `SELECT * FROM feedback_replies WHERE feedback_id = ? ORDER BY id`
+	if err := db.Select(&replies, query, feedbackID); err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+func (db *EriboStore) ListUnsentReplies(player string) ([]*eribo.FeedbackReply, error) {
+	replies := []*eribo.FeedbackReply{}
+	const query = `SELECT * FROM feedback_replies WHERE sent IS NULL AND (? = '' OR player = ?) ORDER BY id`
+	if err := db.Select(&replies, query, player, player); err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+func (db *EriboStore) MarkReplySent(id int64, sent time.Time) error {
+	_, err := db.Exec(`UPDATE feedback_replies SET sent = ? WHERE id = ?`, sent.UTC().Truncate(timeTruncate), id)
+	return err
+}
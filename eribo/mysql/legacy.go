@@ -1,7 +0,0 @@
-// +build !prod
-
-package mysql
-
-func legacyDefault() string {
-	return "DEFAULT CURRENT_TIMESTAMP"
-}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kusubooru/eribo/eribo"
@@ -12,8 +13,16 @@ func (db *EriboStore) AddCmdLog(l *eribo.CmdLog) error {
 		l.Created = time.Now().UTC().Truncate(timeTruncate)
 	}
 	const query = `INSERT INTO cmd_logs(command, args, player, channel, created) VALUES (?, ?, ?, ?, ?)`
-	_, err := db.Exec(query, l.Command, l.Args, l.Player, l.Channel, l.Created)
-	return err
+	res, err := db.Exec(query, l.Command, l.Args, l.Player, l.Channel, l.Created)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	return nil
 }
 
 func (db *EriboStore) GetCmdLog(id int64) (*eribo.CmdLog, error) {
@@ -27,7 +36,7 @@ func (db *EriboStore) GetCmdLog(id int64) (*eribo.CmdLog, error) {
 
 func (db *EriboStore) GetRecentCmdLogs(limit, offset int) ([]*eribo.CmdLog, error) {
 	logs := []*eribo.CmdLog{}
-	const query = `SELECT * FROM cmd_logs ORDER BY created DESC LIMIT ? OFFSET ?`
+	const query = `SELECT * FROM cmd_logs ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`
 	if err := db.Select(&logs, query, limit, offset); err != nil {
 		return nil, err
 	}
@@ -36,13 +45,57 @@ func (db *EriboStore) GetRecentCmdLogs(limit, offset int) ([]*eribo.CmdLog, erro
 
 func (db *EriboStore) CmdStats() ([]*eribo.CmdStat, error) {
 	stats := []*eribo.CmdStat{}
-	const query = `SELECT command, COUNT(command) AS uses FROM cmd_logs GROUP BY command ORDER BY uses DESC`
+	const query = `SELECT command, COUNT(command) AS uses FROM cmd_logs GROUP BY command ORDER BY uses DESC, command`
 	if err := db.Select(&stats, query); err != nil {
 		return nil, err
 	}
 	return stats, nil
 }
 
+// cmdUsageKeys are the expressions of the keys that cmd_logs are grouped by.
+// Times are formatted in the time zone they are written in, which is UTC.
+var cmdUsageKeys = map[eribo.CmdGrouping]string{
+	eribo.ByCommand: "command",
+	eribo.ByChannel: "channel",
+	eribo.ByPlayer:  "player",
+	eribo.ByDay:     "DATE_FORMAT(created, '%Y-%m-%d')",
+	eribo.ByHour:    "DATE_FORMAT(created, '%H')",
+}
+
+func (db *EriboStore) CmdUsage(q eribo.CmdUsageQuery) ([]*eribo.CmdCount, error) {
+	conds, args := conditions(q.Query, columns{id: "id", created: "created", channel: "channel", command: "command", players: []string{"player"}})
+	if q.By == eribo.ByArg {
+		if q.Command == eribo.CmdUnknown {
+			return nil, eribo.ErrArgNeedsCommand
+		}
+		all := []string{}
+		if err := db.Select(&all, `SELECT args FROM cmd_logs`+whereClause(append(conds, "args != ''")), args...); err != nil {
+			return nil, err
+		}
+		return eribo.CountArgs(all, q.Limit), nil
+	}
+	key, ok := cmdUsageKeys[q.By]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %q", q.By)
+	}
+	// Names are grouped case insensitively but ordered by their bytes like
+	// the other stores.
+	order := " ORDER BY uses DESC, CAST(" + key + " AS BINARY)"
+	if q.By.Histogram() {
+		order = " ORDER BY `key`"
+	}
+	query := "SELECT " + key + " AS `key`, COUNT(*) AS uses FROM cmd_logs" + whereClause(conds) + " GROUP BY " + key + order
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	counts := []*eribo.CmdCount{}
+	if err := db.Select(&counts, query, args...); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
 func (db *EriboStore) AddLothLog(l *eribo.LothLog) error {
 	if (l.Created == time.Time{}) {
 		l.Created = time.Now().UTC().Truncate(timeTruncate)
@@ -63,13 +116,21 @@ func (db *EriboStore) AddLothLog(l *eribo.LothLog) error {
 	const query = `INSERT INTO
 	loth_logs(issuer, channel, created, name, role, status, expires, is_new, targets)
 	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := db.Exec(query, l.Issuer, l.Channel, l.Created, name, role, status, expires, l.IsNew, l.Targets)
-	return err
+	res, err := db.Exec(query, l.Issuer, l.Channel, l.Created, name, role, status, expires, l.IsNew, l.Targets)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	return nil
 }
 
 func (db *EriboStore) GetRecentLothLogs(limit, offset int) ([]*eribo.LothLog, error) {
 	logs := []*eribo.LothLog{}
-	const query = `SELECT * FROM loth_logs ORDER BY created DESC LIMIT ? OFFSET ?`
+	const query = `SELECT * FROM loth_logs ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`
 	if err := db.Select(&logs, query, limit, offset); err != nil {
 		return nil, err
 	}
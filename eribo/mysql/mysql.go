@@ -1,7 +1,7 @@
 package mysql
 
 import (
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -11,21 +11,33 @@ type EriboStore struct {
 	*sqlx.DB
 }
 
+// NewEriboStore opens the store and applies the migrations that the database
+// does not have yet. It fails if the database schema is newer than the
+// migrations.
 func NewEriboStore(dataSource string) (*EriboStore, error) {
-	db, err := sqlx.Open("mysql", dataSource)
+	store, err := Open(dataSource)
 	if err != nil {
 		return nil, err
 	}
-	if err := pingDatabase(db); err != nil {
-		log.Fatalln("database ping attempts failed:", err)
-	}
-	store := &EriboStore{DB: db}
-	if err := store.createSchema(); err != nil {
+	if err := store.Migrator().Up(); err != nil {
+		store.Close()
 		return nil, err
 	}
 	return store, nil
 }
 
+// Open opens the store without migrating the database.
+func Open(dataSource string) (*EriboStore, error) {
+	db, err := sqlx.Open("mysql", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if err := pingDatabase(db); err != nil {
+		return nil, fmt.Errorf("database ping attempts failed: %v", err)
+	}
+	return &EriboStore{DB: db}, nil
+}
+
 func pingDatabase(db *sqlx.DB) (err error) {
 	for i := 0; i < 10; i++ {
 		err = db.Ping()
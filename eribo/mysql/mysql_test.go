@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/storetest"
 )
 
 var (
@@ -16,10 +18,6 @@ var (
 	dbname = flag.String("dbname", "eribo_test", "test database to use to run the tests")
 )
 
-func init() {
-	flag.Parse()
-}
-
 func setup(t *testing.T) *EriboStore {
 	if *pass == "" {
 		t.Logf("No password provided for user %q to connect to MySQL and run the tests.", *user)
@@ -35,7 +33,15 @@ func setup(t *testing.T) *EriboStore {
 }
 
 func teardown(t *testing.T, s *EriboStore) {
-	if err := s.dropSchema(); err != nil {
+	if err := s.Migrator().To(0); err != nil {
 		t.Error(err)
 	}
 }
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) eribo.Store {
+		s := setup(t)
+		t.Cleanup(func() { teardown(t, s) })
+		return s
+	})
+}
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"strings"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+// columns are the columns of a table that a query filters on. A query for a
+// player matches any of the player columns and an empty channel column means
+// the table has no channel. Only the command logs have a command column and
+// only the feedback has a status column.
+type columns struct {
+	id      string
+	created string
+	channel string
+	command string
+	status  string
+	players []string
+}
+
+// where returns the WHERE, ORDER BY and LIMIT clauses of a query with their
+// arguments.
+func where(q eribo.Query, cols columns) (string, []interface{}) {
+	conds, args := conditions(q, cols)
+	clause := whereClause(conds)
+	clause += " ORDER BY " + cols.id + " DESC"
+	if q.Limit > 0 {
+		clause += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	return clause, args
+}
+
+// whereClause returns the WHERE clause of conditions, which is empty if there
+// are none.
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// conditions returns the conditions of a query, except for its limit, with
+// their arguments.
+func conditions(q eribo.Query, cols columns) ([]string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if q.BeforeID != 0 {
+		conds = append(conds, cols.id+" < ?")
+		args = append(args, q.BeforeID)
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, cols.created+" >= ?")
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, cols.created+" < ?")
+		args = append(args, q.Until.UTC())
+	}
+	if q.Channel != "" {
+		if cols.channel == "" {
+			conds = append(conds, "FALSE")
+		} else {
+			conds = append(conds, cols.channel+" = ?")
+			args = append(args, q.Channel)
+		}
+	}
+	if q.Command != eribo.CmdUnknown && cols.command != "" {
+		conds = append(conds, cols.command+" = ?")
+		args = append(args, q.Command)
+	}
+	if q.Status != "" && cols.status != "" {
+		conds = append(conds, cols.status+" = ?")
+		args = append(args, q.Status)
+	}
+	if q.Player != "" {
+		var or []string
+		for _, c := range cols.players {
+			or = append(or, c+" = ?")
+			args = append(args, q.Player)
+		}
+		conds = append(conds, "("+strings.Join(or, " OR ")+")")
+	}
+	return conds, args
+}
+
+func (db *EriboStore) ListImages(q eribo.Query) ([]*eribo.Image, error) {
+	clause, args := where(q, columns{id: "img.id", created: "img.created", channel: "m.channel", players: []string{"m.player"}})
+	query := `
+	SELECT
+	  ` + imageColumns + `,
+	  m.id as "message.id",
+	  m.player as "message.player",
+	  m.channel as "message.channel",
+	  m.message as "message.message",
+	  m.created as "message.created"
+	FROM images img
+	  JOIN messages m ON img.message_id=m.id` + clause
+
+	images := []*eribo.Image{}
+	if err := db.Select(&images, query, args...); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (db *EriboStore) ListFeedback(q eribo.Query) ([]*eribo.Feedback, error) {
+	clause, args := where(q, columns{id: "id", created: "created", status: "status", players: []string{"player"}})
+	feedback := []*eribo.Feedback{}
+	if err := db.Select(&feedback, `SELECT * FROM feedback`+clause, args...); err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+func (db *EriboStore) ListCmdLogs(q eribo.Query) ([]*eribo.CmdLog, error) {
+	clause, args := where(q, columns{id: "id", created: "created", channel: "channel", command: "command", players: []string{"player"}})
+	logs := []*eribo.CmdLog{}
+	if err := db.Select(&logs, `SELECT * FROM cmd_logs`+clause, args...); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (db *EriboStore) ListLothLogs(q eribo.Query) ([]*eribo.LothLog, error) {
+	clause, args := where(q, columns{id: "id", created: "created", channel: "channel", players: []string{"issuer", "name"}})
+	logs := []*eribo.LothLog{}
+	if err := db.Select(&logs, `SELECT * FROM loth_logs`+clause, args...); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
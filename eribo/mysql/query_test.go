@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func TestListCmdLogs(t *testing.T) {
+	s := setup(t)
+	defer teardown(t, s)
+
+	day := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	logs := []*eribo.CmdLog{
+		{Command: eribo.CmdTomato, Player: "foo", Channel: "room", Created: day},
+		{Command: eribo.CmdTieup, Player: "bar", Channel: "room", Created: day.Add(time.Hour)},
+		{Command: eribo.CmdTomato, Player: "foo", Channel: "other", Created: day.Add(2 * time.Hour)},
+		{Command: eribo.CmdTomato, Player: "Foo", Channel: "room", Created: day.Add(3 * time.Hour)},
+	}
+	for _, l := range logs {
+		if err := s.AddCmdLog(l); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+
+	var tests = []struct {
+		q    eribo.Query
+		want []int64
+	}{
+		{eribo.Query{}, []int64{4, 3, 2, 1}},
+		{eribo.Query{Limit: 2}, []int64{4, 3}},
+		{eribo.Query{BeforeID: 3, Limit: 2}, []int64{2, 1}},
+		{eribo.Query{Player: "foo"}, []int64{4, 3, 1}},
+		{eribo.Query{Player: "foo", Channel: "room"}, []int64{4, 1}},
+		{eribo.Query{Since: day.Add(time.Hour), Until: day.Add(3 * time.Hour)}, []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		have, err := s.ListCmdLogs(tt.q)
+		if err != nil {
+			t.Fatalf("ListCmdLogs(%+v) failed: %v", tt.q, err)
+		}
+		var ids []int64
+		for _, l := range have {
+			ids = append(ids, l.ID)
+		}
+		deepEqual(t, ids, tt.want, "listing cmd logs")
+	}
+}
+
+func TestListFeedback_channel(t *testing.T) {
+	s := setup(t)
+	defer teardown(t, s)
+
+	if err := s.AddFeedback(&eribo.Feedback{Player: "foo", Message: "bar"}); err != nil {
+		t.Fatal("AddFeedback failed:", err)
+	}
+	have, err := s.ListFeedback(eribo.Query{Channel: "room"})
+	if err != nil {
+		t.Fatal("ListFeedback failed:", err)
+	}
+	if len(have) != 0 {
+		t.Errorf("ListFeedback for a channel = %d feedback, want none", len(have))
+	}
+}
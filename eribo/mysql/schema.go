@@ -1,39 +1,164 @@
 package mysql
 
-func (db *EriboStore) createSchema() error {
-	if _, err := db.Exec(tableMessages); err != nil {
-		return err
-	}
-	if _, err := db.Exec(tableImages); err != nil {
-		return err
-	}
-	if _, err := db.Exec(tableFeedback); err != nil {
-		return err
-	}
-	if _, err := db.Exec(tableCmdLogs); err != nil {
-		return err
-	}
-	if _, err := db.Exec(tableLothLogs); err != nil {
-		return err
-	}
-	return nil
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo/migrate"
+)
+
+// migrations are the versions of the schema. Databases created before
+// migrations existed already have some of the tables and columns, which is
+// why tables are created only if they do not exist and columns are added
+// only if they are missing.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create tables",
+		Up:      migrate.SQL(tableMessages, tableImages, tableFeedback, tableCmdLogs, tableLothLogs),
+		Down: migrate.SQL(
+			`DROP TABLE loth_logs`,
+			`DROP TABLE cmd_logs`,
+			`DROP TABLE feedback`,
+			`DROP TABLE images`,
+			`DROP TABLE messages`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "add image content type and size",
+		Up: func(tx *sqlx.Tx) error {
+			if err := addColumn(tx, "images", "content_type", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			return addColumn(tx, "images", "size", "BIGINT NOT NULL DEFAULT 0")
+		},
+		Down: migrate.SQL(`ALTER TABLE images DROP COLUMN content_type, DROP COLUMN size`),
+	},
+	{
+		Version: 3,
+		Name:    "create audit_logs",
+		Up:      migrate.SQL(tableAuditLogs),
+		Down:    migrate.SQL(`DROP TABLE audit_logs`),
+	},
+	{
+		Version: 4,
+		Name:    "add feedback status, note, tags and replies",
+		Up: migrate.SQL(
+			`ALTER TABLE feedback
+			  ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'open',
+			  ADD COLUMN note VARCHAR(2000) NOT NULL DEFAULT '',
+			  ADD COLUMN tags VARCHAR(2000) NOT NULL DEFAULT '[]'`,
+			tableFeedbackReplies,
+		),
+		Down: migrate.SQL(
+			`DROP TABLE feedback_replies`,
+			`ALTER TABLE feedback DROP COLUMN status, DROP COLUMN note, DROP COLUMN tags`,
+		),
+	},
+	{
+		Version: 5,
+		Name:    "add cmd_logs indexes",
+		Up: migrate.SQL(
+			`ALTER TABLE cmd_logs
+			  ADD INDEX cmd_logs_created (created),
+			  ADD INDEX cmd_logs_command (command, created),
+			  ADD INDEX cmd_logs_player (player, created),
+			  ADD INDEX cmd_logs_channel (channel, created)`,
+		),
+		Down: migrate.SQL(
+			`ALTER TABLE cmd_logs
+			  DROP INDEX cmd_logs_created,
+			  DROP INDEX cmd_logs_command,
+			  DROP INDEX cmd_logs_player,
+			  DROP INDEX cmd_logs_channel`,
+		),
+	},
+	{
+		// Reposts are told apart by the normalized URL, whose hash is
+		// indexed since the URL is too long for a unique index. Images
+		// stored before are not normalized again, so their URL stands in
+		// for it. Images stored twice by concurrent messages are merged
+		// into the first one.
+		Version: 6,
+		Name:    "make image urls unique",
+		Up: func(tx *sqlx.Tx) error {
+			if err := addColumn(tx, "images", "norm_url", "VARCHAR(2000) NULL"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "images", "norm_url_hash", "BINARY(32) NULL"); err != nil {
+				return err
+			}
+			err := migrate.SQL(`UPDATE images SET norm_url = url, norm_url_hash = UNHEX(SHA2(url, 256))`)(tx)
+			if err != nil {
+				return err
+			}
+			if err := dedupImages(tx); err != nil {
+				return err
+			}
+			return migrate.SQL(
+				`ALTER TABLE images
+				  MODIFY norm_url VARCHAR(2000) NOT NULL,
+				  MODIFY norm_url_hash BINARY(32) NOT NULL,
+				  ADD UNIQUE INDEX images_norm_url_hash (norm_url_hash)`,
+			)(tx)
+		},
+		Down: migrate.SQL(`ALTER TABLE images DROP INDEX images_norm_url_hash, DROP COLUMN norm_url_hash, DROP COLUMN norm_url`),
+	},
 }
 
-func (db *EriboStore) dropSchema() error {
-	if _, err := db.Exec(`DROP TABLE images`); err != nil {
+// Migrator returns the migrator of the database schema.
+func (db *EriboStore) Migrator() *migrate.Migrator {
+	return migrate.New(db.DB, migrations)
+}
+
+// dedupImages keeps the first of the images with the same normalized URL and
+// deletes the others. The first one takes their done flag and kuid so that no
+// upload is lost. Images uploaded as different posts cannot be merged and fail
+// the migration until their kuids are fixed by hand.
+func dedupImages(tx *sqlx.Tx) error {
+	const selectConflicts = `
+	SELECT a.id AS a, b.id AS b
+	FROM images a JOIN images b ON a.norm_url_hash = b.norm_url_hash AND a.id < b.id
+	WHERE a.kuid != 0 AND b.kuid != 0 AND a.kuid != b.kuid
+	ORDER BY a.id, b.id`
+	var conflicts []struct{ A, B int64 }
+	if err := tx.Select(&conflicts, selectConflicts); err != nil {
 		return err
 	}
-	if _, err := db.Exec(`DROP TABLE messages`); err != nil {
-		return err
+	if len(conflicts) != 0 {
+		pairs := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			pairs[i] = fmt.Sprintf("%d and %d", c.A, c.B)
+		}
+		return fmt.Errorf("images with the same url have different kuids: %s", strings.Join(pairs, ", "))
 	}
-	if _, err := db.Exec(`DROP TABLE feedback`); err != nil {
-		return err
+
+	const merge = `
+	UPDATE images a JOIN (
+	  SELECT MIN(id) AS id, MAX(done) AS done, MAX(kuid) AS kuid
+	  FROM images GROUP BY norm_url_hash HAVING COUNT(*) > 1
+	) d ON a.id = d.id
+	SET a.done = d.done, a.kuid = d.kuid`
+	return migrate.SQL(merge, `DELETE b FROM images a JOIN images b ON a.norm_url_hash = b.norm_url_hash AND a.id < b.id`)(tx)
+}
+
+// addColumn adds a column to a table that was created before the column
+// existed.
+func addColumn(tx *sqlx.Tx, table, column, definition string) error {
+	const query = `
+	SELECT COUNT(*) FROM information_schema.COLUMNS
+	WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+	var n int
+	if err := tx.Get(&n, query, table, column); err != nil {
+		return fmt.Errorf("checking column %s.%s: %v", table, column, err)
 	}
-	if _, err := db.Exec(`DROP TABLE cmd_logs`); err != nil {
-		return err
+	if n != 0 {
+		return nil
 	}
-	if _, err := db.Exec(`DROP TABLE loth_logs`); err != nil {
-		return err
+	if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return fmt.Errorf("adding column %s.%s: %v", table, column, err)
 	}
 	return nil
 }
@@ -58,6 +183,7 @@ CREATE TABLE IF NOT EXISTS images (
 	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	message_id BIGINT UNSIGNED NOT NULL,
 	PRIMARY KEY (id),
+	INDEX (url(255)),
 	FOREIGN KEY (message_id) REFERENCES messages(id)
 )`
 
@@ -79,9 +205,13 @@ CREATE TABLE IF NOT EXISTS cmd_logs (
 	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	PRIMARY KEY (id)
 )`
-)
 
-var tableLothLogs = `
+	// The expires default is a day after the lowest TIMESTAMP so that it is
+	// valid in any time zone. Older MySQL versions allow
+	// CURRENT_TIMESTAMP only for the first TIMESTAMP column and newer ones in
+	// strict mode reject a column without a default, but both accept a
+	// constant. The store always sets expires.
+	tableLothLogs = `
 CREATE TABLE IF NOT EXISTS loth_logs (
 	id SERIAL,
 	issuer VARCHAR(255) NOT NULL,
@@ -91,7 +221,31 @@ CREATE TABLE IF NOT EXISTS loth_logs (
 	status VARCHAR(10) NOT NULL,
 	is_new BOOL NOT NULL,
 	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-	expires TIMESTAMP NOT NULL ` + legacyDefault() + `,
+	expires TIMESTAMP NOT NULL DEFAULT '1970-01-02 00:00:00',
 	targets TEXT NOT NULL,
 	PRIMARY KEY (id)
 )`
+
+	tableFeedbackReplies = `
+CREATE TABLE feedback_replies (
+	id SERIAL,
+	feedback_id BIGINT UNSIGNED NOT NULL,
+	player VARCHAR(255) NOT NULL,
+	message TEXT NOT NULL,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent TIMESTAMP NULL DEFAULT NULL,
+	PRIMARY KEY (id),
+	INDEX (player, sent),
+	FOREIGN KEY (feedback_id) REFERENCES feedback(id)
+)`
+
+	tableAuditLogs = `
+CREATE TABLE audit_logs (
+	id SERIAL,
+	action VARCHAR(255) NOT NULL,
+	actor VARCHAR(255) NOT NULL DEFAULT '',
+	detail TEXT NOT NULL,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id)
+)`
+)
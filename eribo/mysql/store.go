@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -27,59 +28,117 @@ func (db *EriboStore) Tx(fn func(*sqlx.Tx) error) (err error) {
 	return fn(tx)
 }
 
-func (db *EriboStore) AddMessageWithURLs(m *eribo.Message, urls []string) (err error) {
+// errNoNewImages rolls back a message when every image posted in it was
+// stored by another message after the images were checked.
+var errNoNewImages = errors.New("no new images")
+
+// imageColumns are the columns of images that eribo.Image holds. The
+// norm_url_hash column only backs the unique index on the normalized URL,
+// which is too long to index itself.
+const imageColumns = `img.id, img.url, img.norm_url, img.done, img.kuid, img.content_type, img.size, img.created, img.message_id`
+
+func (db *EriboStore) AddMessageWithImages(m *eribo.Message, images []*eribo.Image) error {
 	if (m.Created == time.Time{}) {
 		m.Created = time.Now().UTC().Truncate(timeTruncate)
 	}
-	tx, err := db.Beginx()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err != nil {
-			if rerr := tx.Rollback(); rerr != nil {
-				err = fmt.Errorf("rollback failed: %v: %v", rerr, err)
+	var (
+		messageID int64
+		newImages []*eribo.Image
+		ids       []int64
+	)
+	err := db.Tx(func(tx *sqlx.Tx) error {
+		// Checking first keeps reposts from using up message IDs. The unique
+		// index on the normalized URL hash still skips the images that
+		// another message stores in the meantime.
+		var unseen []*eribo.Image
+		for _, img := range images {
+			if img.NormURL == "" {
+				img.NormURL = img.URL
+			}
+			var n int
+			if err := tx.Get(&n, `SELECT COUNT(*) FROM images WHERE norm_url_hash = UNHEX(SHA2(?, 256))`, img.NormURL); err != nil {
+				return err
+			}
+			if n == 0 {
+				unseen = append(unseen, img)
 			}
-			return
 		}
-		err = tx.Commit()
-	}()
-
-	const insertMessage = `INSERT INTO messages(message, player, channel, created) VALUES (?, ?, ?, ?)`
-	r, err := tx.Exec(insertMessage, m.Message, m.Player, m.Channel, m.Created)
-	if err != nil {
-		return err
-	}
-
-	messageID, err := r.LastInsertId()
-	if err != nil {
-		return err
-	}
+		if len(unseen) == 0 {
+			return nil
+		}
 
-	const insertImage = "INSERT INTO images(url, done, kuid, message_id, created) VALUES (?, ?, ?, ?, ?)"
-	for _, u := range urls {
-		_, err := tx.Exec(insertImage, u, false, 0, messageID, m.Created)
+		const insertMessage = `INSERT INTO messages(message, player, channel, created) VALUES (?, ?, ?, ?)`
+		r, err := tx.Exec(insertMessage, m.Message, m.Player, m.Channel, m.Created)
 		if err != nil {
 			return err
 		}
+		if messageID, err = r.LastInsertId(); err != nil {
+			return err
+		}
+
+		const insertImage = `
+		INSERT IGNORE INTO images(url, norm_url, norm_url_hash, done, kuid, content_type, size, message_id, created)
+		  VALUES (?, ?, UNHEX(SHA2(?, 256)), ?, ?, ?, ?, ?, ?)`
+		for _, img := range unseen {
+			r, err := tx.Exec(insertImage, img.URL, img.NormURL, img.NormURL, false, 0, img.ContentType, img.Size, messageID, m.Created)
+			if err != nil {
+				return err
+			}
+			n, err := r.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				// Another message stored the image.
+				continue
+			}
+			id, err := r.LastInsertId()
+			if err != nil {
+				return err
+			}
+			newImages = append(newImages, img)
+			ids = append(ids, id)
+		}
+		if len(newImages) == 0 {
+			return errNoNewImages
+		}
+		return nil
+	})
+	if err == errNoNewImages {
+		return nil
+	}
+	if err != nil || messageID == 0 {
+		return err
+	}
+	// The IDs are set once the transaction is committed.
+	m.ID = messageID
+	for i, img := range newImages {
+		img.ID = ids[i]
+		img.MessageID = messageID
 	}
 	return nil
 }
 
-func (db *EriboStore) GetImages(limit, offset int, reverse, filterDone bool) ([]*eribo.Image, error) {
+func (db *EriboStore) GetImages(limit, offset int, reverse, filterDone bool, contentType string) ([]*eribo.Image, error) {
 	desc := ""
 	if reverse {
 		desc = "DESC"
 	}
 
 	filter := ""
+	args := []interface{}{}
 	if filterDone {
 		filter = " AND img.done = 0 "
 	}
+	if contentType != "" {
+		filter += " AND img.content_type = ? "
+		args = append(args, contentType)
+	}
+	args = append(args, offset, limit)
 
 	var query = `
 	SELECT
-	  img.*,
+	  ` + imageColumns + `,
 	  m.id as "message.id",
 	  m.player as "message.player",
 	  m.channel as "message.channel",
@@ -87,10 +146,10 @@ func (db *EriboStore) GetImages(limit, offset int, reverse, filterDone bool) ([]
 	  m.created as "message.created"
 	FROM images img
 	  JOIN messages m ON img.message_id=m.id` + filter + `
-	  ORDER BY created ` + desc + ` LIMIT ?, ?`
+	  ORDER BY img.created ` + desc + `, img.id LIMIT ?, ?`
 
 	images := []*eribo.Image{}
-	if err := db.Select(&images, query, offset, limit); err != nil {
+	if err := db.Select(&images, query, args...); err != nil {
 		return nil, err
 	}
 	return images, nil
@@ -101,7 +160,7 @@ func (db *EriboStore) GetImage(id int64) (*eribo.Image, error) {
 	err := db.Tx(func(tx *sqlx.Tx) error {
 		const query = `
 	    SELECT
-	      img.*,
+	      ` + imageColumns + `,
 	      m.id as "message.id",
 	      m.player as "message.player",
 	      m.channel as "message.channel",
@@ -120,7 +179,7 @@ func (db *EriboStore) GetImage(id int64) (*eribo.Image, error) {
 
 func (db *EriboStore) ToggleImageDone(id int64) (err error) {
 	return db.Tx(func(tx *sqlx.Tx) error {
-		const query = `SELECT * FROM images WHERE id = ?`
+		const query = `SELECT ` + imageColumns + ` FROM images img WHERE img.id = ?`
 
 		img := &eribo.Image{}
 		err = tx.Get(img, query, id)
@@ -148,7 +207,7 @@ func (db *EriboStore) SetImageKuid(id int64, kuid int) error {
 
 func (db *EriboStore) GetAllFeedback(limit, offset int) ([]*eribo.Feedback, error) {
 	feedback := []*eribo.Feedback{}
-	const query = `SELECT * FROM feedback LIMIT ? OFFSET ?`
+	const query = `SELECT * FROM feedback ORDER BY id LIMIT ? OFFSET ?`
 	if err := db.Select(&feedback, query, limit, offset); err != nil {
 		return nil, err
 	}
@@ -157,7 +216,7 @@ func (db *EriboStore) GetAllFeedback(limit, offset int) ([]*eribo.Feedback, erro
 
 func (db *EriboStore) GetRecentFeedback(limit, offset int) ([]*eribo.Feedback, error) {
 	feedback := []*eribo.Feedback{}
-	const query = `SELECT * FROM feedback ORDER BY created DESC LIMIT ? OFFSET ?`
+	const query = `SELECT * FROM feedback ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`
 	if err := db.Select(&feedback, query, limit, offset); err != nil {
 		return nil, err
 	}
@@ -168,7 +227,19 @@ func (db *EriboStore) AddFeedback(f *eribo.Feedback) error {
 	if (f.Created == time.Time{}) {
 		f.Created = time.Now().UTC().Truncate(timeTruncate)
 	}
-	const query = `INSERT INTO feedback(message, player, created) VALUES (?, ?, ?)`
-	_, err := db.Exec(query, f.Message, f.Player, f.Created)
-	return err
+	status := f.Status
+	if status == "" {
+		status = eribo.FeedbackOpen
+	}
+	const query = `INSERT INTO feedback(message, player, created, status, note, tags) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := db.Exec(query, f.Message, f.Player, f.Created, status, f.Note, f.Tags)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	f.ID = id
+	return nil
 }
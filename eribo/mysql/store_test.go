@@ -10,7 +10,7 @@ import (
 	"github.com/kusubooru/eribo/eribo"
 )
 
-func TestAddMessageWithURLs(t *testing.T) {
+func TestAddMessageWithImages(t *testing.T) {
 	s := setup(t)
 	defer teardown(t, s)
 
@@ -22,19 +22,19 @@ func TestAddMessageWithURLs(t *testing.T) {
 		Created: created,
 	}
 	urls := []string{"http://url1", "http://url2"}
-	if err := s.AddMessageWithURLs(m, urls); err != nil {
-		t.Fatal("AddMessageWithURLs failed:", err)
+	if err := s.AddMessageWithImages(m, imagesFor(urls...)); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
 	}
 
-	have, err := s.GetImages(5, 0, false, false)
+	have, err := s.GetImages(5, 0, false, false, "")
 	if err != nil {
 		t.Fatal("GetImages failed:", err)
 	}
 	want := []*eribo.Image{
-		{ID: 1, URL: "http://url1", MessageID: 1, Created: created,
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
-		{ID: 2, URL: "http://url2", MessageID: 1, Created: created,
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
 	}
@@ -53,35 +53,73 @@ func TestGetImages(t *testing.T) {
 		Created: created,
 	}
 	urls := []string{"http://url1", "http://url2"}
-	if err := s.AddMessageWithURLs(m, urls); err != nil {
-		t.Fatal("AddMessageWithURLs failed:", err)
+	if err := s.AddMessageWithImages(m, imagesFor(urls...)); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
 	}
 
 	created2 := created.Add(time.Second)
 	m.Created = created2
 	urls = []string{"http://url3"}
-	if err := s.AddMessageWithURLs(m, urls); err != nil {
-		t.Fatal("AddMessageWithURLs second failed:", err)
+	if err := s.AddMessageWithImages(m, imagesFor(urls...)); err != nil {
+		t.Fatal("AddMessageWithImages second failed:", err)
 	}
 
-	have, err := s.GetImages(5, 0, true, false)
+	have, err := s.GetImages(5, 0, true, false, "")
 	if err != nil {
 		t.Fatal("GetImages failed:", err)
 	}
 	want := []*eribo.Image{
-		{ID: 3, URL: "http://url3", MessageID: 2, Created: created2,
+		{ID: 3, URL: "http://url3", NormURL: "http://url3", MessageID: 2, Created: created2,
 			Message: &eribo.Message{ID: 2, Channel: "foo", Player: "bar", Message: "baz", Created: created2},
 		},
-		{ID: 1, URL: "http://url1", MessageID: 1, Created: created,
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
-		{ID: 2, URL: "http://url2", MessageID: 1, Created: created,
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
 	}
 	deepEqual(t, have, want, "GetImages = ")
 }
 
+func TestAddMessageWithImages_dedup(t *testing.T) {
+	s := setup(t)
+	defer teardown(t, s)
+
+	created := time.Now().UTC().Add(time.Second).Truncate(timeTruncate)
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: created}
+	images := []*eribo.Image{
+		{URL: "http://url1.png", ContentType: "image/png", Size: 42},
+		{URL: "http://url2.gif", ContentType: "image/gif"},
+	}
+	if err := s.AddMessageWithImages(m, images); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	m2 := &eribo.Message{Channel: "foo", Player: "bar", Message: "repost", Created: created}
+	if err := s.AddMessageWithImages(m2, images[:1]); err != nil {
+		t.Fatal("AddMessageWithImages repost failed:", err)
+	}
+
+	have, err := s.GetImages(5, 0, false, false, "image/png")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	want := []*eribo.Image{
+		{ID: 1, URL: "http://url1.png", NormURL: "http://url1.png", ContentType: "image/png", Size: 42, MessageID: 1, Created: created,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
+		},
+	}
+	deepEqual(t, have, want, "AddMessageWithImages repost = ")
+}
+
+func imagesFor(urls ...string) []*eribo.Image {
+	images := make([]*eribo.Image, len(urls))
+	for i, u := range urls {
+		images[i] = &eribo.Image{URL: u}
+	}
+	return images
+}
+
 func TestSetImageKuid(t *testing.T) {
 	s := setup(t)
 	defer teardown(t, s)
@@ -89,8 +127,8 @@ func TestSetImageKuid(t *testing.T) {
 	created := time.Now().UTC().Truncate(timeTruncate)
 	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: created}
 	urls := []string{"http://url"}
-	if err := s.AddMessageWithURLs(m, urls); err != nil {
-		t.Fatal("AddMessageWithURLs failed:", err)
+	if err := s.AddMessageWithImages(m, imagesFor(urls...)); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
 	}
 
 	if err := s.SetImageKuid(1, 1337); err != nil {
@@ -105,6 +143,7 @@ func TestSetImageKuid(t *testing.T) {
 	want := &eribo.Image{
 		ID:        1,
 		URL:       "http://url",
+		NormURL:   "http://url",
 		Done:      true,
 		Kuid:      1337,
 		Created:   created,
@@ -126,23 +165,23 @@ func TestToggleImageDone(t *testing.T) {
 		Created: created,
 	}
 	urls := []string{"http://url1", "http://url2"}
-	if err := s.AddMessageWithURLs(m, urls); err != nil {
-		t.Fatal("AddMessageWithURLs failed:", err)
+	if err := s.AddMessageWithImages(m, imagesFor(urls...)); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
 	}
 
 	if err := s.ToggleImageDone(2); err != nil {
 		t.Fatal("toggling image done failed:", err)
 	}
 
-	have, err := s.GetImages(5, 0, false, false)
+	have, err := s.GetImages(5, 0, false, false, "")
 	if err != nil {
 		t.Fatal("GetImages failed:", err)
 	}
 	want := []*eribo.Image{
-		{ID: 1, URL: "http://url1", MessageID: 1, Created: created,
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
-		{ID: 2, URL: "http://url2", Done: true, MessageID: 1, Created: created,
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", Done: true, MessageID: 1, Created: created,
 			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: created},
 		},
 	}
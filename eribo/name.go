@@ -0,0 +1,97 @@
+package eribo
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseName returns the character name referred to by s and whether it must
+// match a name exactly. Names can be given plainly, as "Quoted Name" or as
+// an F-Chat [user]Name[/user] or [icon]Name[/icon] reference, and the last two
+// only match exactly.
+func ParseName(s string) (name string, exact bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return strings.TrimSpace(s[1 : len(s)-1]), true
+	}
+	lower := strings.ToLower(s)
+	for _, tag := range []string{"user", "icon", "eicon"} {
+		open, close := "["+tag+"]", "[/"+tag+"]"
+		if strings.HasPrefix(lower, open) && strings.HasSuffix(lower, close) && len(s) >= len(open)+len(close) {
+			return strings.TrimSpace(s[len(open) : len(s)-len(close)]), true
+		}
+	}
+	return s, false
+}
+
+// maxSuggestions is the most names that Suggest returns.
+const maxSuggestions = 3
+
+// suggest returns up to maxSuggestions of the names which are close to name,
+// closest first. A name is close when a few edits turn name into it or into
+// its beginning, so typos in an abbreviated name are still suggested.
+func suggest(name string, names []string) []string {
+	name = strings.ToLower(name)
+	n := utf8.RuneCountInString(name)
+	if n == 0 {
+		return nil
+	}
+	max := n / 3
+	if max < 1 {
+		max = 1
+	}
+	type candidate struct {
+		name string
+		dist int
+	}
+	var cs []candidate
+	for _, s := range names {
+		lower := strings.ToLower(s)
+		dist := levenshtein(name, lower)
+		if r := []rune(lower); len(r) > n {
+			if d := levenshtein(name, string(r[:n])); d < dist {
+				dist = d
+			}
+		}
+		if dist <= max {
+			cs = append(cs, candidate{s, dist})
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].dist != cs[j].dist {
+			return cs[i].dist < cs[j].dist
+		}
+		return cs[i].name < cs[j].name
+	})
+	if len(cs) > maxSuggestions {
+		cs = cs[:maxSuggestions]
+	}
+	var suggestions []string
+	for _, c := range cs {
+		suggestions = append(suggestions, c.name)
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
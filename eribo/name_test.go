@@ -0,0 +1,45 @@
+package eribo
+
+import "testing"
+
+func TestParseName(t *testing.T) {
+	var tests = []struct {
+		in    string
+		name  string
+		exact bool
+	}{
+		{"Alice", "Alice", false},
+		{" Bob Doe ", "Bob Doe", false},
+		{`"Bob Doe"`, "Bob Doe", true},
+		{`'Bob'`, "Bob", true},
+		{`"Bob`, `"Bob`, false},
+		{"[user]Bob Doe[/user]", "Bob Doe", true},
+		{"[USER]Bob[/USER]", "Bob", true},
+		{"[icon]Bob[/icon]", "Bob", true},
+		{"[user]Bob", "[user]Bob", false},
+	}
+	for _, tt := range tests {
+		name, exact := ParseName(tt.in)
+		if name != tt.name || exact != tt.exact {
+			t.Errorf("ParseName(%q) = %q, %v, want %q, %v", tt.in, name, exact, tt.name, tt.exact)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kitten", "sitting", 3},
+		{"bob", "bbo", 2},
+		{"", "abc", 3},
+		{"ñandú", "nandu", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
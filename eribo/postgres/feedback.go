@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func (db *EriboStore) GetFeedback(id int64) (*eribo.Feedback, error) {
+	f := &eribo.Feedback{}
+	if err := db.Get(f, `SELECT * FROM feedback WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	utcFeedback([]*eribo.Feedback{f})
+	return f, nil
+}
+
+// UpdateFeedback returns sql.ErrNoRows for missing feedback like the other
+// stores.
+func (db *EriboStore) UpdateFeedback(f *eribo.Feedback) error {
+	const query = `UPDATE feedback SET status = $1, note = $2, tags = $3 WHERE id = $4`
+	n, err := rowsAffected(db.Exec(query, f.Status, f.Note, f.Tags, f.ID))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("feedback %d: %w", f.ID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (db *EriboStore) AddFeedbackReply(r *eribo.FeedbackReply) error {
+	if (r.Created == time.Time{}) {
+		r.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	const query = `INSERT INTO feedback_replies(feedback_id, player, message, created) VALUES ($1, $2, $3, $4) RETURNING id`
+	return db.Get(&r.ID, query, r.FeedbackID, r.Player, r.Message, r.Created.UTC())
+}
+
+func (db *EriboStore) ListFeedbackReplies(feedbackID int64) ([]*eribo.FeedbackReply, error) {
+	replies := []*eribo.FeedbackReply{}
+	const query = `SELECT * FROM feedback_replies WHERE feedback_id = $1 ORDER BY id`
+	if err := db.Select(&replies, query, feedbackID); err != nil {
+		return nil, err
+	}
+	utcReplies(replies)
+	return replies, nil
+}
+
+func (db *EriboStore) ListUnsentReplies(player string) ([]*eribo.FeedbackReply, error) {
+	replies := []*eribo.FeedbackReply{}
+	const query = `SELECT * FROM feedback_replies WHERE sent IS NULL AND ($1::text = '' OR player = $1::citext) ORDER BY id`
+	if err := db.Select(&replies, query, player); err != nil {
+		return nil, err
+	}
+	utcReplies(replies)
+	return replies, nil
+}
+
+func (db *EriboStore) MarkReplySent(id int64, sent time.Time) error {
+	_, err := db.Exec(`UPDATE feedback_replies SET sent = $1 WHERE id = $2`, sent.UTC().Truncate(timeTruncate), id)
+	return err
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+// params collects the arguments of a query and numbers their placeholders.
+type params struct {
+	args []interface{}
+}
+
+// add adds an argument and returns its placeholder.
+func (p *params) add(arg interface{}) string {
+	p.args = append(p.args, arg)
+	return "$" + strconv.Itoa(len(p.args))
+}
+
+// columns are the columns of a table that a query filters on. A query for a
+// player matches any of the player columns and an empty channel column means
+// the table has no channel. Only the command logs have a command column and
+// only the feedback has a status column.
+type columns struct {
+	id      string
+	created string
+	channel string
+	command string
+	status  string
+	players []string
+}
+
+// where returns the WHERE, ORDER BY and LIMIT clauses of a query with their
+// arguments.
+func where(q eribo.Query, cols columns) (string, []interface{}) {
+	var p params
+	clause := whereClause(conditions(q, cols, &p))
+	clause += " ORDER BY " + cols.id + " DESC"
+	if q.Limit > 0 {
+		clause += " LIMIT " + p.add(q.Limit)
+	}
+	return clause, p.args
+}
+
+// whereClause returns the WHERE clause of conditions, which is empty if there
+// are none.
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// conditions returns the conditions of a query, except for its limit, and
+// adds their arguments to p.
+func conditions(q eribo.Query, cols columns, p *params) []string {
+	var conds []string
+	if q.BeforeID != 0 {
+		conds = append(conds, cols.id+" < "+p.add(q.BeforeID))
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, cols.created+" >= "+p.add(q.Since.UTC()))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, cols.created+" < "+p.add(q.Until.UTC()))
+	}
+	if q.Channel != "" {
+		if cols.channel == "" {
+			conds = append(conds, "FALSE")
+		} else {
+			conds = append(conds, cols.channel+" = "+p.add(q.Channel))
+		}
+	}
+	if q.Command != eribo.CmdUnknown && cols.command != "" {
+		conds = append(conds, cols.command+" = "+p.add(q.Command))
+	}
+	if q.Status != "" && cols.status != "" {
+		conds = append(conds, cols.status+" = "+p.add(q.Status))
+	}
+	if q.Player != "" {
+		var or []string
+		for _, c := range cols.players {
+			or = append(or, c+" = "+p.add(q.Player))
+		}
+		conds = append(conds, "("+strings.Join(or, " OR ")+")")
+	}
+	return conds
+}
+
+func (db *EriboStore) ListImages(q eribo.Query) ([]*eribo.Image, error) {
+	clause, args := where(q, columns{id: "img.id", created: "img.created", channel: "m.channel", players: []string{"m.player"}})
+	images := []*eribo.Image{}
+	if err := db.Select(&images, selectImages+clause, args...); err != nil {
+		return nil, err
+	}
+	utcImages(images)
+	return images, nil
+}
+
+func (db *EriboStore) ListFeedback(q eribo.Query) ([]*eribo.Feedback, error) {
+	clause, args := where(q, columns{id: "id", created: "created", status: "status", players: []string{"player"}})
+	feedback := []*eribo.Feedback{}
+	if err := db.Select(&feedback, `SELECT * FROM feedback`+clause, args...); err != nil {
+		return nil, err
+	}
+	utcFeedback(feedback)
+	return feedback, nil
+}
+
+func (db *EriboStore) ListCmdLogs(q eribo.Query) ([]*eribo.CmdLog, error) {
+	clause, args := where(q, columns{id: "id", created: "created", channel: "channel", command: "command", players: []string{"player"}})
+	logs := []*eribo.CmdLog{}
+	if err := db.Select(&logs, `SELECT * FROM cmd_logs`+clause, args...); err != nil {
+		return nil, err
+	}
+	utcCmdLogs(logs)
+	return logs, nil
+}
+
+func (db *EriboStore) ListLothLogs(q eribo.Query) ([]*eribo.LothLog, error) {
+	clause, args := where(q, columns{id: "id", created: "created", channel: "channel", players: []string{"issuer", "name"}})
+	logs := []*eribo.LothLog{}
+	if err := db.Select(&logs, `SELECT * FROM loth_logs`+clause, args...); err != nil {
+		return nil, err
+	}
+	utcLothLogs(logs)
+	return logs, nil
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func TestWhere(t *testing.T) {
+	day := time.Date(2018, 5, 1, 12, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
+	cols := columns{id: "id", created: "created", channel: "channel", players: []string{"issuer", "name"}}
+	tests := []struct {
+		q        eribo.Query
+		cols     columns
+		want     string
+		wantArgs []interface{}
+	}{
+		{eribo.Query{}, cols, " ORDER BY id DESC", nil},
+		{eribo.Query{Limit: 2}, cols, " ORDER BY id DESC LIMIT $1", []interface{}{2}},
+		{
+			eribo.Query{BeforeID: 3, Since: day, Until: day.Add(time.Hour), Channel: "room", Player: "foo", Limit: 5},
+			cols,
+			" WHERE id < $1 AND created >= $2 AND created < $3 AND channel = $4 AND (issuer = $5 OR name = $6) ORDER BY id DESC LIMIT $7",
+			[]interface{}{int64(3), day.UTC(), day.Add(time.Hour).UTC(), "room", "foo", "foo", 5},
+		},
+		{
+			eribo.Query{Channel: "room", Player: "foo"},
+			columns{id: "id", created: "created", players: []string{"player"}},
+			" WHERE FALSE AND (player = $1) ORDER BY id DESC",
+			[]interface{}{"foo"},
+		},
+	}
+	for _, tt := range tests {
+		got, gotArgs := where(tt.q, tt.cols)
+		if got != tt.want {
+			t.Errorf("where(%+v)\nhave: %q\nwant: %q", tt.q, got, tt.want)
+		}
+		if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("where(%+v) args\nhave: %#v\nwant: %#v", tt.q, gotArgs, tt.wantArgs)
+		}
+	}
+}
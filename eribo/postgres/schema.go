@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo/migrate"
+)
+
+// migrations are the versions of the schema. Rolling back the first one
+// drops the tables but keeps the citext extension, which other databases of
+// the cluster may use.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create tables",
+		Up:      migrate.SQL(extensionCitext, tableMessages, tableImages, indexImagesURL, tableFeedback, tableCmdLogs, tableLothLogs),
+		Down: migrate.SQL(
+			`DROP TABLE loth_logs`,
+			`DROP TABLE cmd_logs`,
+			`DROP TABLE feedback`,
+			`DROP TABLE images`,
+			`DROP TABLE messages`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create audit_logs",
+		Up:      migrate.SQL(tableAuditLogs),
+		Down:    migrate.SQL(`DROP TABLE audit_logs`),
+	},
+	{
+		Version: 3,
+		Name:    "add feedback status, note, tags and replies",
+		Up: migrate.SQL(
+			`ALTER TABLE feedback
+			  ADD COLUMN status TEXT NOT NULL DEFAULT 'open',
+			  ADD COLUMN note TEXT NOT NULL DEFAULT '',
+			  ADD COLUMN tags JSONB NOT NULL DEFAULT '[]'`,
+			tableFeedbackReplies,
+			indexFeedbackRepliesPlayer,
+		),
+		Down: migrate.SQL(
+			`DROP TABLE feedback_replies`,
+			`ALTER TABLE feedback DROP COLUMN status, DROP COLUMN note, DROP COLUMN tags`,
+		),
+	},
+	{
+		Version: 4,
+		Name:    "add cmd_logs indexes",
+		Up:      migrate.SQL(indexCmdLogsCreated, indexCmdLogsCommand, indexCmdLogsPlayer, indexCmdLogsChannel),
+		Down: migrate.SQL(
+			`DROP INDEX cmd_logs_created`,
+			`DROP INDEX cmd_logs_command`,
+			`DROP INDEX cmd_logs_player`,
+			`DROP INDEX cmd_logs_channel`,
+		),
+	},
+	{
+		// Reposts are told apart by the normalized URL. Images stored
+		// before are not normalized again, so their URL stands in for it.
+		// Images stored twice by concurrent messages are merged into the
+		// first one.
+		Version: 5,
+		Name:    "make image urls unique",
+		Up: func(tx *sqlx.Tx) error {
+			err := migrate.SQL(
+				`ALTER TABLE images ADD COLUMN norm_url TEXT NOT NULL DEFAULT ''`,
+				`UPDATE images SET norm_url = url`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			if err := dedupImages(tx); err != nil {
+				return err
+			}
+			return migrate.SQL(indexImagesNormURL)(tx)
+		},
+		Down: migrate.SQL(`DROP INDEX images_norm_url`, `ALTER TABLE images DROP COLUMN norm_url`),
+	},
+}
+
+// Migrator returns the migrator of the database schema.
+func (db *EriboStore) Migrator() *migrate.Migrator {
+	return migrate.New(db.DB, migrations)
+}
+
+// dedupImages keeps the first of the images with the same normalized URL and
+// deletes the others. The first one takes their done flag and kuid so that no
+// upload is lost. Images uploaded as different posts cannot be merged and fail
+// the migration until their kuids are fixed by hand.
+func dedupImages(tx *sqlx.Tx) error {
+	const selectConflicts = `
+	SELECT a.id AS a, b.id AS b
+	FROM images a JOIN images b ON a.norm_url = b.norm_url AND a.id < b.id
+	WHERE a.kuid != 0 AND b.kuid != 0 AND a.kuid != b.kuid
+	ORDER BY a.id, b.id`
+	var conflicts []struct{ A, B int64 }
+	if err := tx.Select(&conflicts, selectConflicts); err != nil {
+		return err
+	}
+	if len(conflicts) != 0 {
+		pairs := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			pairs[i] = fmt.Sprintf("%d and %d", c.A, c.B)
+		}
+		return fmt.Errorf("images with the same url have different kuids: %s", strings.Join(pairs, ", "))
+	}
+
+	const merge = `
+	UPDATE images a SET done = d.done, kuid = d.kuid
+	FROM (
+	  SELECT MIN(id) AS id, BOOL_OR(done) AS done, MAX(kuid) AS kuid
+	  FROM images GROUP BY norm_url HAVING COUNT(*) > 1
+	) d
+	WHERE a.id = d.id`
+	return migrate.SQL(merge, `DELETE FROM images a USING images b WHERE a.norm_url = b.norm_url AND a.id > b.id`)(tx)
+}
+
+const (
+	extensionCitext = `CREATE EXTENSION IF NOT EXISTS citext`
+
+	tableMessages = `
+CREATE TABLE messages (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	message TEXT NOT NULL,
+	player CITEXT NOT NULL,
+	channel CITEXT NOT NULL,
+	created TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+	tableImages = `
+CREATE TABLE images (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	url TEXT NOT NULL,
+	done BOOLEAN NOT NULL DEFAULT FALSE,
+	kuid INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+	size BIGINT NOT NULL DEFAULT 0,
+	created TIMESTAMPTZ NOT NULL DEFAULT now(),
+	message_id BIGINT NOT NULL REFERENCES messages(id)
+)`
+
+	indexImagesURL     = `CREATE INDEX images_url ON images(url)`
+	indexImagesNormURL = `CREATE UNIQUE INDEX images_norm_url ON images(norm_url)`
+
+	tableFeedback = `
+CREATE TABLE feedback (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	message TEXT NOT NULL,
+	player CITEXT NOT NULL,
+	created TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+	tableCmdLogs = `
+CREATE TABLE cmd_logs (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	command TEXT NOT NULL,
+	args TEXT NOT NULL DEFAULT '',
+	player CITEXT NOT NULL,
+	channel CITEXT NOT NULL DEFAULT '',
+	created TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+	// The indexes serve the command usage queries, which select the logs by
+	// time and by command, player or channel.
+	indexCmdLogsCreated = `CREATE INDEX cmd_logs_created ON cmd_logs(created)`
+	indexCmdLogsCommand = `CREATE INDEX cmd_logs_command ON cmd_logs(command, created)`
+	indexCmdLogsPlayer  = `CREATE INDEX cmd_logs_player ON cmd_logs(player, created)`
+	indexCmdLogsChannel = `CREATE INDEX cmd_logs_channel ON cmd_logs(channel, created)`
+
+	tableLothLogs = `
+CREATE TABLE loth_logs (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	issuer CITEXT NOT NULL,
+	channel CITEXT NOT NULL DEFAULT '',
+	name CITEXT NOT NULL,
+	role TEXT NOT NULL,
+	status TEXT NOT NULL,
+	is_new BOOLEAN NOT NULL,
+	created TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires TIMESTAMPTZ NOT NULL,
+	targets JSONB NOT NULL
+)`
+
+	tableFeedbackReplies = `
+CREATE TABLE feedback_replies (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	feedback_id BIGINT NOT NULL REFERENCES feedback(id),
+	player CITEXT NOT NULL,
+	message TEXT NOT NULL,
+	created TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent TIMESTAMPTZ
+)`
+
+	indexFeedbackRepliesPlayer = `CREATE INDEX feedback_replies_player ON feedback_replies(player, sent)`
+
+	tableAuditLogs = `
+CREATE TABLE audit_logs (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	action TEXT NOT NULL,
+	actor CITEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL,
+	created TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+)
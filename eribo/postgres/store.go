@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo"
+)
+
+const timeTruncate = 1 * time.Second
+
+func (db *EriboStore) Tx(fn func(*sqlx.Tx) error) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				err = fmt.Errorf("rollback failed: %v: %v", rerr, err)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(tx)
+}
+
+// errNoNewImages rolls back a message when every image posted in it was
+// stored by another message after the images were checked.
+var errNoNewImages = errors.New("no new images")
+
+func (db *EriboStore) AddMessageWithImages(m *eribo.Message, images []*eribo.Image) error {
+	if (m.Created == time.Time{}) {
+		m.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	var (
+		messageID int64
+		newImages []*eribo.Image
+		ids       []int64
+	)
+	err := db.Tx(func(tx *sqlx.Tx) error {
+		// Checking first keeps reposts from using up message IDs. The unique
+		// index on the normalized URL still skips the images that another
+		// message stores in the meantime.
+		var unseen []*eribo.Image
+		for _, img := range images {
+			if img.NormURL == "" {
+				img.NormURL = img.URL
+			}
+			var n int
+			if err := tx.Get(&n, `SELECT COUNT(*) FROM images WHERE norm_url = $1`, img.NormURL); err != nil {
+				return err
+			}
+			if n == 0 {
+				unseen = append(unseen, img)
+			}
+		}
+		if len(unseen) == 0 {
+			return nil
+		}
+
+		const insertMessage = `INSERT INTO messages(message, player, channel, created) VALUES ($1, $2, $3, $4) RETURNING id`
+		if err := tx.Get(&messageID, insertMessage, m.Message, m.Player, m.Channel, m.Created.UTC()); err != nil {
+			return err
+		}
+
+		const insertImage = `
+		INSERT INTO images(url, norm_url, content_type, size, message_id, created) VALUES ($1, $2, $3, $4, $5, $6)
+		  ON CONFLICT (norm_url) DO NOTHING
+		  RETURNING id`
+		for _, img := range unseen {
+			var id int64
+			err := tx.Get(&id, insertImage, img.URL, img.NormURL, img.ContentType, img.Size, messageID, m.Created.UTC())
+			if err == sql.ErrNoRows {
+				// Another message stored the image.
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			newImages = append(newImages, img)
+			ids = append(ids, id)
+		}
+		if len(newImages) == 0 {
+			return errNoNewImages
+		}
+		return nil
+	})
+	if err == errNoNewImages {
+		return nil
+	}
+	if err != nil || messageID == 0 {
+		return err
+	}
+	// The IDs are set once the transaction is committed.
+	m.ID = messageID
+	for i, img := range newImages {
+		img.ID = ids[i]
+		img.MessageID = messageID
+	}
+	return nil
+}
+
+const selectImages = `
+	SELECT
+	  img.*,
+	  m.id as "message.id",
+	  m.player as "message.player",
+	  m.channel as "message.channel",
+	  m.message as "message.message",
+	  m.created as "message.created"
+	FROM images img
+	  JOIN messages m ON img.message_id=m.id`
+
+func (db *EriboStore) GetImages(limit, offset int, reverse, filterDone bool, contentType string) ([]*eribo.Image, error) {
+	desc := ""
+	if reverse {
+		desc = "DESC"
+	}
+
+	var p params
+	filter := " WHERE TRUE"
+	if filterDone {
+		filter += " AND NOT img.done"
+	}
+	if contentType != "" {
+		filter += " AND img.content_type = " + p.add(contentType)
+	}
+
+	query := selectImages + filter + ` ORDER BY img.created ` + desc + `, img.id LIMIT ` + p.add(limit) + ` OFFSET ` + p.add(offset)
+	images := []*eribo.Image{}
+	if err := db.Select(&images, query, p.args...); err != nil {
+		return nil, err
+	}
+	utcImages(images)
+	return images, nil
+}
+
+func (db *EriboStore) GetImage(id int64) (*eribo.Image, error) {
+	img := &eribo.Image{}
+	if err := db.Get(img, selectImages+` WHERE img.id = $1`, id); err != nil {
+		return nil, err
+	}
+	utcImages([]*eribo.Image{img})
+	return img, nil
+}
+
+// ToggleImageDone returns sql.ErrNoRows for a missing image like the other
+// stores.
+func (db *EriboStore) ToggleImageDone(id int64) error {
+	var done bool
+	return db.Get(&done, `UPDATE images SET done = NOT done WHERE id = $1 RETURNING done`, id)
+}
+
+func (db *EriboStore) SetImageKuid(id int64, kuid int) error {
+	const query = `UPDATE images SET kuid = $1, done = $2 WHERE id = $3`
+	_, err := db.Exec(query, kuid, kuid != 0, id)
+	return err
+}
+
+func (db *EriboStore) GetAllFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	feedback := []*eribo.Feedback{}
+	const query = `SELECT * FROM feedback ORDER BY id LIMIT $1 OFFSET $2`
+	if err := db.Select(&feedback, query, limit, offset); err != nil {
+		return nil, err
+	}
+	utcFeedback(feedback)
+	return feedback, nil
+}
+
+func (db *EriboStore) GetRecentFeedback(limit, offset int) ([]*eribo.Feedback, error) {
+	feedback := []*eribo.Feedback{}
+	const query = `SELECT * FROM feedback ORDER BY created DESC, id DESC LIMIT $1 OFFSET $2`
+	if err := db.Select(&feedback, query, limit, offset); err != nil {
+		return nil, err
+	}
+	utcFeedback(feedback)
+	return feedback, nil
+}
+
+func (db *EriboStore) AddFeedback(f *eribo.Feedback) error {
+	if (f.Created == time.Time{}) {
+		f.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	status := f.Status
+	if status == "" {
+		status = eribo.FeedbackOpen
+	}
+	const query = `INSERT INTO feedback(message, player, created, status, note, tags) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return db.Get(&f.ID, query, f.Message, f.Player, f.Created.UTC(), status, f.Note, f.Tags)
+}
+
+// The driver scans timestamptz columns in a fixed zone of the offset that the
+// server sends while the rest of the bot uses UTC.
+
+func utcImages(images []*eribo.Image) {
+	for _, img := range images {
+		img.Created = img.Created.UTC()
+		if img.Message != nil {
+			img.Message.Created = img.Message.Created.UTC()
+		}
+	}
+}
+
+func utcFeedback(feedback []*eribo.Feedback) {
+	for _, f := range feedback {
+		f.Created = f.Created.UTC()
+	}
+}
+
+func utcReplies(replies []*eribo.FeedbackReply) {
+	for _, r := range replies {
+		r.Created = r.Created.UTC()
+		if r.Sent != nil {
+			sent := r.Sent.UTC()
+			r.Sent = &sent
+		}
+	}
+}
+
+func utcCmdLogs(logs []*eribo.CmdLog) {
+	for _, l := range logs {
+		l.Created = l.Created.UTC()
+	}
+}
+
+func utcLothLogs(logs []*eribo.LothLog) {
+	for _, l := range logs {
+		l.Created = l.Created.UTC()
+		if l.Loth != nil {
+			l.Expires = l.Expires.UTC()
+		}
+	}
+}
+
+func utcAuditLogs(logs []*eribo.AuditLog) {
+	for _, a := range logs {
+		a.Created = a.Created.UTC()
+	}
+}
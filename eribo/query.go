@@ -0,0 +1,57 @@
+package eribo
+
+import (
+	"strings"
+	"time"
+)
+
+// Query selects the records returned by the List methods of Store. Records
+// are returned newest first and zero fields select everything.
+type Query struct {
+	// Player selects the records of a player, compared case insensitively.
+	Player string
+	// Channel selects the records of a channel. Feedback is sent in private
+	// so it has no channel.
+	Channel string
+	// Command selects the command logs of a command. CmdUnknown selects all
+	// of them and the other records ignore it.
+	Command Command
+	// Status selects the feedback with a status. The other records ignore
+	// it.
+	Status FeedbackStatus
+	// Since and Until select the records created at or after Since and
+	// before Until.
+	Since time.Time
+	Until time.Time
+	// BeforeID selects the records with an ID lower than it, so the ID of
+	// the last record of a page is the cursor of the next page.
+	BeforeID int64
+	// Limit is the most records to return, or all of them if it is 0.
+	Limit int
+}
+
+// Match reports whether a record with the id, creation time and channel is
+// selected by the query. A record matches the player if any of players does.
+func (q Query) Match(id int64, created time.Time, channel string, players ...string) bool {
+	if q.BeforeID != 0 && id >= q.BeforeID {
+		return false
+	}
+	if !q.Since.IsZero() && created.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !created.Before(q.Until) {
+		return false
+	}
+	if q.Channel != "" && channel != q.Channel {
+		return false
+	}
+	if q.Player == "" {
+		return true
+	}
+	for _, p := range players {
+		if strings.EqualFold(p, q.Player) {
+			return true
+		}
+	}
+	return false
+}
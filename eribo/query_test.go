@@ -0,0 +1,34 @@
+package eribo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryMatch(t *testing.T) {
+	day := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		q       Query
+		id      int64
+		created time.Time
+		channel string
+		players []string
+		want    bool
+	}{
+		{Query{}, 1, day, "", nil, true},
+		{Query{BeforeID: 5}, 5, day, "", nil, false},
+		{Query{BeforeID: 5}, 4, day, "", nil, true},
+		{Query{Since: day}, 1, day, "", nil, true},
+		{Query{Since: day}, 1, day.Add(-time.Second), "", nil, false},
+		{Query{Until: day}, 1, day, "", nil, false},
+		{Query{Channel: "room"}, 1, day, "room", nil, true},
+		{Query{Channel: "room"}, 1, day, "", nil, false},
+		{Query{Player: "alice"}, 1, day, "", []string{"Bob", "Alice"}, true},
+		{Query{Player: "alice"}, 1, day, "", []string{"Bob"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Match(tt.id, tt.created, tt.channel, tt.players...); got != tt.want {
+			t.Errorf("%+v.Match(%d, %v, %q, %q) = %v, want %v", tt.q, tt.id, tt.created, tt.channel, tt.players, got, tt.want)
+		}
+	}
+}
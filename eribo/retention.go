@@ -0,0 +1,97 @@
+package eribo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Table is a table of the store whose records can be deleted when they are
+// older than their retention.
+type Table string
+
+const (
+	// TableMessages holds the messages with images and, through them, the
+	// images posted in them.
+	TableMessages Table = "messages"
+	TableFeedback Table = "feedback"
+	TableCmdLogs  Table = "cmd_logs"
+	TableLothLogs Table = "loth_logs"
+)
+
+// Tables are the tables that DeleteBefore accepts.
+var Tables = []Table{TableMessages, TableFeedback, TableCmdLogs, TableLothLogs}
+
+// ParseTable returns the table of a name.
+func ParseTable(name string) (Table, error) {
+	for _, t := range Tables {
+		if string(t) == name {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown table %q", name)
+}
+
+// Forgotten is the name that replaces the name of a player in the records
+// that ForgetPlayer keeps. It cannot be the name of a character.
+const Forgotten = "[forgotten]"
+
+// ForgetResult is the number of records of a player that ForgetPlayer
+// deleted or anonymized.
+type ForgetResult struct {
+	// Messages were anonymized: their player became Forgotten and their
+	// text was removed. The images posted in them are kept.
+	Messages int64
+	// Feedback was deleted.
+	Feedback int64
+	// CmdLogs were deleted.
+	CmdLogs int64
+	// LothLogs that the player issued or was chosen in were anonymized.
+	LothLogs int64
+	// Targets are the loth logs whose targets, which record who consented
+	// to be chosen, the player was removed from.
+	Targets int64
+}
+
+func (r ForgetResult) String() string {
+	return fmt.Sprintf("%d messages anonymized, %d feedback deleted, %d command logs deleted, %d loth logs anonymized, removed from the targets of %d loth logs",
+		r.Messages, r.Feedback, r.CmdLogs, r.LothLogs, r.Targets)
+}
+
+// RemoveTarget returns the targets without the player with a name, compared
+// case insensitively, and whether the player was there.
+func (t Targets) RemoveTarget(name string) (Targets, bool) {
+	kept := Targets{}
+	for _, p := range t {
+		if !strings.EqualFold(p.Name, name) {
+			kept = append(kept, p)
+		}
+	}
+	return kept, len(kept) != len(t)
+}
+
+// Audit actions.
+const (
+	AuditForgetMe = "forgetme"
+	AuditPurge    = "purge"
+)
+
+// AuditLog records a deletion of data: a player asking to be forgotten or a
+// purge of records older than their retention.
+type AuditLog struct {
+	ID     int64
+	Action string
+	// Actor is the player who asked for the deletion, which is kept as the
+	// record of their request, or empty for the bot.
+	Actor   string
+	Detail  string
+	Created time.Time
+}
+
+func (a AuditLog) String() string {
+	actor := a.Actor
+	if actor == "" {
+		actor = "bot"
+	}
+	return fmt.Sprintf("%4d: %v %s by %s - %s", a.ID, a.Created.Format(time.Stamp), a.Action, actor, a.Detail)
+}
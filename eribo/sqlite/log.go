@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+func (db *EriboStore) AddCmdLog(l *eribo.CmdLog) error {
+	if (l.Created == time.Time{}) {
+		l.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	const query = `INSERT INTO cmd_logs(command, args, player, channel, created) VALUES (?, ?, ?, ?, ?)`
+	res, err := db.Exec(query, l.Command, l.Args, l.Player, l.Channel, l.Created.UTC())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	return nil
+}
+
+func (db *EriboStore) GetCmdLog(id int64) (*eribo.CmdLog, error) {
+	e := &eribo.CmdLog{}
+	const query = `SELECT * FROM cmd_logs where id = ?`
+	if err := db.Get(e, query, id); err != nil {
+		return nil, err
+	}
+	utcCmdLogs([]*eribo.CmdLog{e})
+	return e, nil
+}
+
+func (db *EriboStore) GetRecentCmdLogs(limit, offset int) ([]*eribo.CmdLog, error) {
+	logs := []*eribo.CmdLog{}
+	const query = `SELECT * FROM cmd_logs ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`
+	if err := db.Select(&logs, query, limit, offset); err != nil {
+		return nil, err
+	}
+	utcCmdLogs(logs)
+	return logs, nil
+}
+
+func (db *EriboStore) CmdStats() ([]*eribo.CmdStat, error) {
+	stats := []*eribo.CmdStat{}
+	const query = `SELECT command, COUNT(command) AS uses FROM cmd_logs GROUP BY command ORDER BY uses DESC, command`
+	if err := db.Select(&stats, query); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// cmdUsageKeys are the expressions of the keys that cmd_logs are grouped by.
+// Times are stored in UTC.
+var cmdUsageKeys = map[eribo.CmdGrouping]string{
+	eribo.ByCommand: "command",
+	eribo.ByChannel: "channel",
+	eribo.ByPlayer:  "player",
+	eribo.ByDay:     "strftime('%Y-%m-%d', created)",
+	eribo.ByHour:    "strftime('%H', created)",
+}
+
+func (db *EriboStore) CmdUsage(q eribo.CmdUsageQuery) ([]*eribo.CmdCount, error) {
+	conds, args := conditions(q.Query, columns{id: "id", created: "created", channel: "channel", command: "command", players: []string{"player"}})
+	if q.By == eribo.ByArg {
+		if q.Command == eribo.CmdUnknown {
+			return nil, eribo.ErrArgNeedsCommand
+		}
+		all := []string{}
+		if err := db.Select(&all, `SELECT args FROM cmd_logs`+whereClause(append(conds, "args != ''")), args...); err != nil {
+			return nil, err
+		}
+		return eribo.CountArgs(all, q.Limit), nil
+	}
+	key, ok := cmdUsageKeys[q.By]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %q", q.By)
+	}
+	// Names are grouped case insensitively but ordered by their bytes like
+	// the other stores.
+	order := ` ORDER BY uses DESC, "key" COLLATE BINARY`
+	if q.By.Histogram() {
+		order = ` ORDER BY "key"`
+	}
+	query := `SELECT ` + key + ` AS "key", COUNT(*) AS uses FROM cmd_logs` + whereClause(conds) + ` GROUP BY ` + key + order
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	counts := []*eribo.CmdCount{}
+	if err := db.Select(&counts, query, args...); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (db *EriboStore) AddLothLog(l *eribo.LothLog) error {
+	if (l.Created == time.Time{}) {
+		l.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	var (
+		name    string
+		role    flist.Role
+		status  flist.Status
+		expires time.Time = l.Created
+	)
+	if l.Loth != nil {
+		name = l.Loth.Name
+		role = l.Loth.Role
+		status = l.Loth.Status
+		expires = l.Loth.Expires
+	}
+
+	const query = `INSERT INTO
+	loth_logs(issuer, channel, created, name, role, status, expires, is_new, targets)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.Exec(query, l.Issuer, l.Channel, l.Created.UTC(), name, role, status, expires.UTC(), l.IsNew, l.Targets)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	return nil
+}
+
+func (db *EriboStore) GetRecentLothLogs(limit, offset int) ([]*eribo.LothLog, error) {
+	logs := []*eribo.LothLog{}
+	const query = `SELECT * FROM loth_logs ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`
+	if err := db.Select(&logs, query, limit, offset); err != nil {
+		return nil, err
+	}
+	utcLothLogs(logs)
+	return logs, nil
+}
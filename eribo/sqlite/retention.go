@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo"
+)
+
+func (db *EriboStore) DeleteBefore(table eribo.Table, before time.Time) (int64, error) {
+	var n int64
+	err := db.Tx(func(tx *sqlx.Tx) error {
+		switch table {
+		case eribo.TableMessages:
+			const deleteImages = `DELETE FROM images WHERE message_id IN (SELECT id FROM messages WHERE created < ?)`
+			if _, err := tx.Exec(deleteImages, before.UTC()); err != nil {
+				return err
+			}
+		case eribo.TableFeedback:
+			const deleteReplies = `DELETE FROM feedback_replies WHERE feedback_id IN (SELECT id FROM feedback WHERE created < ?)`
+			if _, err := tx.Exec(deleteReplies, before.UTC()); err != nil {
+				return err
+			}
+		case eribo.TableCmdLogs, eribo.TableLothLogs:
+		default:
+			return fmt.Errorf("unknown table %q", table)
+		}
+		var err error
+		n, err = rowsAffected(tx.Exec(`DELETE FROM `+string(table)+` WHERE created < ?`, before.UTC()))
+		return err
+	})
+	return n, err
+}
+
+func (db *EriboStore) ForgetPlayer(name string, audit *eribo.AuditLog) (*eribo.ForgetResult, error) {
+	r := &eribo.ForgetResult{}
+	err := db.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		const anonymizeMessages = `UPDATE messages SET player = ?, message = '' WHERE player = ?`
+		if r.Messages, err = rowsAffected(tx.Exec(anonymizeMessages, eribo.Forgotten, name)); err != nil {
+			return err
+		}
+		const deleteReplies = `DELETE FROM feedback_replies WHERE feedback_id IN (SELECT id FROM feedback WHERE player = ?)`
+		if _, err := tx.Exec(deleteReplies, name); err != nil {
+			return err
+		}
+		if r.Feedback, err = rowsAffected(tx.Exec(`DELETE FROM feedback WHERE player = ?`, name)); err != nil {
+			return err
+		}
+		if r.CmdLogs, err = rowsAffected(tx.Exec(`DELETE FROM cmd_logs WHERE player = ?`, name)); err != nil {
+			return err
+		}
+		const anonymizeLothLogs = `
+		UPDATE loth_logs SET
+		  role = CASE WHEN name = ? THEN '' ELSE role END,
+		  status = CASE WHEN name = ? THEN '' ELSE status END,
+		  name = CASE WHEN name = ? THEN ? ELSE name END,
+		  issuer = CASE WHEN issuer = ? THEN ? ELSE issuer END
+		WHERE issuer = ? OR name = ?`
+		args := []interface{}{name, name, name, eribo.Forgotten, name, eribo.Forgotten, name, name}
+		if r.LothLogs, err = rowsAffected(tx.Exec(anonymizeLothLogs, args...)); err != nil {
+			return err
+		}
+		if r.Targets, err = removeTargets(tx, name); err != nil {
+			return err
+		}
+		if audit == nil {
+			return nil
+		}
+		audit.Detail = r.String()
+		return addAuditLog(tx, audit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// removeTargets removes a player from the targets of the loth logs. The
+// query only narrows down the logs whose targets may have the player, which
+// are then checked in Go.
+func removeTargets(tx *sqlx.Tx, name string) (int64, error) {
+	b, err := json.Marshal(name)
+	if err != nil {
+		return 0, err
+	}
+	logs := []struct {
+		ID      int64
+		Targets eribo.Targets
+	}{}
+	const query = `SELECT id, targets FROM loth_logs WHERE LOWER(targets) LIKE ?`
+	if err := tx.Select(&logs, query, "%"+strings.ToLower(string(b))+"%"); err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, l := range logs {
+		targets, ok := l.Targets.RemoveTarget(name)
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE loth_logs SET targets = ? WHERE id = ?`, targets, l.ID); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func rowsAffected(r sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+func (db *EriboStore) AddAuditLog(a *eribo.AuditLog) error {
+	return addAuditLog(db, a)
+}
+
+func addAuditLog(e sqlx.Execer, a *eribo.AuditLog) error {
+	if (a.Created == time.Time{}) {
+		a.Created = time.Now().UTC().Truncate(timeTruncate)
+	}
+	const query = `INSERT INTO audit_logs(action, actor, detail, created) VALUES (?, ?, ?, ?)`
+	res, err := e.Exec(query, a.Action, a.Actor, a.Detail, a.Created.UTC())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	return nil
+}
+
+func (db *EriboStore) ListAuditLogs(q eribo.Query) ([]*eribo.AuditLog, error) {
+	clause, args := where(q, columns{id: "id", created: "created", players: []string{"actor"}})
+	logs := []*eribo.AuditLog{}
+	if err := db.Select(&logs, `SELECT * FROM audit_logs`+clause, args...); err != nil {
+		return nil, err
+	}
+	utcAuditLogs(logs)
+	return logs, nil
+}
@@ -0,0 +1,207 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kusubooru/eribo/eribo/migrate"
+)
+
+// migrations are the versions of the schema. The tables are created only if
+// they do not exist for databases created before migrations existed.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create tables",
+		Up:      migrate.SQL(tableMessages, tableImages, indexImagesURL, tableFeedback, tableCmdLogs, tableLothLogs),
+		Down: migrate.SQL(
+			`DROP TABLE loth_logs`,
+			`DROP TABLE cmd_logs`,
+			`DROP TABLE feedback`,
+			`DROP TABLE images`,
+			`DROP TABLE messages`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create audit_logs",
+		Up:      migrate.SQL(tableAuditLogs),
+		Down:    migrate.SQL(`DROP TABLE audit_logs`),
+	},
+	{
+		Version: 3,
+		Name:    "add feedback status, note, tags and replies",
+		Up: migrate.SQL(
+			`ALTER TABLE feedback ADD COLUMN status TEXT NOT NULL DEFAULT 'open'`,
+			`ALTER TABLE feedback ADD COLUMN note TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE feedback ADD COLUMN tags TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(tags))`,
+			tableFeedbackReplies,
+			indexFeedbackRepliesPlayer,
+		),
+		Down: migrate.SQL(
+			`DROP TABLE feedback_replies`,
+			`ALTER TABLE feedback DROP COLUMN tags`,
+			`ALTER TABLE feedback DROP COLUMN note`,
+			`ALTER TABLE feedback DROP COLUMN status`,
+		),
+	},
+	{
+		Version: 4,
+		Name:    "add cmd_logs indexes",
+		Up:      migrate.SQL(indexCmdLogsCreated, indexCmdLogsCommand, indexCmdLogsPlayer, indexCmdLogsChannel),
+		Down: migrate.SQL(
+			`DROP INDEX cmd_logs_created`,
+			`DROP INDEX cmd_logs_command`,
+			`DROP INDEX cmd_logs_player`,
+			`DROP INDEX cmd_logs_channel`,
+		),
+	},
+	{
+		// Reposts are told apart by the normalized URL. Images stored
+		// before are not normalized again, so their URL stands in for it.
+		// Images stored twice by concurrent messages are merged into the
+		// first one.
+		Version: 5,
+		Name:    "make image urls unique",
+		Up: func(tx *sqlx.Tx) error {
+			err := migrate.SQL(
+				`ALTER TABLE images ADD COLUMN norm_url TEXT NOT NULL DEFAULT ''`,
+				`UPDATE images SET norm_url = url`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			if err := dedupImages(tx); err != nil {
+				return err
+			}
+			return migrate.SQL(indexImagesNormURL)(tx)
+		},
+		Down: migrate.SQL(`DROP INDEX images_norm_url`, `ALTER TABLE images DROP COLUMN norm_url`),
+	},
+}
+
+// Migrator returns the migrator of the database schema.
+func (db *EriboStore) Migrator() *migrate.Migrator {
+	return migrate.New(db.DB, migrations)
+}
+
+// dedupImages keeps the first of the images with the same normalized URL and
+// deletes the others. The first one takes their done flag and kuid so that no
+// upload is lost. Images uploaded as different posts cannot be merged and fail
+// the migration until their kuids are fixed by hand.
+func dedupImages(tx *sqlx.Tx) error {
+	const selectConflicts = `
+	SELECT a.id AS a, b.id AS b
+	FROM images a JOIN images b ON a.norm_url = b.norm_url AND a.id < b.id
+	WHERE a.kuid != 0 AND b.kuid != 0 AND a.kuid != b.kuid
+	ORDER BY a.id, b.id`
+	var conflicts []struct{ A, B int64 }
+	if err := tx.Select(&conflicts, selectConflicts); err != nil {
+		return err
+	}
+	if len(conflicts) != 0 {
+		pairs := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			pairs[i] = fmt.Sprintf("%d and %d", c.A, c.B)
+		}
+		return fmt.Errorf("images with the same url have different kuids: %s", strings.Join(pairs, ", "))
+	}
+
+	const merge = `
+	UPDATE images SET
+	  done = (SELECT MAX(done) FROM images b WHERE b.norm_url = images.norm_url),
+	  kuid = (SELECT MAX(kuid) FROM images b WHERE b.norm_url = images.norm_url)
+	WHERE id IN (SELECT MIN(id) FROM images GROUP BY norm_url HAVING COUNT(*) > 1)`
+	return migrate.SQL(merge, `DELETE FROM images WHERE id NOT IN (SELECT MIN(id) FROM images GROUP BY norm_url)`)(tx)
+}
+
+// The tables are the ones of the MySQL store. Names are compared case
+// insensitively as they are by MySQL's default collation, the TIMESTAMP type
+// makes the driver scan the columns as times and loth_logs.targets holds the
+// JSON of eribo.Targets.
+const (
+	tableMessages = `
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message TEXT NOT NULL,
+	player TEXT NOT NULL COLLATE NOCASE,
+	channel TEXT NOT NULL COLLATE NOCASE,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+	tableImages = `
+CREATE TABLE IF NOT EXISTS images (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	done BOOL NOT NULL DEFAULT 0,
+	kuid INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL DEFAULT 0,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	message_id INTEGER NOT NULL REFERENCES messages(id)
+)`
+
+	indexImagesURL     = `CREATE INDEX IF NOT EXISTS images_url ON images(url)`
+	indexImagesNormURL = `CREATE UNIQUE INDEX images_norm_url ON images(norm_url)`
+
+	tableFeedback = `
+CREATE TABLE IF NOT EXISTS feedback (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message TEXT NOT NULL,
+	player TEXT NOT NULL COLLATE NOCASE,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+	tableCmdLogs = `
+CREATE TABLE IF NOT EXISTS cmd_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	command TEXT NOT NULL,
+	args TEXT NOT NULL DEFAULT '',
+	player TEXT NOT NULL COLLATE NOCASE,
+	channel TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+	// The indexes serve the command usage queries, which select the logs by
+	// time and by command, player or channel.
+	indexCmdLogsCreated = `CREATE INDEX cmd_logs_created ON cmd_logs(created)`
+	indexCmdLogsCommand = `CREATE INDEX cmd_logs_command ON cmd_logs(command, created)`
+	indexCmdLogsPlayer  = `CREATE INDEX cmd_logs_player ON cmd_logs(player, created)`
+	indexCmdLogsChannel = `CREATE INDEX cmd_logs_channel ON cmd_logs(channel, created)`
+
+	tableLothLogs = `
+CREATE TABLE IF NOT EXISTS loth_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	issuer TEXT NOT NULL COLLATE NOCASE,
+	channel TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+	name TEXT NOT NULL COLLATE NOCASE,
+	role TEXT NOT NULL,
+	status TEXT NOT NULL,
+	is_new BOOL NOT NULL,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	targets TEXT NOT NULL CHECK (json_valid(targets))
+)`
+
+	tableFeedbackReplies = `
+CREATE TABLE feedback_replies (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	feedback_id INTEGER NOT NULL REFERENCES feedback(id),
+	player TEXT NOT NULL COLLATE NOCASE,
+	message TEXT NOT NULL,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent TIMESTAMP
+)`
+
+	indexFeedbackRepliesPlayer = `CREATE INDEX feedback_replies_player ON feedback_replies(player, sent)`
+
+	tableAuditLogs = `
+CREATE TABLE audit_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+	detail TEXT NOT NULL,
+	created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+)
@@ -0,0 +1,59 @@
+// Package sqlite implements eribo.Store with SQLite, which needs no server
+// and suits trying the bot locally and tests. The data source is a file name
+// or URI as accepted by modernc.org/sqlite, e.g. "eribo.db" or
+// "file::memory:".
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+type EriboStore struct {
+	*sqlx.DB
+}
+
+// NewEriboStore opens the store and applies the migrations that the database
+// does not have yet. It fails if the database schema is newer than the
+// migrations.
+func NewEriboStore(dataSource string) (*EriboStore, error) {
+	store, err := Open(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Migrator().Up(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Open opens the store without migrating the database.
+func Open(dataSource string) (*EriboStore, error) {
+	db, err := sqlx.Open("sqlite", withParams(dataSource))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time and every connection to an in
+	// memory database is a database of its own, so the store uses one
+	// connection.
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	return &EriboStore{DB: db}, nil
+}
+
+// withParams adds the parameters the store needs to the data source: foreign
+// keys are enforced like in MySQL and times are written in a format that
+// sorts and compares like the time.
+func withParams(dataSource string) string {
+	params := []string{"_pragma=foreign_keys(1)", "_pragma=busy_timeout(5000)", "_time_format=sqlite"}
+	sep := "?"
+	if strings.Contains(dataSource, "?") {
+		sep = "&"
+	}
+	return dataSource + sep + strings.Join(params, "&")
+}
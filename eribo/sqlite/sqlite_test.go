@@ -0,0 +1,256 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/migrate"
+	"github.com/kusubooru/eribo/eribo/storetest"
+)
+
+func setup(t *testing.T) *EriboStore {
+	s, err := NewEriboStore(filepath.Join(t.TempDir(), "eribo.db"))
+	if err != nil {
+		t.Fatal("NewEriboStore failed:", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return s
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) eribo.Store { return setup(t) })
+}
+
+func TestNewEriboStore_memory(t *testing.T) {
+	s, err := NewEriboStore("file::memory:")
+	if err != nil {
+		t.Fatal("NewEriboStore failed:", err)
+	}
+	defer s.Close()
+	if err := s.AddFeedback(&eribo.Feedback{Player: "foo", Message: "bar"}); err != nil {
+		t.Fatal("AddFeedback failed:", err)
+	}
+	fb, err := s.GetAllFeedback(10, 0)
+	if err != nil {
+		t.Fatal("GetAllFeedback failed:", err)
+	}
+	if len(fb) != 1 {
+		t.Errorf("GetAllFeedback = %d feedback, want 1", len(fb))
+	}
+}
+
+func TestAddMessageWithImages_rollback(t *testing.T) {
+	s := setup(t)
+	const trigger = `
+	CREATE TRIGGER fail_image BEFORE INSERT ON images WHEN NEW.url = 'http://bad'
+	BEGIN SELECT RAISE(ABORT, 'bad image'); END`
+	if _, err := s.Exec(trigger); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz"}
+	if err := s.AddMessageWithImages(m, imagesFor("http://good", "http://bad")); err == nil {
+		t.Fatal("AddMessageWithImages with a failing image succeeded")
+	}
+
+	var messages, images int
+	if err := s.Get(&messages, `SELECT COUNT(*) FROM messages`); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Get(&images, `SELECT COUNT(*) FROM images`); err != nil {
+		t.Fatal(err)
+	}
+	if messages != 0 || images != 0 {
+		t.Errorf("after failed AddMessageWithImages: %d messages, %d images, want none", messages, images)
+	}
+}
+
+func TestForgetPlayer_auditRollback(t *testing.T) {
+	s := setup(t)
+	if err := s.AddFeedback(&eribo.Feedback{Player: "foo", Message: "hi"}); err != nil {
+		t.Fatal("AddFeedback failed:", err)
+	}
+	const trigger = `
+	CREATE TRIGGER fail_audit BEFORE INSERT ON audit_logs
+	BEGIN SELECT RAISE(ABORT, 'no audit'); END`
+	if _, err := s.Exec(trigger); err != nil {
+		t.Fatal(err)
+	}
+
+	audit := &eribo.AuditLog{Action: eribo.AuditForgetMe, Actor: "foo"}
+	if _, err := s.ForgetPlayer("foo", audit); err == nil {
+		t.Fatal("ForgetPlayer with a failing audit log succeeded")
+	}
+	var feedback int
+	if err := s.Get(&feedback, `SELECT COUNT(*) FROM feedback`); err != nil {
+		t.Fatal(err)
+	}
+	if feedback != 1 {
+		t.Errorf("after ForgetPlayer failed to add the audit log: %d feedback, want it kept", feedback)
+	}
+}
+
+func TestAddMessageWithImages_conflict(t *testing.T) {
+	s := setup(t)
+	if err := s.AddMessageWithImages(&eribo.Message{Message: "first"}, imagesFor("http://first")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	// The trigger stores the image as if another message did after it was
+	// checked.
+	const trigger = `
+	CREATE TRIGGER race AFTER INSERT ON messages WHEN NEW.message != 'first'
+	BEGIN INSERT INTO images(url, norm_url, message_id) VALUES ('http://raced', 'http://raced', 1); END`
+	if _, err := s.Exec(trigger); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &eribo.Message{Message: "partial"}
+	images := imagesFor("http://raced", "http://new")
+	if err := s.AddMessageWithImages(m, images); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	if m.ID == 0 || images[0].ID != 0 || images[1].ID == 0 {
+		t.Errorf("IDs after conflict: message %d, images %d and %d, want the raced image skipped", m.ID, images[0].ID, images[1].ID)
+	}
+
+	if _, err := s.Exec(`DELETE FROM images WHERE url = 'http://raced'`); err != nil {
+		t.Fatal(err)
+	}
+	m = &eribo.Message{Message: "repost"}
+	if err := s.AddMessageWithImages(m, imagesFor("http://raced")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	var messages int
+	if err := s.Get(&messages, `SELECT COUNT(*) FROM messages WHERE message = 'repost'`); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 0 || messages != 0 {
+		t.Errorf("message whose only image conflicted: ID %d, stored %d times, want it not stored", m.ID, messages)
+	}
+}
+
+func TestMigrateUniqueImageURLs(t *testing.T) {
+	s := setup(t)
+	m := s.Migrator()
+	if err := m.To(4); err != nil {
+		t.Fatal("migrating down to 4 failed:", err)
+	}
+	for _, q := range []string{
+		`INSERT INTO messages(message, player, channel) VALUES ('a', 'foo', 'bar')`,
+		`INSERT INTO images(url, message_id) VALUES ('http://url', 1)`,
+		`INSERT INTO images(url, done, kuid, message_id) VALUES ('http://url', 1, 42, 1)`,
+		`INSERT INTO images(url, message_id) VALUES ('http://url', 1)`,
+		`INSERT INTO images(url, kuid, message_id) VALUES ('http://other', 7, 1)`,
+	} {
+		if _, err := s.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal("migrating up failed:", err)
+	}
+	var images []struct {
+		ID   int64
+		Done bool
+		Kuid int
+	}
+	if err := s.Select(&images, `SELECT id, done, kuid FROM images ORDER BY id`); err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0].ID != 1 || !images[0].Done || images[0].Kuid != 42 || images[1].ID != 4 {
+		t.Errorf("images after making urls unique = %+v, want the first one done with kuid 42 and the other url", images)
+	}
+}
+
+func TestMigrateUniqueImageURLs_conflict(t *testing.T) {
+	s := setup(t)
+	m := s.Migrator()
+	if err := m.To(4); err != nil {
+		t.Fatal("migrating down to 4 failed:", err)
+	}
+	for _, q := range []string{
+		`INSERT INTO messages(message, player, channel) VALUES ('a', 'foo', 'bar')`,
+		`INSERT INTO images(url, kuid, message_id) VALUES ('http://url', 1, 1)`,
+		`INSERT INTO images(url, kuid, message_id) VALUES ('http://url', 2, 1)`,
+	} {
+		if _, err := s.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "1 and 2") {
+		t.Errorf("migrating up images with different kuids = %v, want error listing 1 and 2", err)
+	}
+	var n int
+	if err := s.Get(&n, `SELECT COUNT(*) FROM images`); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("images after failed migration = %d, want 2", n)
+	}
+}
+
+func TestTimesUTC(t *testing.T) {
+	s := setup(t)
+	created := time.Date(2018, 5, 1, 15, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
+	if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo", Created: created}); err != nil {
+		t.Fatal("AddCmdLog failed:", err)
+	}
+	l, err := s.GetCmdLog(1)
+	if err != nil {
+		t.Fatal("GetCmdLog failed:", err)
+	}
+	if want := created.UTC(); l.Created != want {
+		t.Errorf("created = %v, want %v", l.Created, want)
+	}
+	logs, err := s.ListCmdLogs(eribo.Query{Since: created})
+	if err != nil {
+		t.Fatal("ListCmdLogs failed:", err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("ListCmdLogs since creation in another zone = %d logs, want 1", len(logs))
+	}
+}
+
+func TestNewEriboStore_migrations(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "eribo.db")
+	s, err := NewEriboStore(file)
+	if err != nil {
+		t.Fatal("NewEriboStore failed:", err)
+	}
+	m := s.Migrator()
+	if err := m.To(0); err != nil {
+		t.Fatal("migrating down to 0 failed:", err)
+	}
+	var n int
+	if err := s.Get(&n, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' AND name NOT LIKE 'sqlite_%'`); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("after migrating down to 0: %d tables, want none", n)
+	}
+	if _, err := s.Exec(`INSERT INTO schema_migrations(version, name) VALUES (?, ?)`, m.Latest()+1, "from the future"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if _, err := NewEriboStore(file); !errors.Is(err, migrate.ErrNewerSchema) {
+		t.Errorf("NewEriboStore on newer schema = %v, want %v", err, migrate.ErrNewerSchema)
+	}
+}
+
+func imagesFor(urls ...string) []*eribo.Image {
+	images := make([]*eribo.Image, len(urls))
+	for i, u := range urls {
+		images[i] = &eribo.Image{URL: u}
+	}
+	return images
+}
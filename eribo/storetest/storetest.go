@@ -0,0 +1,1150 @@
+// Package storetest is a conformance test suite for the implementations of
+// eribo.Store. A backend runs it from its own tests:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) eribo.Store {
+//			return newEmptyStore(t)
+//		})
+//	}
+package storetest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// Run runs the suite. Each test gets a new empty store from newStore, whose
+// IDs start at 1, and should clean it up with t.Cleanup.
+func Run(t *testing.T, newStore func(t *testing.T) eribo.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s eribo.Store)
+	}{
+		{"AddMessageWithImages", testAddMessageWithImages},
+		{"AddMessageWithImages_dedup", testAddMessageWithImagesDedup},
+		{"AddMessageWithImages_concurrent", testAddMessageWithImagesConcurrent},
+		{"GetImages", testGetImages},
+		{"SetImageKuid", testSetImageKuid},
+		{"ToggleImageDone", testToggleImageDone},
+		{"ListImages", testListImages},
+		{"AddFeedback", testAddFeedback},
+		{"GetRecentFeedback", testGetRecentFeedback},
+		{"ListFeedback", testListFeedback},
+		{"UpdateFeedback", testUpdateFeedback},
+		{"FeedbackReplies", testFeedbackReplies},
+		{"GetRecentCmdLogs", testGetRecentCmdLogs},
+		{"ListCmdLogs", testListCmdLogs},
+		{"GetRecentLothLogs", testGetRecentLothLogs},
+		{"GetRecentLothLogs_noLoth", testGetRecentLothLogsNoLoth},
+		{"ListLothLogs", testListLothLogs},
+		{"GetImages_table", testGetImagesTable},
+		{"ToggleImageDone_table", testToggleImageDoneTable},
+		{"GetRecent_table", testGetRecentTable},
+		{"CmdStats", testCmdStats},
+		{"CmdUsage", testCmdUsage},
+		{"DeleteBefore", testDeleteBefore},
+		{"ForgetPlayer", testForgetPlayer},
+		{"AuditLogs", testAuditLogs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+// day is when the records of the tests are created. Times are whole seconds
+// in UTC, which all backends keep as they are.
+var day = time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func imagesFor(urls ...string) []*eribo.Image {
+	images := make([]*eribo.Image, len(urls))
+	for i, u := range urls {
+		images[i] = &eribo.Image{URL: u}
+	}
+	return images
+}
+
+func deepEqual(t *testing.T, have, want interface{}, message string) {
+	t.Helper()
+	if !reflect.DeepEqual(have, want) {
+		h, _ := json.Marshal(have)
+		w, _ := json.Marshal(want)
+		t.Fatalf("%s\nhave: %s\nwant: %s", message, h, w)
+	}
+}
+
+func testAddMessageWithImages(t *testing.T, s eribo.Store) {
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: day}
+	if err := s.AddMessageWithImages(m, imagesFor("http://url1", "http://url2")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+
+	have, err := s.GetImages(5, 0, false, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	want := []*eribo.Image{
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+	}
+	deepEqual(t, have, want, "AddMessageWithImages")
+
+	img, err := s.GetImage(2)
+	if err != nil {
+		t.Fatal("GetImage failed:", err)
+	}
+	deepEqual(t, img, want[1], "GetImage")
+	if _, err := s.GetImage(3); err == nil {
+		t.Error("GetImage of a missing image succeeded")
+	}
+}
+
+func testAddMessageWithImagesDedup(t *testing.T, s eribo.Store) {
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: day}
+	images := []*eribo.Image{
+		{URL: "http://www.url1.png?si=1", NormURL: "http://url1.png", ContentType: "image/png", Size: 42},
+		{URL: "http://url2.gif", ContentType: "image/gif"},
+	}
+	if err := s.AddMessageWithImages(m, images); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	// A message that only reposts images is not stored. Images are reposts
+	// when their normalized URLs are the same.
+	m2 := &eribo.Message{Channel: "foo", Player: "bar", Message: "repost", Created: day.Add(time.Second)}
+	if err := s.AddMessageWithImages(m2, imagesFor("http://url1.png")); err != nil {
+		t.Fatal("AddMessageWithImages repost failed:", err)
+	}
+	m3 := &eribo.Message{Channel: "foo", Player: "qux", Message: "new", Created: day.Add(2 * time.Second)}
+	partial := imagesFor("http://url2.gif", "http://url3")
+	if err := s.AddMessageWithImages(m3, partial); err != nil {
+		t.Fatal("AddMessageWithImages partial repost failed:", err)
+	}
+	ids := [][2]int64{{m.ID, 0}, {images[0].ID, images[0].MessageID}, {images[1].ID, images[1].MessageID},
+		{m2.ID, 0}, {m3.ID, 0}, {partial[0].ID, partial[0].MessageID}, {partial[1].ID, partial[1].MessageID}}
+	wantIDs := [][2]int64{{1, 0}, {1, 1}, {2, 1}, {0, 0}, {2, 0}, {0, 0}, {3, 2}}
+	deepEqual(t, ids, wantIDs, "IDs set by AddMessageWithImages")
+
+	have, err := s.GetImages(5, 0, false, false, "image/png")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	want := []*eribo.Image{
+		{ID: 1, URL: "http://www.url1.png?si=1", NormURL: "http://url1.png", ContentType: "image/png", Size: 42, MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+	}
+	deepEqual(t, have, want, "GetImages of type after repost")
+
+	all, err := s.GetImages(5, 0, false, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("GetImages after reposts = %d images, want 3", len(all))
+	}
+	if img := all[2]; img.URL != "http://url3" || img.MessageID != 2 || img.Message.Message != "new" {
+		t.Errorf("image of partial repost = %s of message %d %q, want http://url3 of message 2 \"new\"", img.URL, img.MessageID, img.Message.Message)
+	}
+}
+
+func testAddMessageWithImagesConcurrent(t *testing.T, s eribo.Store) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m := &eribo.Message{Channel: "foo", Player: "bar", Message: fmt.Sprint(i), Created: day}
+			if err := s.AddMessageWithImages(m, imagesFor("http://url")); err != nil {
+				t.Error("AddMessageWithImages failed:", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	images, err := s.GetImages(10, 0, false, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	if len(images) != 1 {
+		t.Errorf("GetImages after concurrent posts of an image = %d images, want 1", len(images))
+	}
+}
+
+func testGetImages(t *testing.T, s eribo.Store) {
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: day}
+	if err := s.AddMessageWithImages(m, imagesFor("http://url1", "http://url2")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	created2 := day.Add(time.Second)
+	m2 := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: created2}
+	if err := s.AddMessageWithImages(m2, imagesFor("http://url3")); err != nil {
+		t.Fatal("AddMessageWithImages second failed:", err)
+	}
+
+	have, err := s.GetImages(5, 0, true, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	want := []*eribo.Image{
+		{ID: 3, URL: "http://url3", NormURL: "http://url3", MessageID: 2, Created: created2,
+			Message: &eribo.Message{ID: 2, Channel: "foo", Player: "bar", Message: "baz", Created: created2},
+		},
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+	}
+	deepEqual(t, have, want, "GetImages reverse")
+}
+
+func testSetImageKuid(t *testing.T, s eribo.Store) {
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: day}
+	if err := s.AddMessageWithImages(m, imagesFor("http://url")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	if err := s.SetImageKuid(1, 1337); err != nil {
+		t.Fatal("SetImageKuid failed:", err)
+	}
+
+	have, err := s.GetImage(1)
+	if err != nil {
+		t.Fatal("GetImage failed:", err)
+	}
+	want := &eribo.Image{
+		ID:        1,
+		URL:       "http://url",
+		NormURL:   "http://url",
+		Done:      true,
+		Kuid:      1337,
+		Created:   day,
+		MessageID: 1,
+		Message:   &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+	}
+	deepEqual(t, have, want, "SetImageKuid")
+}
+
+func testToggleImageDone(t *testing.T, s eribo.Store) {
+	m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: day}
+	if err := s.AddMessageWithImages(m, imagesFor("http://url1", "http://url2")); err != nil {
+		t.Fatal("AddMessageWithImages failed:", err)
+	}
+	if err := s.ToggleImageDone(2); err != nil {
+		t.Fatal("ToggleImageDone failed:", err)
+	}
+
+	have, err := s.GetImages(5, 0, false, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	want := []*eribo.Image{
+		{ID: 1, URL: "http://url1", NormURL: "http://url1", MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+		{ID: 2, URL: "http://url2", NormURL: "http://url2", Done: true, MessageID: 1, Created: day,
+			Message: &eribo.Message{ID: 1, Channel: "foo", Player: "bar", Message: "baz", Created: day},
+		},
+	}
+	deepEqual(t, have, want, "ToggleImageDone")
+}
+
+func testListImages(t *testing.T, s eribo.Store) {
+	messages := []*eribo.Message{
+		{Channel: "room", Player: "foo", Message: "a", Created: day},
+		{Channel: "room", Player: "bar", Message: "b", Created: day.Add(time.Hour)},
+		{Channel: "other", Player: "foo", Message: "c", Created: day.Add(2 * time.Hour)},
+	}
+	for i, m := range messages {
+		if err := s.AddMessageWithImages(m, imagesFor("http://url"+string(rune('1'+i)))); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+
+	tests := []struct {
+		q    eribo.Query
+		want []int64
+	}{
+		{eribo.Query{}, []int64{3, 2, 1}},
+		{eribo.Query{Limit: 1, BeforeID: 3}, []int64{2}},
+		{eribo.Query{Player: "FOO"}, []int64{3, 1}},
+		{eribo.Query{Channel: "room"}, []int64{2, 1}},
+		{eribo.Query{Since: day.Add(time.Hour)}, []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		have, err := s.ListImages(tt.q)
+		if err != nil {
+			t.Fatalf("ListImages(%+v) failed: %v", tt.q, err)
+		}
+		var ids []int64
+		for _, img := range have {
+			ids = append(ids, img.ID)
+		}
+		deepEqual(t, ids, tt.want, "ListImages")
+	}
+}
+
+func testAddFeedback(t *testing.T, s eribo.Store) {
+	feedback := []*eribo.Feedback{
+		{Player: "foo", Message: "bar", Created: day},
+		{Player: "foo", Message: "baz", Created: day, Status: eribo.FeedbackResolved, Note: "fixed", Tags: eribo.Tags{"bug", "loth"}},
+	}
+	for i, f := range feedback {
+		if err := s.AddFeedback(f); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+		if want := int64(i + 1); f.ID != want {
+			t.Errorf("AddFeedback set ID %d, want %d", f.ID, want)
+		}
+	}
+
+	have, err := s.GetAllFeedback(10, 0)
+	if err != nil {
+		t.Fatal("GetAllFeedback failed:", err)
+	}
+	want := []*eribo.Feedback{
+		{ID: 1, Player: "foo", Message: "bar", Created: day, Status: eribo.FeedbackOpen},
+		{ID: 2, Player: "foo", Message: "baz", Created: day, Status: eribo.FeedbackResolved, Note: "fixed", Tags: eribo.Tags{"bug", "loth"}},
+	}
+	deepEqual(t, have, want, "AddFeedback")
+}
+
+func testUpdateFeedback(t *testing.T, s eribo.Store) {
+	if err := s.AddFeedback(&eribo.Feedback{Player: "foo", Message: "bar", Created: day}); err != nil {
+		t.Fatal("AddFeedback failed:", err)
+	}
+	f, err := s.GetFeedback(1)
+	if err != nil {
+		t.Fatal("GetFeedback failed:", err)
+	}
+	f.Status, f.Note, f.Tags = eribo.FeedbackAcknowledged, "look into it", eribo.Tags{"idea"}
+	f.Message, f.Player = "changed", "changed"
+	if err := s.UpdateFeedback(f); err != nil {
+		t.Fatal("UpdateFeedback failed:", err)
+	}
+	have, err := s.GetFeedback(1)
+	if err != nil {
+		t.Fatal("GetFeedback failed:", err)
+	}
+	want := &eribo.Feedback{ID: 1, Player: "foo", Message: "bar", Created: day, Status: eribo.FeedbackAcknowledged, Note: "look into it", Tags: eribo.Tags{"idea"}}
+	deepEqual(t, have, want, "feedback after UpdateFeedback")
+
+	// Updating with the same values is not mistaken for missing feedback.
+	if err := s.UpdateFeedback(have); err != nil {
+		t.Error("UpdateFeedback without changes failed:", err)
+	}
+	have.Tags = nil
+	if err := s.UpdateFeedback(have); err != nil {
+		t.Fatal("UpdateFeedback failed:", err)
+	}
+	if have, err = s.GetFeedback(1); err != nil || have.Tags != nil {
+		t.Errorf("GetFeedback after clearing tags = %v, %v, want no tags", have, err)
+	}
+
+	if _, err := s.GetFeedback(2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetFeedback of missing feedback = %v, want %v", err, sql.ErrNoRows)
+	}
+	if err := s.UpdateFeedback(&eribo.Feedback{ID: 2, Status: eribo.FeedbackResolved}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateFeedback of missing feedback = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func testFeedbackReplies(t *testing.T, s eribo.Store) {
+	for _, player := range []string{"Foo", "bar"} {
+		if err := s.AddFeedback(&eribo.Feedback{Player: player, Message: "hi", Created: day}); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+	}
+	replies := []*eribo.FeedbackReply{
+		{FeedbackID: 1, Player: "Foo", Message: "thanks", Created: day},
+		{FeedbackID: 2, Player: "bar", Message: "no", Created: day.Add(time.Second)},
+		{FeedbackID: 1, Player: "Foo", Message: "fixed", Created: day.Add(2 * time.Second)},
+	}
+	for i, r := range replies {
+		if err := s.AddFeedbackReply(r); err != nil {
+			t.Fatal("AddFeedbackReply failed:", err)
+		}
+		if r.ID != int64(i+1) {
+			t.Errorf("AddFeedbackReply set ID %d, want %d", r.ID, i+1)
+		}
+	}
+	if err := s.AddFeedbackReply(&eribo.FeedbackReply{FeedbackID: 3, Player: "baz", Message: "who?"}); err == nil {
+		t.Error("AddFeedbackReply to missing feedback succeeded")
+	}
+
+	have, err := s.ListFeedbackReplies(1)
+	if err != nil {
+		t.Fatal("ListFeedbackReplies failed:", err)
+	}
+	deepEqual(t, have, []*eribo.FeedbackReply{replies[0], replies[2]}, "ListFeedbackReplies")
+
+	sent := day.Add(time.Hour)
+	if err := s.MarkReplySent(1, sent); err != nil {
+		t.Fatal("MarkReplySent failed:", err)
+	}
+	tests := []struct {
+		player string
+		want   []int64
+	}{
+		{"", []int64{2, 3}},
+		{"foo", []int64{3}},
+		{"baz", nil},
+	}
+	for _, tt := range tests {
+		have, err := s.ListUnsentReplies(tt.player)
+		if err != nil {
+			t.Fatalf("ListUnsentReplies(%q) failed: %v", tt.player, err)
+		}
+		var ids []int64
+		for _, r := range have {
+			ids = append(ids, r.ID)
+		}
+		deepEqual(t, ids, tt.want, fmt.Sprintf("ListUnsentReplies(%q)", tt.player))
+	}
+	have, err = s.ListFeedbackReplies(1)
+	if err != nil {
+		t.Fatal("ListFeedbackReplies failed:", err)
+	}
+	if have[0].Sent == nil || !have[0].Sent.Equal(sent) || have[1].Sent != nil {
+		t.Errorf("replies sent = %v, %v, want %v, nil", have[0].Sent, have[1].Sent, sent)
+	}
+
+	// Replies go with their feedback.
+	if _, err := s.ForgetPlayer("bar", nil); err != nil {
+		t.Fatal("ForgetPlayer failed:", err)
+	}
+	if _, err := s.DeleteBefore(eribo.TableFeedback, day.Add(time.Second)); err != nil {
+		t.Fatal("DeleteBefore failed:", err)
+	}
+	have, err = s.ListUnsentReplies("")
+	if err != nil {
+		t.Fatal("ListUnsentReplies failed:", err)
+	}
+	if len(have) != 0 {
+		t.Errorf("replies left after deleting their feedback = %d, want 0", len(have))
+	}
+}
+
+func testGetRecentFeedback(t *testing.T, s eribo.Store) {
+	for i := 1; i <= 3; i++ {
+		f := &eribo.Feedback{Player: "foo", Message: "bar", Created: day.Add(time.Duration(i) * time.Second)}
+		if err := s.AddFeedback(f); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+	}
+
+	have, err := s.GetRecentFeedback(2, 0)
+	if err != nil {
+		t.Fatal("GetRecentFeedback failed:", err)
+	}
+	want := []*eribo.Feedback{
+		{ID: 3, Player: "foo", Message: "bar", Created: day.Add(3 * time.Second), Status: eribo.FeedbackOpen},
+		{ID: 2, Player: "foo", Message: "bar", Created: day.Add(2 * time.Second), Status: eribo.FeedbackOpen},
+	}
+	deepEqual(t, have, want, "GetRecentFeedback")
+}
+
+func testListFeedback(t *testing.T, s eribo.Store) {
+	fb := []*eribo.Feedback{
+		{Player: "foo", Message: "a", Created: day},
+		{Player: "bar", Message: "b", Created: day.Add(time.Hour)},
+		{Player: "Foo", Message: "c", Created: day.Add(2 * time.Hour)},
+	}
+	for _, f := range fb {
+		if err := s.AddFeedback(f); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+	}
+	fb[1].Status = eribo.FeedbackResolved
+	if err := s.UpdateFeedback(fb[1]); err != nil {
+		t.Fatal("UpdateFeedback failed:", err)
+	}
+
+	tests := []struct {
+		q    eribo.Query
+		want []int64
+	}{
+		{eribo.Query{}, []int64{3, 2, 1}},
+		{eribo.Query{Player: "foo"}, []int64{3, 1}},
+		{eribo.Query{Status: eribo.FeedbackOpen}, []int64{3, 1}},
+		{eribo.Query{Status: eribo.FeedbackResolved, Player: "bar"}, []int64{2}},
+		{eribo.Query{Status: eribo.FeedbackWontfix}, nil},
+		{eribo.Query{Until: day.Add(time.Hour)}, []int64{1}},
+		// Feedback has no channel.
+		{eribo.Query{Channel: "room"}, nil},
+	}
+	for _, tt := range tests {
+		have, err := s.ListFeedback(tt.q)
+		if err != nil {
+			t.Fatalf("ListFeedback(%+v) failed: %v", tt.q, err)
+		}
+		var ids []int64
+		for _, f := range have {
+			ids = append(ids, f.ID)
+		}
+		deepEqual(t, ids, tt.want, "ListFeedback")
+	}
+}
+
+func testGetRecentCmdLogs(t *testing.T, s eribo.Store) {
+	for i := 1; i <= 3; i++ {
+		l := &eribo.CmdLog{Command: eribo.CmdTomato, Args: "x", Player: "foo", Channel: "room", Created: day.Add(time.Duration(i) * time.Second)}
+		if err := s.AddCmdLog(l); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+
+	have, err := s.GetRecentCmdLogs(2, 1)
+	if err != nil {
+		t.Fatal("GetRecentCmdLogs failed:", err)
+	}
+	want := []*eribo.CmdLog{
+		{ID: 2, Command: eribo.CmdTomato, Args: "x", Player: "foo", Channel: "room", Created: day.Add(2 * time.Second)},
+		{ID: 1, Command: eribo.CmdTomato, Args: "x", Player: "foo", Channel: "room", Created: day.Add(time.Second)},
+	}
+	deepEqual(t, have, want, "GetRecentCmdLogs with offset")
+}
+
+func testListCmdLogs(t *testing.T, s eribo.Store) {
+	logs := []*eribo.CmdLog{
+		{Command: eribo.CmdTomato, Player: "foo", Channel: "room", Created: day},
+		{Command: eribo.CmdTieup, Player: "bar", Channel: "room", Created: day.Add(time.Hour)},
+		{Command: eribo.CmdTomato, Player: "foo", Channel: "other", Created: day.Add(2 * time.Hour)},
+		{Command: eribo.CmdTomato, Player: "Foo", Channel: "room", Created: day.Add(3 * time.Hour)},
+	}
+	for i, l := range logs {
+		if err := s.AddCmdLog(l); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+		if want := int64(i + 1); l.ID != want {
+			t.Errorf("AddCmdLog set ID %d, want %d", l.ID, want)
+		}
+	}
+
+	tests := []struct {
+		q    eribo.Query
+		want []int64
+	}{
+		{eribo.Query{}, []int64{4, 3, 2, 1}},
+		{eribo.Query{Limit: 2}, []int64{4, 3}},
+		{eribo.Query{BeforeID: 3, Limit: 2}, []int64{2, 1}},
+		{eribo.Query{Player: "foo"}, []int64{4, 3, 1}},
+		{eribo.Query{Player: "foo", Channel: "room"}, []int64{4, 1}},
+		{eribo.Query{Command: eribo.CmdTomato}, []int64{4, 3, 1}},
+		{eribo.Query{Command: eribo.CmdTomato, Channel: "room", Limit: 1}, []int64{4}},
+		{eribo.Query{Since: day.Add(time.Hour), Until: day.Add(3 * time.Hour)}, []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		have, err := s.ListCmdLogs(tt.q)
+		if err != nil {
+			t.Fatalf("ListCmdLogs(%+v) failed: %v", tt.q, err)
+		}
+		var ids []int64
+		for _, l := range have {
+			ids = append(ids, l.ID)
+		}
+		deepEqual(t, ids, tt.want, "ListCmdLogs")
+	}
+}
+
+func testGetRecentLothLogs(t *testing.T, s eribo.Store) {
+	loth := &eribo.Loth{
+		Player:  &eribo.Player{Name: "foo", Role: flist.RoleSwitch, Status: flist.StatusOnline},
+		Expires: day.Add(time.Hour),
+	}
+	targets := eribo.Targets{
+		{Name: "bar", Role: flist.RoleFullSub, Status: flist.StatusLooking, Gender: flist.GenderFemale, Fave: true},
+		{Name: "baz"},
+	}
+	for i := 1; i <= 3; i++ {
+		l := &eribo.LothLog{Issuer: "jin", Channel: "2ch", Loth: loth, IsNew: true, Targets: targets, Created: day.Add(time.Duration(i) * time.Second)}
+		if err := s.AddLothLog(l); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+	}
+
+	have, err := s.GetRecentLothLogs(2, 0)
+	if err != nil {
+		t.Fatal("GetRecentLothLogs failed:", err)
+	}
+	want := []*eribo.LothLog{
+		{ID: 3, Issuer: "jin", Channel: "2ch", Created: day.Add(3 * time.Second),
+			Loth: &eribo.Loth{
+				Player:  &eribo.Player{Name: "foo", Role: flist.RoleSwitch, Status: flist.StatusOnline},
+				Expires: day.Add(time.Hour),
+			},
+			IsNew: true, Targets: targets},
+		{ID: 2, Issuer: "jin", Channel: "2ch", Created: day.Add(2 * time.Second),
+			Loth: &eribo.Loth{
+				Player:  &eribo.Player{Name: "foo", Role: flist.RoleSwitch, Status: flist.StatusOnline},
+				Expires: day.Add(time.Hour),
+			},
+			IsNew: true, Targets: targets},
+	}
+	deepEqual(t, have, want, "GetRecentLothLogs")
+}
+
+// testGetRecentLothLogsNoLoth checks the logs of loth commands that found no
+// eligible player, whose loth expires when it was chosen.
+func testGetRecentLothLogsNoLoth(t *testing.T, s eribo.Store) {
+	targets := eribo.Targets{{Name: "bar"}, {Name: "baz"}}
+	l := &eribo.LothLog{Issuer: "jin", Channel: "2ch", Targets: targets, Created: day}
+	if err := s.AddLothLog(l); err != nil {
+		t.Fatal("AddLothLog failed:", err)
+	}
+
+	have, err := s.GetRecentLothLogs(2, 0)
+	if err != nil {
+		t.Fatal("GetRecentLothLogs failed:", err)
+	}
+	want := []*eribo.LothLog{
+		{ID: 1, Issuer: "jin", Channel: "2ch", Created: day,
+			Loth:    &eribo.Loth{Player: &eribo.Player{}, Expires: day},
+			Targets: targets},
+	}
+	deepEqual(t, have, want, "GetRecentLothLogs without loth")
+}
+
+func testListLothLogs(t *testing.T, s eribo.Store) {
+	logs := []*eribo.LothLog{
+		{Issuer: "foo", Channel: "room", Loth: &eribo.Loth{Player: &eribo.Player{Name: "bar"}, Expires: day}, Targets: eribo.Targets{}, Created: day},
+		{Issuer: "bar", Channel: "room", Loth: &eribo.Loth{Player: &eribo.Player{Name: "Foo"}, Expires: day}, Targets: eribo.Targets{}, Created: day.Add(time.Hour)},
+		{Issuer: "baz", Channel: "other", Loth: &eribo.Loth{Player: &eribo.Player{Name: "qux"}, Expires: day}, Targets: eribo.Targets{}, Created: day.Add(2 * time.Hour)},
+	}
+	for i, l := range logs {
+		if err := s.AddLothLog(l); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+		if want := int64(i + 1); l.ID != want {
+			t.Errorf("AddLothLog set ID %d, want %d", l.ID, want)
+		}
+	}
+
+	tests := []struct {
+		q    eribo.Query
+		want []int64
+	}{
+		{eribo.Query{}, []int64{3, 2, 1}},
+		{eribo.Query{Player: "foo"}, []int64{2, 1}},
+		{eribo.Query{Channel: "room", Limit: 1}, []int64{2}},
+	}
+	for _, tt := range tests {
+		have, err := s.ListLothLogs(tt.q)
+		if err != nil {
+			t.Fatalf("ListLothLogs(%+v) failed: %v", tt.q, err)
+		}
+		var ids []int64
+		for _, l := range have {
+			ids = append(ids, l.ID)
+		}
+		deepEqual(t, ids, tt.want, "ListLothLogs")
+	}
+}
+
+// addImages stores one message with an image for each of the images, which
+// are created at the given seconds after day, so that the image IDs follow
+// the order of images.
+func addImages(t *testing.T, s eribo.Store, images []*eribo.Image, seconds []int) {
+	t.Helper()
+	for i, img := range images {
+		m := &eribo.Message{Channel: "room", Player: "foo", Message: img.URL, Created: day.Add(time.Duration(seconds[i]) * time.Second)}
+		if err := s.AddMessageWithImages(m, []*eribo.Image{img}); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+}
+
+func imageIDs(images []*eribo.Image) []int64 {
+	ids := []int64{}
+	for _, img := range images {
+		ids = append(ids, img.ID)
+	}
+	return ids
+}
+
+func testGetImagesTable(t *testing.T, s eribo.Store) {
+	// Images 1 and 4 are created at the same time and image 3 before image 2.
+	addImages(t, s, []*eribo.Image{
+		{URL: "http://1.png", ContentType: "image/png"},
+		{URL: "http://2.gif", ContentType: "image/gif"},
+		{URL: "http://3.png", ContentType: "image/png"},
+		{URL: "http://4.jpg", ContentType: "image/jpeg"},
+		{URL: "http://5.png", ContentType: "image/png"},
+	}, []int{0, 2, 1, 0, 3})
+	if err := s.SetImageKuid(3, 42); err != nil {
+		t.Fatal("SetImageKuid failed:", err)
+	}
+	if err := s.ToggleImageDone(5); err != nil {
+		t.Fatal("ToggleImageDone failed:", err)
+	}
+
+	tests := []struct {
+		limit, offset       int
+		reverse, filterDone bool
+		contentType         string
+		want                []int64
+	}{
+		{10, 0, false, false, "", []int64{1, 4, 3, 2, 5}},
+		{10, 0, true, false, "", []int64{5, 2, 3, 1, 4}},
+		{2, 0, false, false, "", []int64{1, 4}},
+		{2, 2, false, false, "", []int64{3, 2}},
+		{2, 4, false, false, "", []int64{5}},
+		{2, 5, false, false, "", []int64{}},
+		{10, 0, false, true, "", []int64{1, 4, 2}},
+		{10, 0, true, true, "", []int64{2, 1, 4}},
+		{10, 0, false, false, "image/png", []int64{1, 3, 5}},
+		{10, 0, false, true, "image/png", []int64{1}},
+		{1, 1, true, false, "image/png", []int64{3}},
+		{10, 0, false, false, "image/webp", []int64{}},
+	}
+	for _, tt := range tests {
+		have, err := s.GetImages(tt.limit, tt.offset, tt.reverse, tt.filterDone, tt.contentType)
+		if err != nil {
+			t.Fatalf("GetImages(%d, %d, %v, %v, %q) failed: %v", tt.limit, tt.offset, tt.reverse, tt.filterDone, tt.contentType, err)
+		}
+		if ids := imageIDs(have); !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("GetImages(%d, %d, %v, %v, %q) = %v, want %v", tt.limit, tt.offset, tt.reverse, tt.filterDone, tt.contentType, ids, tt.want)
+		}
+	}
+}
+
+func testToggleImageDoneTable(t *testing.T, s eribo.Store) {
+	addImages(t, s, imagesFor("http://1", "http://2"), []int{0, 1})
+
+	tests := []struct {
+		toggle   int64
+		wantErr  bool
+		wantDone []bool
+	}{
+		{1, false, []bool{true, false}},
+		{2, false, []bool{true, true}},
+		{1, false, []bool{false, true}},
+		{3, true, []bool{false, true}},
+	}
+	for _, tt := range tests {
+		err := s.ToggleImageDone(tt.toggle)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ToggleImageDone(%d) error = %v, want error %v", tt.toggle, err, tt.wantErr)
+		}
+		images, err := s.GetImages(10, 0, false, false, "")
+		if err != nil {
+			t.Fatal("GetImages failed:", err)
+		}
+		var done []bool
+		for _, img := range images {
+			done = append(done, img.Done)
+		}
+		if !reflect.DeepEqual(done, tt.wantDone) {
+			t.Errorf("after ToggleImageDone(%d) done = %v, want %v", tt.toggle, done, tt.wantDone)
+		}
+	}
+}
+
+// testGetRecentTable checks that the recent feedback, command logs and loth
+// logs are ordered by when they were created, not by ID, and paged by limit
+// and offset.
+func testGetRecentTable(t *testing.T, s eribo.Store) {
+	// Record 3 is the newest and records 1 and 4 are created at the same
+	// time, so the newer ID comes first.
+	seconds := []int{1, 2, 3, 1}
+	for _, sec := range seconds {
+		created := day.Add(time.Duration(sec) * time.Second)
+		if err := s.AddFeedback(&eribo.Feedback{Player: "foo", Message: "bar", Created: created}); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+		if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "foo", Created: created}); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+		if err := s.AddLothLog(&eribo.LothLog{Issuer: "foo", Targets: eribo.Targets{}, Created: created}); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+	}
+
+	tests := []struct {
+		limit, offset int
+		want          []int64
+	}{
+		{10, 0, []int64{3, 2, 4, 1}},
+		{2, 0, []int64{3, 2}},
+		{2, 2, []int64{4, 1}},
+		{10, 3, []int64{1}},
+		{10, 4, []int64{}},
+		{0, 0, []int64{}},
+	}
+	for _, tt := range tests {
+		fb, err := s.GetRecentFeedback(tt.limit, tt.offset)
+		if err != nil {
+			t.Fatal("GetRecentFeedback failed:", err)
+		}
+		cmdLogs, err := s.GetRecentCmdLogs(tt.limit, tt.offset)
+		if err != nil {
+			t.Fatal("GetRecentCmdLogs failed:", err)
+		}
+		lothLogs, err := s.GetRecentLothLogs(tt.limit, tt.offset)
+		if err != nil {
+			t.Fatal("GetRecentLothLogs failed:", err)
+		}
+		fbIDs, cmdIDs, lothIDs := []int64{}, []int64{}, []int64{}
+		for i := range fb {
+			fbIDs = append(fbIDs, fb[i].ID)
+		}
+		for i := range cmdLogs {
+			cmdIDs = append(cmdIDs, cmdLogs[i].ID)
+		}
+		for i := range lothLogs {
+			lothIDs = append(lothIDs, lothLogs[i].ID)
+		}
+		if !reflect.DeepEqual(fbIDs, tt.want) {
+			t.Errorf("GetRecentFeedback(%d, %d) = %v, want %v", tt.limit, tt.offset, fbIDs, tt.want)
+		}
+		if !reflect.DeepEqual(cmdIDs, tt.want) {
+			t.Errorf("GetRecentCmdLogs(%d, %d) = %v, want %v", tt.limit, tt.offset, cmdIDs, tt.want)
+		}
+		if !reflect.DeepEqual(lothIDs, tt.want) {
+			t.Errorf("GetRecentLothLogs(%d, %d) = %v, want %v", tt.limit, tt.offset, lothIDs, tt.want)
+		}
+	}
+
+	all, err := s.GetAllFeedback(2, 1)
+	if err != nil {
+		t.Fatal("GetAllFeedback failed:", err)
+	}
+	var ids []int64
+	for _, f := range all {
+		ids = append(ids, f.ID)
+	}
+	if want := []int64{2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetAllFeedback(2, 1) = %v, want %v in order of ID", ids, want)
+	}
+}
+
+func testCmdStats(t *testing.T, s eribo.Store) {
+	tests := []struct {
+		add  []eribo.Command
+		want []*eribo.CmdStat
+	}{
+		{nil, []*eribo.CmdStat{}},
+		{
+			[]eribo.Command{eribo.CmdTomato},
+			[]*eribo.CmdStat{{Command: eribo.CmdTomato, Uses: 1}},
+		},
+		{
+			[]eribo.Command{eribo.CmdTieup, eribo.CmdTieup},
+			[]*eribo.CmdStat{{Command: eribo.CmdTieup, Uses: 2}, {Command: eribo.CmdTomato, Uses: 1}},
+		},
+		{
+			// Equal uses are ordered by command.
+			[]eribo.Command{eribo.CmdTomato, eribo.CmdLoth},
+			[]*eribo.CmdStat{{Command: eribo.CmdTieup, Uses: 2}, {Command: eribo.CmdTomato, Uses: 2}, {Command: eribo.CmdLoth, Uses: 1}},
+		},
+	}
+	for i, tt := range tests {
+		for _, cmd := range tt.add {
+			if err := s.AddCmdLog(&eribo.CmdLog{Command: cmd, Player: "foo", Channel: "room", Created: day}); err != nil {
+				t.Fatal("AddCmdLog failed:", err)
+			}
+		}
+		have, err := s.CmdStats()
+		if err != nil {
+			t.Fatal("CmdStats failed:", err)
+		}
+		deepEqual(t, have, tt.want, fmt.Sprintf("CmdStats after step %d", i))
+	}
+}
+
+func testCmdUsage(t *testing.T, s eribo.Store) {
+	logs := []*eribo.CmdLog{
+		{Command: eribo.CmdTomato, Player: "Alice", Channel: "room", Created: day.Add(-2 * time.Hour)},
+		{Command: eribo.CmdTieup, Args: "Bob feet", Player: "Alice", Channel: "room", Created: day.Add(-time.Hour)},
+		{Command: eribo.CmdTieup, Args: "Alice Feet  arms", Player: "Bob", Channel: "other", Created: day.Add(22*time.Hour + 30*time.Minute)},
+		{Command: eribo.CmdTicklizer, Args: "feathers", Player: "Carol", Channel: "room", Created: day.Add(35 * time.Hour)},
+		{Command: eribo.CmdTieup, Args: "bob", Player: "Alice", Channel: "room", Created: day.Add(46 * time.Hour)},
+	}
+	for _, l := range logs {
+		if err := s.AddCmdLog(l); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+
+	counts := func(kv ...interface{}) []*eribo.CmdCount {
+		c := []*eribo.CmdCount{}
+		for i := 0; i < len(kv); i += 2 {
+			c = append(c, &eribo.CmdCount{Key: kv[i].(string), Uses: kv[i+1].(int)})
+		}
+		return c
+	}
+	tests := []struct {
+		q    eribo.CmdUsageQuery
+		want []*eribo.CmdCount
+	}{
+		{eribo.CmdUsageQuery{By: eribo.ByCommand}, counts("!tieup", 3, "!ticklizer", 1, "!tomato", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByCommand, Query: eribo.Query{Limit: 2}}, counts("!tieup", 3, "!ticklizer", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByChannel}, counts("room", 4, "other", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByPlayer, Query: eribo.Query{Command: eribo.CmdTieup}}, counts("Alice", 2, "Bob", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByPlayer, Query: eribo.Query{Channel: "ROOM", Since: day}}, counts("Alice", 1, "Carol", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByDay}, counts("2018-05-01", 2, "2018-05-02", 2, "2018-05-03", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByDay, Query: eribo.Query{Player: "alice", Until: day.Add(24 * time.Hour)}}, counts("2018-05-01", 2)},
+		{eribo.CmdUsageQuery{By: eribo.ByHour}, counts("10", 3, "11", 1, "23", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByHour, Query: eribo.Query{Command: eribo.CmdTomato}}, counts("10", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByArg, Query: eribo.Query{Command: eribo.CmdTieup}}, counts("bob", 2, "feet", 2, "alice", 1, "arms", 1)},
+		{eribo.CmdUsageQuery{By: eribo.ByArg, Query: eribo.Query{Command: eribo.CmdTieup, Limit: 1}}, counts("bob", 2)},
+		{eribo.CmdUsageQuery{By: eribo.ByArg, Query: eribo.Query{Command: eribo.CmdAstro}}, counts()},
+	}
+	for _, tt := range tests {
+		have, err := s.CmdUsage(tt.q)
+		if err != nil {
+			t.Fatalf("CmdUsage(%+v) failed: %v", tt.q, err)
+		}
+		deepEqual(t, have, tt.want, fmt.Sprintf("CmdUsage(%+v)", tt.q))
+	}
+
+	if _, err := s.CmdUsage(eribo.CmdUsageQuery{By: "week"}); err == nil {
+		t.Error("CmdUsage by week succeeded")
+	}
+	if _, err := s.CmdUsage(eribo.CmdUsageQuery{By: eribo.ByArg}); !errors.Is(err, eribo.ErrArgNeedsCommand) {
+		t.Errorf("CmdUsage by arg without a command = %v, want %v", err, eribo.ErrArgNeedsCommand)
+	}
+}
+
+func testDeleteBefore(t *testing.T, s eribo.Store) {
+	for i := 0; i < 3; i++ {
+		created := day.Add(time.Duration(i) * time.Hour)
+		m := &eribo.Message{Channel: "foo", Player: "bar", Message: "baz", Created: created}
+		if err := s.AddMessageWithImages(m, imagesFor(fmt.Sprintf("http://url%d.a", i), fmt.Sprintf("http://url%d.b", i))); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+		if err := s.AddFeedback(&eribo.Feedback{Player: "bar", Message: "baz", Created: created}); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+		if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: "bar", Created: created}); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+		if err := s.AddLothLog(&eribo.LothLog{Issuer: "bar", Created: created}); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+	}
+
+	tests := []struct {
+		table  eribo.Table
+		before time.Time
+		want   int64
+	}{
+		{eribo.TableMessages, day, 0},
+		{eribo.TableMessages, day.Add(time.Hour), 1},
+		{eribo.TableMessages, day.Add(time.Hour), 0},
+		{eribo.TableFeedback, day.Add(2 * time.Hour), 2},
+		{eribo.TableCmdLogs, day.Add(3 * time.Hour), 3},
+		{eribo.TableLothLogs, day.Add(time.Hour + time.Second), 2},
+	}
+	for _, tt := range tests {
+		n, err := s.DeleteBefore(tt.table, tt.before)
+		if err != nil {
+			t.Fatalf("DeleteBefore(%q, %v) failed: %v", tt.table, tt.before, err)
+		}
+		if n != tt.want {
+			t.Errorf("DeleteBefore(%q, %v) = %d, want %d", tt.table, tt.before, n, tt.want)
+		}
+	}
+	if _, err := s.DeleteBefore("images", day); err == nil {
+		t.Error("DeleteBefore of an unknown table succeeded")
+	}
+
+	images, err := s.GetImages(10, 0, false, false, "")
+	if err != nil {
+		t.Fatal("GetImages failed:", err)
+	}
+	var urls []string
+	for _, img := range images {
+		urls = append(urls, img.URL)
+	}
+	deepEqual(t, urls, []string{"http://url1.a", "http://url1.b", "http://url2.a", "http://url2.b"}, "images after deleting the first message")
+	if _, err := s.GetImage(1); err == nil {
+		t.Error("GetImage of an image of a deleted message succeeded")
+	}
+
+	feedback, err := s.ListFeedback(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListFeedback failed:", err)
+	}
+	cmdLogs, err := s.ListCmdLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListCmdLogs failed:", err)
+	}
+	lothLogs, err := s.ListLothLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListLothLogs failed:", err)
+	}
+	counts := []int{len(feedback), len(cmdLogs), len(lothLogs)}
+	deepEqual(t, counts, []int{1, 0, 1}, "feedback, cmd logs and loth logs left")
+	if lothLogs[0].ID != 3 {
+		t.Errorf("loth log left = %d, want 3", lothLogs[0].ID)
+	}
+
+	// IDs are not reused after deletes.
+	if err := s.AddFeedback(&eribo.Feedback{Player: "bar", Message: "new", Created: day}); err != nil {
+		t.Fatal("AddFeedback failed:", err)
+	}
+	feedback, err = s.ListFeedback(eribo.Query{Limit: 1})
+	if err != nil {
+		t.Fatal("ListFeedback failed:", err)
+	}
+	if feedback[0].ID != 4 {
+		t.Errorf("feedback added after deletes has ID %d, want 4", feedback[0].ID)
+	}
+}
+
+func testForgetPlayer(t *testing.T, s eribo.Store) {
+	posts := []*eribo.Message{
+		{Channel: "room", Player: "Foo", Message: "my cat http://cat.png", Created: day},
+		{Channel: "room", Player: "bar", Message: "http://dog.png", Created: day},
+	}
+	for i, m := range posts {
+		if err := s.AddMessageWithImages(m, imagesFor(fmt.Sprintf("http://img%d", i))); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+	for _, player := range []string{"foo", "bar", "FOO"} {
+		if err := s.AddFeedback(&eribo.Feedback{Player: player, Message: "hi", Created: day}); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+		if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTomato, Player: player, Channel: "room", Created: day}); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+	foo := &eribo.Player{Name: "foo", Role: flist.RoleFullSub, Status: flist.StatusLooking}
+	bar := &eribo.Player{Name: "bar", Role: flist.RoleFullDom, Status: flist.StatusOnline}
+	lothLogs := []*eribo.LothLog{
+		{Issuer: "bar", Channel: "room", Created: day, Loth: &eribo.Loth{Player: foo, Expires: day}, Targets: eribo.Targets{foo, bar}},
+		{Issuer: "foo", Channel: "room", Created: day, Loth: &eribo.Loth{Player: bar, Expires: day}, Targets: eribo.Targets{bar}},
+		{Issuer: "bar", Channel: "room", Created: day, Loth: &eribo.Loth{Player: bar, Expires: day}, Targets: eribo.Targets{bar, {Name: "Foo"}}},
+		{Issuer: "bar", Channel: "room", Created: day, Loth: &eribo.Loth{Player: bar, Expires: day}, Targets: eribo.Targets{{Name: "foobar"}}},
+	}
+	for _, l := range lothLogs {
+		if err := s.AddLothLog(l); err != nil {
+			t.Fatal("AddLothLog failed:", err)
+		}
+	}
+
+	audit := &eribo.AuditLog{Action: eribo.AuditForgetMe, Actor: "fOO", Created: day}
+	r, err := s.ForgetPlayer("fOO", audit)
+	if err != nil {
+		t.Fatal("ForgetPlayer failed:", err)
+	}
+	deepEqual(t, r, &eribo.ForgetResult{Messages: 1, Feedback: 2, CmdLogs: 2, LothLogs: 2, Targets: 2}, "ForgetPlayer result")
+	audits, err := s.ListAuditLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListAuditLogs failed:", err)
+	}
+	wantAudit := &eribo.AuditLog{ID: 1, Action: eribo.AuditForgetMe, Actor: "fOO", Detail: r.String(), Created: day}
+	deepEqual(t, audit, wantAudit, "audit log set by ForgetPlayer")
+	deepEqual(t, audits, []*eribo.AuditLog{wantAudit}, "audit logs after ForgetPlayer")
+
+	img, err := s.GetImage(1)
+	if err != nil {
+		t.Fatal("GetImage failed:", err)
+	}
+	if m := img.Message; m.Player != eribo.Forgotten || m.Message != "" || img.URL != "http://img0" {
+		t.Errorf("image of forgotten player = %s in message by %q %q, want http://img0 in message by %q \"\"", img.URL, m.Player, m.Message, eribo.Forgotten)
+	}
+	for _, q := range []eribo.Query{{Player: "foo"}, {Player: eribo.Forgotten}} {
+		feedback, err := s.ListFeedback(q)
+		if err != nil {
+			t.Fatal("ListFeedback failed:", err)
+		}
+		cmdLogs, err := s.ListCmdLogs(q)
+		if err != nil {
+			t.Fatal("ListCmdLogs failed:", err)
+		}
+		if len(feedback) != 0 || len(cmdLogs) != 0 {
+			t.Errorf("after ForgetPlayer %+v: %d feedback, %d cmd logs, want none", q, len(feedback), len(cmdLogs))
+		}
+	}
+
+	have, err := s.ListLothLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListLothLogs failed:", err)
+	}
+	type lothLog struct {
+		Issuer, Name, Role, Status string
+		Targets                    []string
+	}
+	var got []lothLog
+	for i := len(have) - 1; i >= 0; i-- {
+		l := have[i]
+		var targets []string
+		for _, p := range l.Targets {
+			targets = append(targets, p.Name)
+		}
+		got = append(got, lothLog{l.Issuer, l.Name, string(l.Role), string(l.Status), targets})
+	}
+	want := []lothLog{
+		{"bar", eribo.Forgotten, "", "", []string{"bar"}},
+		{eribo.Forgotten, "bar", "Always dominant", "online", []string{"bar"}},
+		{"bar", "bar", "Always dominant", "online", []string{"bar"}},
+		{"bar", "bar", "Always dominant", "online", []string{"foobar"}},
+	}
+	deepEqual(t, got, want, "loth logs after ForgetPlayer")
+
+	r, err = s.ForgetPlayer("foo", nil)
+	if err != nil {
+		t.Fatal("ForgetPlayer again failed:", err)
+	}
+	deepEqual(t, r, &eribo.ForgetResult{}, "ForgetPlayer again")
+}
+
+func testAuditLogs(t *testing.T, s eribo.Store) {
+	logs := []*eribo.AuditLog{
+		{Action: eribo.AuditPurge, Detail: "deleted 3 cmd_logs", Created: day},
+		{Action: eribo.AuditForgetMe, Actor: "Foo", Detail: "1 messages anonymized", Created: day.Add(time.Hour)},
+	}
+	for i, l := range logs {
+		if err := s.AddAuditLog(l); err != nil {
+			t.Fatal("AddAuditLog failed:", err)
+		}
+		if want := int64(i + 1); l.ID != want {
+			t.Errorf("AddAuditLog set ID %d, want %d", l.ID, want)
+		}
+	}
+	have, err := s.ListAuditLogs(eribo.Query{})
+	if err != nil {
+		t.Fatal("ListAuditLogs failed:", err)
+	}
+	want := []*eribo.AuditLog{
+		{ID: 2, Action: eribo.AuditForgetMe, Actor: "Foo", Detail: "1 messages anonymized", Created: day.Add(time.Hour)},
+		{ID: 1, Action: eribo.AuditPurge, Detail: "deleted 3 cmd_logs", Created: day},
+	}
+	deepEqual(t, have, want, "ListAuditLogs")
+
+	tests := []struct {
+		q    eribo.Query
+		want int
+	}{
+		{eribo.Query{Player: "foo"}, 1},
+		{eribo.Query{Channel: "room"}, 0},
+		{eribo.Query{Since: day.Add(time.Hour)}, 1},
+		{eribo.Query{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		have, err := s.ListAuditLogs(tt.q)
+		if err != nil {
+			t.Fatalf("ListAuditLogs(%+v) failed: %v", tt.q, err)
+		}
+		if len(have) != tt.want {
+			t.Errorf("ListAuditLogs(%+v) = %d logs, want %d", tt.q, len(have), tt.want)
+		}
+	}
+}
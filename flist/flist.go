@@ -6,13 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/kusubooru/eribo/logging"
 )
 
 var (
@@ -200,7 +203,7 @@ func (c *FLN) CmdDecode(data []byte) error { return cmdDecode(data, c) }
 // possibility is online.
 type NLN struct {
 	Identity string `json:"identity"`
-	Gender   string `json:"gender"`
+	Gender   Gender `json:"gender"`
 	Status   Status `json:"status"`
 }
 
@@ -298,6 +301,19 @@ const (
 	RoleFullSub = "Always submissive"
 )
 
+type Gender string
+
+const (
+	GenderMale        = "Male"
+	GenderFemale      = "Female"
+	GenderTransgender = "Transgender"
+	GenderHerm        = "Herm"
+	GenderShemale     = "Shemale"
+	GenderMaleHerm    = "Male-Herm"
+	GenderCuntBoy     = "Cunt-boy"
+	GenderNone        = "None"
+)
+
 type Status string
 
 func (s Status) IsActive() bool {
@@ -454,13 +470,26 @@ func (c *VAR) CmdDecode(data []byte) error {
 }
 
 type Client struct {
-	ws             *websocket.Conn
+	ws *websocket.Conn
+	// wmu serializes the writes to ws, which supports only one concurrent
+	// writer, so that the client can send from any goroutine.
+	wmu            sync.Mutex
 	Name           string
 	Version        string
 	mu             sync.Mutex
 	chatMax        int
 	privMax        int
 	joinedChannels []string
+	log            *slog.Logger
+	// noisy logs every message sent and received so it is sampled.
+	noisy *slog.Logger
+}
+
+// SetLogger sets the logger of the client, which is slog.Default() by
+// default.
+func (c *Client) SetLogger(l *slog.Logger) {
+	c.log = l
+	c.noisy = logging.Sample(l, 20, time.Second)
 }
 
 func (c *Client) AddJoinedChannel(ch string) {
@@ -492,11 +521,17 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) Disconnect() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
 	return c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 }
 
 func (c *Client) ReadMessage() ([]byte, error) {
 	_, message, err := c.ws.ReadMessage()
+	if err == nil {
+		messagesReceived.Inc(cmdType(message))
+		c.noisy.Debug("received", "cmd_type", cmdType(message), "size", len(message))
+	}
 	return message, err
 }
 
@@ -514,9 +549,13 @@ func Connect(url string) (*Client, error) {
 	dialer.WriteBufferSize = 52000
 	ws, _, err := dialer.Dial(url, nil)
 	if err != nil {
+		connects.Inc("error")
 		return nil, fmt.Errorf("dial: %v", err)
 	}
-	return &Client{ws: ws, Name: clientName, Version: clientVersion}, nil
+	connects.Inc("ok")
+	c := &Client{ws: ws, Name: clientName, Version: clientVersion}
+	c.SetLogger(slog.Default())
+	return c, nil
 }
 
 func isCmd(data []byte, cmdType string) bool {
@@ -571,15 +610,28 @@ func DecodeCommand(data []byte) (Command, error) {
 }
 
 func (c *Client) writeMessage(data []byte) error {
-	return c.ws.WriteMessage(websocket.TextMessage, data)
+	c.wmu.Lock()
+	err := c.ws.WriteMessage(websocket.TextMessage, data)
+	c.wmu.Unlock()
+	if err != nil {
+		sendErrors.Inc(cmdType(data), "write")
+		c.log.Warn("send failed", "cmd_type", cmdType(data), "err", err)
+		return err
+	}
+	messagesSent.Inc(cmdType(data))
+	c.noisy.Debug("sent", "cmd_type", cmdType(data), "size", len(data))
+	return nil
 }
 
 func (c *Client) SendMSG(msg *MSG) error {
 	data, err := msg.CmdEncode()
 	if err != nil {
+		sendErrors.Inc("MSG", "encode")
 		return fmt.Errorf("MSG encode failed: %v", err)
 	}
 	if c.chatMax != 0 && len(data) > c.chatMax {
+		sendErrors.Inc("MSG", "too_long")
+		c.log.Warn("message too long", "cmd_type", "MSG", "channel", msg.Channel, "size", len(data), "max", c.chatMax)
 		return ErrMsgTooLong
 	}
 
@@ -592,9 +644,12 @@ func (c *Client) SendMSG(msg *MSG) error {
 func (c *Client) SendPRI(pri *PRI) error {
 	data, err := pri.CmdEncode()
 	if err != nil {
+		sendErrors.Inc("PRI", "encode")
 		return fmt.Errorf("PRI encode failed: %v", err)
 	}
 	if c.privMax != 0 && len(data) > c.privMax {
+		sendErrors.Inc("PRI", "too_long")
+		c.log.Warn("message too long", "cmd_type", "PRI", "player", pri.Recipient, "size", len(data), "max", c.privMax)
 		return ErrMsgTooLong
 	}
 
@@ -611,6 +666,7 @@ func (c *Client) SendORS() error {
 func (c *Client) SendCmd(cmd CmdEncoder) error {
 	data, err := cmd.CmdEncode()
 	if err != nil {
+		sendErrors.Inc(cmd.CmdName(), "encode")
 		return fmt.Errorf("%q encoding: %v", cmd.CmdName(), err)
 	}
 
@@ -630,7 +686,8 @@ func (c *Client) Identify(account, password, character string) error {
 	return c.SendCmd(idn)
 }
 
-func GetTicket(account, password string) (string, error) {
+func GetTicket(account, password string) (_ string, err error) {
+	defer countAPIError("getApiTicket", &err)
 	u := "https://www.f-list.net/json/getApiTicket.php"
 
 	v := url.Values{}
@@ -642,6 +699,7 @@ func GetTicket(account, password string) (string, error) {
 
 	body := strings.NewReader(v.Encode())
 	resp, err := http.Post(u, "application/x-www-form-urlencoded", body)
+	observeAPI("getApiTicket", resp, err)
 	if err != nil {
 		return "", fmt.Errorf("post failed: %v", err)
 	}
@@ -782,7 +840,8 @@ func (it *Infotags) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func GetCharacterData(name, account, ticket string) (*CharacterData, error) {
+func GetCharacterData(name, account, ticket string) (_ *CharacterData, err error) {
+	defer countAPIError("character-data", &err)
 	u := "https://www.f-list.net/json/api/character-data.php"
 
 	v := url.Values{}
@@ -792,6 +851,7 @@ func GetCharacterData(name, account, ticket string) (*CharacterData, error) {
 
 	body := strings.NewReader(v.Encode())
 	resp, err := http.Post(u, "application/x-www-form-urlencoded", body)
+	observeAPI("character-data", resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("post character data failed: %v", err)
 	}
@@ -878,10 +938,12 @@ func (ml MappingList) ListitemsMap() map[string]string {
 	return m
 }
 
-func GetMappingList() (*MappingList, error) {
+func GetMappingList() (_ *MappingList, err error) {
+	defer countAPIError("mapping-list", &err)
 	u := "https://www.f-list.net/json/api/mapping-list.php"
 
 	resp, err := http.Get(u)
+	observeAPI("mapping-list", resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("get mapping list failed: %v", err)
 	}
@@ -893,7 +955,8 @@ func GetMappingList() (*MappingList, error) {
 	return d, nil
 }
 
-func GetAccountCharacters(account, ticket string) ([]string, error) {
+func GetAccountCharacters(account, ticket string) (_ []string, err error) {
+	defer countAPIError("character-list", &err)
 	u := "https://www.f-list.net/json/api/character-list.php"
 
 	v := url.Values{}
@@ -902,6 +965,7 @@ func GetAccountCharacters(account, ticket string) ([]string, error) {
 
 	body := strings.NewReader(v.Encode())
 	resp, err := http.Post(u, "application/x-www-form-urlencoded", body)
+	observeAPI("character-list", resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("post character data failed: %v", err)
 	}
@@ -920,7 +984,7 @@ func GetAccountCharacters(account, ticket string) ([]string, error) {
 	}
 	chars := ac.Characters
 	if ac.Error != "" {
-		return chars, fmt.Errorf(ac.Error)
+		return chars, errors.New(ac.Error)
 	}
 	return chars, nil
 }
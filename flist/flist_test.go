@@ -3,8 +3,15 @@ package flist
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestCharacterData_HasFaveKink(t *testing.T) {
@@ -130,3 +137,66 @@ func TestCharacterData_UnmarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestCmdType(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want string
+	}{
+		{`MSG {"channel":"x"}`, "MSG"},
+		{"PIN", "PIN"},
+		{"", "unknown"},
+		{"ms", "unknown"},
+		{"msg {}", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := cmdType([]byte(tt.in)); got != tt.want {
+			t.Errorf("cmdType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClient_concurrentSends(t *testing.T) {
+	const n = 20
+	received := make(chan string, n)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error("upgrade failed:", err)
+			return
+		}
+		defer ws.Close()
+		for i := 0; i < n; i++ {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				t.Error("reading message failed:", err)
+				return
+			}
+			received <- string(data)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := Connect("ws" + strings.TrimPrefix(ts.URL, "http"))
+	if err != nil {
+		t.Fatal("Connect failed:", err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.SendPRI(&PRI{Recipient: "foo", Message: fmt.Sprint(i)}); err != nil {
+				t.Error("SendPRI failed:", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if msg := <-received; !strings.HasPrefix(msg, "PRI ") {
+			t.Errorf("received %q, want a PRI command", msg)
+		}
+	}
+}
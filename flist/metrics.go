@@ -0,0 +1,48 @@
+package flist
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kusubooru/eribo/metrics"
+)
+
+var (
+	messagesReceived = metrics.NewCounter("flist_messages_received_total", "F-Chat messages received by command type.", "command")
+	messagesSent     = metrics.NewCounter("flist_messages_sent_total", "F-Chat messages sent by command type.", "command")
+	sendErrors       = metrics.NewCounter("flist_send_errors_total", "F-Chat messages that could not be sent by command type and reason: too_long, encode or write.", "command", "reason")
+	connects         = metrics.NewCounter("flist_connects_total", "Connections to the F-Chat server by result: ok or error.", "result")
+	apiRequests      = metrics.NewCounter("flist_api_requests_total", "F-list JSON API requests by endpoint and HTTP status code, or error if there was no response.", "endpoint", "code")
+	apiErrors        = metrics.NewCounter("flist_api_errors_total", "F-list JSON API calls that returned an error by endpoint.", "endpoint")
+)
+
+// cmdType returns the command type of an F-Chat message, which is its first
+// three letters, or "unknown".
+func cmdType(data []byte) string {
+	if len(data) < 3 {
+		return "unknown"
+	}
+	for _, b := range data[:3] {
+		if b < 'A' || b > 'Z' {
+			return "unknown"
+		}
+	}
+	return string(data[:3])
+}
+
+// observeAPI counts a request to an f-list API endpoint.
+func observeAPI(endpoint string, resp *http.Response, err error) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	apiRequests.Inc(endpoint, code)
+}
+
+// countAPIError counts the error of an f-list API call, if any. It is meant
+// to be deferred with the address of the call's error.
+func countAPIError(endpoint string, err *error) {
+	if *err != nil {
+		apiErrors.Inc(endpoint)
+	}
+}
@@ -0,0 +1,88 @@
+// Package health serves health and readiness endpoints made of named checks,
+// each of which reports its own status, so that a supervisor can tell what is
+// wrong and not only that something is.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Statuses of checks and of the whole report.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is a named check. Func returns a detail that describes what it saw and
+// an error if it is unhealthy. It should return when the context is done.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) (detail string, err error)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks of a handler. Its status is ok only
+// if all the checks are.
+type Report struct {
+	Status string    `json:"status"`
+	Checks []*Result `json:"checks"`
+}
+
+// Timeout is how long a handler waits for its checks.
+const Timeout = 5 * time.Second
+
+// Run runs the checks concurrently and returns their report.
+func Run(ctx context.Context, checks ...Check) *Report {
+	rep := &Report{Status: StatusOK, Checks: make([]*Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			res := &Result{Name: c.Name, Status: StatusOK}
+			detail, err := c.Func(ctx)
+			res.Detail = detail
+			if err != nil {
+				res.Status = StatusFail
+				res.Error = err.Error()
+			}
+			rep.Checks[i] = res
+		}(i, c)
+	}
+	wg.Wait()
+	for _, res := range rep.Checks {
+		if res.Status != StatusOK {
+			rep.Status = StatusFail
+		}
+	}
+	return rep
+}
+
+// Handler returns a handler that runs the checks and replies with their
+// report as JSON, with status 200 if all of them are ok and 503 otherwise.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), Timeout)
+		defer cancel()
+		rep := Run(ctx, checks...)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if rep.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+	})
+}
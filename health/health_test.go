@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func ok(detail string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return detail, nil }
+}
+
+func fail(msg string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return "", errors.New(msg) }
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		checks   []Check
+		wantCode int
+		want     *Report
+	}{
+		{
+			[]Check{{"a", ok("fine")}, {"b", ok("")}},
+			http.StatusOK,
+			&Report{StatusOK, []*Result{{"a", StatusOK, "fine", ""}, {"b", StatusOK, "", ""}}},
+		},
+		{
+			[]Check{{"a", ok("fine")}, {"b", fail("broken")}},
+			http.StatusServiceUnavailable,
+			&Report{StatusFail, []*Result{{"a", StatusOK, "fine", ""}, {"b", StatusFail, "", "broken"}}},
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		Handler(tt.checks...).ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != tt.wantCode {
+			t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+		}
+		got := new(Report)
+		if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("report = %+v, want %+v", got, tt.want)
+		}
+	}
+}
+
+func TestRun_timeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := Check{"slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}
+	rep := Run(ctx, slow)
+	if rep.Status != StatusFail || rep.Checks[0].Error != context.Canceled.Error() {
+		t.Errorf("Run with done context = %+v, want failed slow check", rep.Checks[0])
+	}
+}
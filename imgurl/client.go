@@ -0,0 +1,67 @@
+package imgurl
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxRedirects is how many redirects a request of the client returned by
+// NewClient follows.
+const maxRedirects = 3
+
+// NewClient returns an HTTP client for the classifier. Since the URLs are
+// posted by players, it only connects to public addresses, so that they
+// cannot reach the bot's own host or network, and it follows a few redirects
+// at most. It does not use a proxy, which would hide the addresses.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
+		Timeout:       timeout,
+	}
+}
+
+// publicOnly is a dialer control function that refuses to connect to an
+// address that is not public. It runs after the host name is resolved, so a
+// name that resolves to a private address is refused too.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
+
+// isPublic reports whether ip is not a loopback, private, link-local,
+// multicast or unspecified address.
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return nil
+}
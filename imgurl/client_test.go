@@ -0,0 +1,59 @@
+package imgurl
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	var tests = []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublic(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewClient_loopback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request to loopback server reached it: %s %s", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	c := NewClassifier(NewClient(time.Second))
+	if _, _, err := c.head(context.Background(), ts.URL+"/image"); err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Errorf("HEAD of loopback server = %v, want non-public address error", err)
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	req := &http.Request{}
+	via := make([]*http.Request, maxRedirects)
+	if err := checkRedirect(req, via[:maxRedirects-1]); err != nil {
+		t.Errorf("redirect %d returned error: %v", maxRedirects, err)
+	}
+	if err := checkRedirect(req, via); err == nil {
+		t.Errorf("redirect %d succeeded, want error", maxRedirects+1)
+	}
+}
@@ -0,0 +1,261 @@
+// Package imgurl normalizes the URLs posted in channels and tells which of
+// them are images.
+package imgurl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultUserAgent = "Eribo (https://github.com/kusubooru/eribo)"
+
+// extTypes are the content types of direct image links by extension.
+var extTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+}
+
+// imageHosts serve images even when their links have no extension.
+var imageHosts = map[string]bool{
+	"i.imgur.com":          true,
+	"pbs.twimg.com":        true,
+	"cdn.discordapp.com":   true,
+	"media.discordapp.net": true,
+	"i.redd.it":            true,
+	"static.f-list.net":    true,
+	"static1.e621.net":     true,
+	"i.gyazo.com":          true,
+	"i.ibb.co":             true,
+	"files.catbox.moe":     true,
+}
+
+// pageHosts never serve images directly, e.g. videos and F-List profiles.
+var pageHosts = map[string]bool{
+	"youtube.com":   true,
+	"m.youtube.com": true,
+	"youtu.be":      true,
+	"f-list.net":    true,
+	"twitter.com":   true,
+	"x.com":         true,
+	"vimeo.com":     true,
+	"twitch.tv":     true,
+}
+
+// trackingParams are query parameters that do not change what a URL points
+// to and are removed by Normalize.
+var trackingParams = []string{"utm_", "fbclid", "gclid", "ref_src", "igshid", "si"}
+
+// Normalize returns the canonical form of a URL so that reposts of the same
+// link compare equal. The scheme and host are lowercased, "www." and default
+// ports are dropped, as are the fragment and tracking parameters, and the
+// remaining query parameters are sorted.
+func Normalize(rawURL string) (string, error) {
+	u, err := parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if host == "" {
+		return "", fmt.Errorf("url %q has no host", rawURL)
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	q := u.Query()
+	for k := range q {
+		for _, p := range trackingParams {
+			if k == p || strings.HasSuffix(p, "_") && strings.HasPrefix(k, p) {
+				q.Del(k)
+			}
+		}
+	}
+	// Encode sorts by key.
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// parse parses an http or https URL. URLs without a scheme are taken to be
+// http.
+func parse(rawURL string) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		u, err = url.Parse("http://" + rawURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// Info describes a URL that was classified.
+type Info struct {
+	// URL is the URL as it was posted, which is the one to request since
+	// hosts may need the parts that normalizing drops.
+	URL string
+	// NormURL is the normalized URL, which tells reposts of the URL apart.
+	NormURL     string
+	ContentType string
+	// Size is the length of the content in bytes or 0 if it is not known.
+	Size    int64
+	IsImage bool
+}
+
+// Classifier tells which URLs are images.
+type Classifier struct {
+	client *http.Client
+
+	// User agent used for the HEAD requests.
+	UserAgent string
+}
+
+// NewClassifier returns a classifier which makes HEAD requests with the HTTP
+// client to find the content type and size of the URLs. With a nil client no
+// requests are made and URLs are classified by their extension and host only.
+// The client should be one returned by NewClient unless the URLs are trusted.
+func NewClassifier(httpClient *http.Client) *Classifier {
+	return &Classifier{client: httpClient, UserAgent: defaultUserAgent}
+}
+
+// Classify normalizes a URL and tells whether it is an image. Links to known
+// pages such as videos and profiles are never images. Otherwise the URL is
+// guessed to be an image by its extension or host and a HEAD request, if
+// possible, has the final say and provides the size.
+func (c *Classifier) Classify(ctx context.Context, rawURL string) (*Info, error) {
+	orig, err := parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	norm, err := Normalize(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(norm)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{URL: orig.String(), NormURL: norm}
+	if pageHosts[u.Hostname()] {
+		return info, nil
+	}
+	if ct, ok := extTypes[strings.ToLower(path.Ext(u.Path))]; ok {
+		info.ContentType = ct
+		info.IsImage = true
+	} else if imageHosts[u.Hostname()] {
+		info.IsImage = true
+	}
+	if c.client == nil {
+		return info, nil
+	}
+	ct, size, err := c.head(ctx, info.URL)
+	if err != nil {
+		// Keep the guess when the host cannot tell.
+		return info, nil
+	}
+	info.ContentType = ct
+	info.Size = size
+	info.IsImage = strings.HasPrefix(ct, "image/")
+	return info, nil
+}
+
+func (c *Classifier) head(ctx context.Context, u string) (contentType string, size int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", u, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", 0, fmt.Errorf("HEAD %v: %d", u, resp.StatusCode)
+	}
+	contentType = resp.Header.Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i != -1 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return "", 0, fmt.Errorf("HEAD %v: no content type", u)
+	}
+	if resp.ContentLength > 0 {
+		size = resp.ContentLength
+	}
+	return contentType, size, nil
+}
+
+// Images classifies the URLs and returns the images among them, each
+// normalized URL only once, in the order they were given. URLs that cannot be
+// parsed are skipped.
+func (c *Classifier) Images(ctx context.Context, urls []string) []*Info {
+	seen := make(map[string]bool)
+	var images []*Info
+	for _, u := range urls {
+		info, err := c.Classify(ctx, u)
+		if err != nil || !info.IsImage || seen[info.NormURL] {
+			continue
+		}
+		seen[info.NormURL] = true
+		images = append(images, info)
+	}
+	return images
+}
+
+// Types returns the short names of the image content types that are known
+// by extension, e.g. "gif" and "png", sorted.
+func Types() []string {
+	m := make(map[string]bool)
+	for _, ct := range extTypes {
+		m[strings.TrimPrefix(ct, "image/")] = true
+	}
+	types := make([]string, 0, len(m))
+	for t := range m {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ContentType returns the content type that a short type name such as "gif"
+// stands for. Content types such as "image/gif" are returned as they are.
+func ContentType(typ string) string {
+	typ = strings.ToLower(typ)
+	if typ == "" || strings.Contains(typ, "/") {
+		return typ
+	}
+	if typ == "jpg" {
+		typ = "jpeg"
+	}
+	return "image/" + typ
+}
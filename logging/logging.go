@@ -0,0 +1,231 @@
+// Package logging sets up the bot's structured loggers. Each subsystem, such
+// as the F-Chat client or the store, gets its own logger whose level can be
+// changed while the bot runs.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logging makes the loggers of the subsystems and keeps their levels.
+type Logging struct {
+	handler slog.Handler
+
+	mu     sync.Mutex
+	level  slog.Level
+	levels map[string]*slog.LevelVar
+}
+
+// New returns loggers that write to w as text, or as JSON if json is true,
+// and log records at level or above until their level is changed.
+func New(w io.Writer, json bool, level slog.Level) *Logging {
+	// Subsystem levels decide what is logged so the handler logs everything.
+	opts := &slog.HandlerOptions{Level: slog.Level(-1 << 10)}
+	var h slog.Handler = slog.NewTextHandler(w, opts)
+	if json {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return &Logging{handler: h, level: level, levels: make(map[string]*slog.LevelVar)}
+}
+
+// Logger returns the logger of a subsystem. Its records have a subsystem
+// attribute with the name.
+func (l *Logging) Logger(subsystem string) *slog.Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lv, ok := l.levels[subsystem]
+	if !ok {
+		lv = new(slog.LevelVar)
+		lv.Set(l.level)
+		l.levels[subsystem] = lv
+	}
+	return slog.New(&levelHandler{level: lv, handler: l.handler}).With("subsystem", subsystem)
+}
+
+// SetLevels changes levels according to a comma separated list of levels
+// for all subsystems or for one, e.g. "info,flist=debug,store=warn".
+// Subsystems that have no logger yet start at the level for all.
+func (l *Logging) SetLevels(spec string) error {
+	type change struct {
+		subsystem string
+		level     slog.Level
+	}
+	var changes []change
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		subsystem, level := "", s
+		if i := strings.Index(s, "="); i != -1 {
+			subsystem, level = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		}
+		lvl, err := ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change{subsystem, lvl})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range changes {
+		if c.subsystem != "" {
+			if _, ok := l.levels[c.subsystem]; !ok {
+				return fmt.Errorf("unknown subsystem %q, want one of %s", c.subsystem, strings.Join(l.subsystems(), ", "))
+			}
+		}
+	}
+	for _, c := range changes {
+		if c.subsystem == "" {
+			l.level = c.level
+			for _, lv := range l.levels {
+				lv.Set(c.level)
+			}
+			continue
+		}
+		l.levels[c.subsystem].Set(c.level)
+	}
+	return nil
+}
+
+func (l *Logging) subsystems() []string {
+	names := make([]string, 0, len(l.levels))
+	for name := range l.levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the levels of the subsystems, e.g. "flist=DEBUG store=INFO".
+func (l *Logging) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var levels []string
+	for _, name := range l.subsystems() {
+		levels = append(levels, name+"="+l.levels[name].Level().String())
+	}
+	return strings.Join(levels, " ")
+}
+
+// ParseLevel parses debug, info, warn or error in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+	}
+	return l, nil
+}
+
+// levelHandler is a handler that logs the records at its level or above.
+type levelHandler struct {
+	level   *slog.LevelVar
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
+
+// Sample returns a logger for noisy events that logs the first n records of
+// each message in every period and drops the rest. The first record of a
+// message logged after some were dropped has a dropped attribute with their
+// number.
+func Sample(l *slog.Logger, n int, period time.Duration) *slog.Logger {
+	s := &sampler{n: n, period: period, now: time.Now, windows: make(map[string]*window)}
+	return slog.New(&sampleHandler{sampler: s, handler: l.Handler()})
+}
+
+type sampler struct {
+	n      int
+	period time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// window counts the records of a message in the current period.
+type window struct {
+	start   time.Time
+	logged  int
+	dropped int
+}
+
+// allow reports whether a record with the message should be logged and how
+// many records of the message were dropped before it.
+func (s *sampler) allow(msg string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	w, ok := s.windows[msg]
+	if !ok || now.Sub(w.start) >= s.period {
+		dropped := 0
+		if ok {
+			dropped = w.dropped
+		}
+		s.windows[msg] = &window{start: now, logged: 1}
+		return true, dropped
+	}
+	if w.logged < s.n {
+		w.logged++
+		return true, 0
+	}
+	w.dropped++
+	return false, 0
+}
+
+type sampleHandler struct {
+	*sampler
+	handler slog.Handler
+}
+
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	ok, dropped := h.allow(r.Message)
+	if !ok {
+		return nil
+	}
+	if dropped != 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("dropped", dropped))
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{sampler: h.sampler, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{sampler: h.sampler, handler: h.handler.WithGroup(name)}
+}
+
+// Discard returns a logger that logs nothing, for tests and for packages
+// whose user did not set a logger.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(1 << 10)}))
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, false, slog.LevelInfo)
+	fl := l.Logger("flist")
+	st := l.Logger("store")
+
+	fl.Debug("hidden")
+	st.Info("shown", "method", "GetImage")
+	if err := l.SetLevels("warn,flist=debug"); err != nil {
+		t.Fatal(err)
+	}
+	fl.Debug("received", "cmd_type", "MSG")
+	st.Info("hidden")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("records below the level were logged:\n%s", out)
+	}
+	for _, want := range []string{
+		"level=INFO msg=shown subsystem=store method=GetImage",
+		"level=DEBUG msg=received subsystem=flist cmd_type=MSG",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+	if got, want := l.String(), "flist=DEBUG store=WARN"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestSetLevels_errors(t *testing.T) {
+	l := New(&bytes.Buffer{}, false, slog.LevelInfo)
+	l.Logger("flist")
+	for _, spec := range []string{"loud", "web=debug", "flist=loud"} {
+		if err := l.SetLevels(spec); err == nil {
+			t.Errorf("SetLevels(%q) expected error", spec)
+		}
+	}
+	if got, want := l.String(), "flist=INFO"; got != want {
+		t.Errorf("levels after failed changes = %q, want %q", got, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, true, slog.LevelInfo).Logger("bot").Info("command", "command", "!tomato", "channel", "Room")
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("log is not JSON: %v: %s", err, buf.String())
+	}
+	for k, want := range map[string]string{"msg": "command", "subsystem": "bot", "command": "!tomato", "channel": "Room"} {
+		if rec[k] != want {
+			t.Errorf("record %s = %v, want %q", k, rec[k], want)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	var buf bytes.Buffer
+	l := Sample(New(&buf, false, slog.LevelInfo).Logger("flist"), 2, time.Minute)
+	now := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	l.Handler().(*sampleHandler).now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		l.Info("decode error", "i", i)
+	}
+	l.Info("other")
+	now = now.Add(time.Minute)
+	l.Info("decode error", "i", 5)
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		got = append(got, line[strings.Index(line, "msg="):])
+	}
+	want := []string{
+		`msg="decode error" subsystem=flist i=0`,
+		`msg="decode error" subsystem=flist i=1`,
+		`msg=other subsystem=flist`,
+		`msg="decode error" subsystem=flist i=5 dropped=3`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sampled log =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,77 @@
+package loot
+
+import "math/rand"
+
+// aliasTable implements Vose's alias method for sampling from a discrete
+// distribution in O(1) time after O(n) preprocessing.
+//
+// The weights are kept as integers so sampling is exact: item i is selected
+// with probability weight[i] / totalWeight without any floating point error.
+type aliasTable struct {
+	total int
+	prob  []int
+	alias []int
+}
+
+func newAliasTable(weights []int) *aliasTable {
+	n := len(weights)
+	a := &aliasTable{prob: make([]int, n), alias: make([]int, n)}
+	scaled := make([]int, n)
+	for i, w := range weights {
+		if w < 0 {
+			w = 0
+		}
+		a.total += w
+		scaled[i] = w * n
+	}
+	if a.total == 0 {
+		return a
+	}
+
+	small := make([]int, 0, n)
+	large := make([]int, 0, n)
+	for i, s := range scaled {
+		if s < a.total {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) != 0 && len(large) != 0 {
+		l := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		a.prob[l] = scaled[l]
+		a.alias[l] = g
+		scaled[g] = scaled[g] + scaled[l] - a.total
+		if scaled[g] < a.total {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, g := range large {
+		a.prob[g] = a.total
+		a.alias[g] = g
+	}
+	for _, l := range small {
+		a.prob[l] = a.total
+		a.alias[l] = l
+	}
+	return a
+}
+
+// sample returns the index of a randomly selected item or -1 if the total
+// weight is zero.
+func (a *aliasTable) sample(r *rand.Rand) int {
+	if a.total == 0 {
+		return -1
+	}
+	i := r.Intn(len(a.prob))
+	if r.Intn(a.total) < a.prob[i] {
+		return i
+	}
+	return a.alias[i]
+}
@@ -0,0 +1,55 @@
+package loot
+
+import "math/rand"
+
+// The declarations below keep code written against the untyped tables of
+// earlier versions working while it moves to Table[T].
+
+// AnyDrop is a drop of an item of any type.
+//
+// Deprecated: Use Drop with the type of the items.
+type AnyDrop = Drop[interface{}]
+
+// AnyTable is a table of items of any type, like the tables of earlier
+// versions.
+//
+// Deprecated: Use Table with the type of the items.
+type AnyTable = Table[interface{}]
+
+// DefaultTable is an empty table of items of any type.
+//
+// Deprecated: NewTable returns an empty table when it is given no drops.
+var DefaultTable = &AnyTable{}
+
+// RollSeed selects an item like Roll using a source seeded with seed,
+// returning the zero item when the table has no item with positive weight.
+// It is the Roll(seed) of earlier versions.
+//
+// Deprecated: Use NewTableSource or SetSource to seed a table and Roll.
+func (t *Table[T]) RollSeed(seed int64) (int, T) {
+	return t.withSeed(seed, t.roll)
+}
+
+// RollDecreaseWeightSeed is RollDecreaseWeight using a source seeded with
+// seed. It is the RollDecreaseWeight(seed) of earlier versions.
+//
+// Deprecated: Use NewTableSource or SetSource to seed a table and
+// RollDecreaseWeight.
+func (t *Table[T]) RollDecreaseWeightSeed(seed int64) (int, T) {
+	return t.withSeed(seed, t.rollDecreaseWeight)
+}
+
+// withSeed rolls with a source seeded with seed instead of the source of the
+// table.
+func (t *Table[T]) withSeed(seed int64, roll func() (int, T, bool)) (int, T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rnd := t.rnd
+	t.rnd = rand.New(rand.NewSource(seed))
+	defer func() { t.rnd = rnd }()
+	i, item, ok := roll()
+	if !ok {
+		return 0, item
+	}
+	return i, item
+}
@@ -0,0 +1,251 @@
+package loot
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RollContext describes the circumstances of a roll. Conditions of composite
+// table entries inspect it to decide if an entry may drop.
+type RollContext struct {
+	Channel string
+	Player  string
+	Role    string
+	Time    time.Time
+}
+
+// Condition reports whether an entry is allowed to drop for a roll.
+type Condition func(RollContext) bool
+
+// InChannels returns a condition that is true when the roll happens in one of
+// the channels.
+func InChannels(channels ...string) Condition {
+	return func(ctx RollContext) bool {
+		for _, ch := range channels {
+			if ctx.Channel == ch {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithRoles returns a condition that is true when the player that rolls has
+// one of the roles.
+func WithRoles(roles ...string) Condition {
+	return func(ctx RollContext) bool {
+		for _, r := range roles {
+			if ctx.Role == r {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// BetweenHours returns a condition that is true when the hour of the roll is
+// in [from, to). If from is greater than to, the range wraps around midnight,
+// e.g. BetweenHours(22, 4) is true from 22:00 until 03:59.
+func BetweenHours(from, to int) Condition {
+	return func(ctx RollContext) bool {
+		h := ctx.Time.Hour()
+		if from <= to {
+			return from <= h && h < to
+		}
+		return h >= from || h < to
+	}
+}
+
+// Not returns a condition that negates c.
+func Not(c Condition) Condition {
+	return func(ctx RollContext) bool { return !c(ctx) }
+}
+
+// Entry is an entry of a composite loot table. An entry either holds an Item
+// or, when Table is set, a nested table which is rolled when the entry is
+// selected. An entry with a Cond can only drop if the condition is true.
+type Entry[T any] struct {
+	Item   T
+	Table  *Composite[T]
+	Weight int
+	Cond   Condition
+}
+
+// Composite is a loot table whose entries can be items or other composite
+// tables and can be guarded by conditions. Nested tables must not form a
+// cycle. It is safe for concurrent use.
+type Composite[T any] struct {
+	mu      sync.Mutex
+	entries []Entry[T]
+	rnd     *rand.Rand
+}
+
+// NewComposite creates a new composite loot table with the entries.
+func NewComposite[T any](entries ...Entry[T]) *Composite[T] {
+	return &Composite[T]{entries: entries}
+}
+
+// SetSource replaces the random source used by the table. Nested tables use
+// the random source of the table that is being rolled.
+func (c *Composite[T]) SetSource(src rand.Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rnd = rand.New(&lockedSource{src: src})
+}
+
+// Add adds an entry to the table.
+func (c *Composite[T]) Add(e Entry[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, e)
+}
+
+// AddItem adds an item to the table with a specific weight chance.
+func (c *Composite[T]) AddItem(item T, weight int) {
+	c.Add(Entry[T]{Item: item, Weight: weight})
+}
+
+// AddTable adds a nested table to the table with a specific weight chance.
+func (c *Composite[T]) AddTable(table *Composite[T], weight int) {
+	c.Add(Entry[T]{Table: table, Weight: weight})
+}
+
+// Len returns how many entries the table holds.
+func (c *Composite[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Roll selects one item for the roll context. It returns false when no
+// entry is allowed to drop.
+func (c *Composite[T]) Roll(ctx RollContext) (T, bool) {
+	items := c.RollN(ctx, 1)
+	if len(items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return items[0], true
+}
+
+// RollN selects up to n items for the roll context without replacement,
+// meaning that the same item entry never drops twice in a single call. Fewer
+// than n items are returned if the table runs out of eligible items and none
+// if n is not positive.
+func (c *Composite[T]) RollN(ctx RollContext, n int) []T {
+	if n <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	if c.rnd == nil {
+		c.rnd = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+	}
+	// The source is locked, so r can be used after c.mu is released while
+	// other rolls use it too.
+	r := c.rnd
+	c.mu.Unlock()
+
+	items := make([]T, 0, n)
+	picked := make(map[leaf]bool)
+	for len(items) < n {
+		item, l, ok := c.roll(ctx, r, picked)
+		if !ok {
+			break
+		}
+		picked[l] = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// lockedSource is a random source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// leaf identifies an item entry of a composite table.
+type leaf struct {
+	table interface{}
+	index int
+}
+
+func (c *Composite[T]) roll(ctx RollContext, r *rand.Rand, picked map[leaf]bool) (T, leaf, bool) {
+	c.mu.Lock()
+	entries := make([]Entry[T], len(c.entries))
+	copy(entries, c.entries)
+	c.mu.Unlock()
+
+	weights := make([]int, len(entries))
+	total := 0
+	for i, e := range entries {
+		if c.eligible(i, e, ctx, picked) {
+			weights[i] = e.Weight
+			total += e.Weight
+		}
+	}
+	var zero T
+	if total == 0 {
+		return zero, leaf{}, false
+	}
+	n := r.Intn(total)
+	for i, e := range entries {
+		if n >= weights[i] {
+			n -= weights[i]
+			continue
+		}
+		if e.Table != nil {
+			return e.Table.roll(ctx, r, picked)
+		}
+		return e.Item, leaf{c, i}, true
+	}
+	return zero, leaf{}, false
+}
+
+func (c *Composite[T]) eligible(i int, e Entry[T], ctx RollContext, picked map[leaf]bool) bool {
+	if e.Weight <= 0 {
+		return false
+	}
+	if e.Cond != nil && !e.Cond(ctx) {
+		return false
+	}
+	if e.Table != nil {
+		return e.Table.hasEligible(ctx, picked)
+	}
+	return !picked[leaf{c, i}]
+}
+
+func (c *Composite[T]) hasEligible(ctx RollContext, picked map[leaf]bool) bool {
+	c.mu.Lock()
+	entries := make([]Entry[T], len(c.entries))
+	copy(entries, c.entries)
+	c.mu.Unlock()
+	for i, e := range entries {
+		if c.eligible(i, e, ctx, picked) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromTable creates a composite table with the current drops of a table.
+func FromTable[T any](t *Table[T]) *Composite[T] {
+	c := &Composite[T]{}
+	for _, d := range t.Drops() {
+		c.entries = append(c.entries, Entry[T]{Item: d.Item, Weight: d.Weight})
+	}
+	return c
+}
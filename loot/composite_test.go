@@ -0,0 +1,121 @@
+package loot
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComposite_Roll_nested(t *testing.T) {
+	rare := NewComposite(Entry[string]{Item: "rare sword", Weight: 1})
+	common := NewComposite(
+		Entry[string]{Item: "stick", Weight: 1},
+		Entry[string]{Item: "stone", Weight: 1},
+	)
+	table := NewComposite(
+		Entry[string]{Table: rare, Weight: 1},
+		Entry[string]{Table: common, Weight: 3},
+	)
+	table.SetSource(rand.NewSource(1))
+
+	m := make(map[string]int)
+	rolls := 40000
+	for k := 0; k < rolls; k++ {
+		item, ok := table.Roll(RollContext{})
+		if !ok {
+			t.Fatal("Roll returned no item")
+		}
+		m[item]++
+	}
+	want := map[string]float64{"rare sword": 0.25, "stick": 0.375, "stone": 0.375}
+	for item, pr := range want {
+		if got := float64(m[item]) / float64(rolls); got < pr-0.01 || got > pr+0.01 {
+			t.Errorf("%q Pr = %f, want %f", item, got, pr)
+		}
+	}
+}
+
+func TestComposite_RollN(t *testing.T) {
+	quality := NewComposite(
+		Entry[string]{Item: "a", Weight: 1},
+		Entry[string]{Item: "b", Weight: 1},
+	)
+	table := NewComposite(
+		Entry[string]{Table: quality, Weight: 100},
+		Entry[string]{Item: "c", Weight: 1},
+	)
+	table.SetSource(rand.NewSource(1))
+
+	got := table.RollN(RollContext{}, 5)
+	sort.Strings(got)
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RollN(5) = %q, want %q", got, want)
+	}
+	for _, n := range []int{0, -1} {
+		if got := table.RollN(RollContext{}, n); got != nil {
+			t.Errorf("RollN(%d) = %q, want nil", n, got)
+		}
+	}
+}
+
+func TestComposite_RollN_concurrent(t *testing.T) {
+	table := NewComposite(
+		Entry[string]{Item: "a", Weight: 1},
+		Entry[string]{Item: "b", Weight: 1},
+	)
+	table.SetSource(rand.NewSource(1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := table.RollN(RollContext{}, 2); len(got) != 2 {
+					t.Errorf("RollN(2) = %q, want 2 items", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestComposite_Roll_conditions(t *testing.T) {
+	night := time.Date(2018, 1, 1, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	table := NewComposite(
+		Entry[string]{Item: "moon", Weight: 1, Cond: BetweenHours(22, 4)},
+		Entry[string]{Item: "lab", Weight: 1, Cond: InChannels("lab")},
+		Entry[string]{Item: "sub", Weight: 1, Cond: WithRoles("Always submissive")},
+		Entry[string]{Item: "default", Weight: 1, Cond: Not(InChannels("lab"))},
+	)
+	table.SetSource(rand.NewSource(1))
+
+	tests := []struct {
+		ctx  RollContext
+		want []string
+	}{
+		{RollContext{Time: day}, []string{"default"}},
+		{RollContext{Time: night}, []string{"default", "moon"}},
+		{RollContext{Time: day, Channel: "lab"}, []string{"lab"}},
+		{RollContext{Time: night, Channel: "lab", Role: "Always submissive"}, []string{"lab", "moon", "sub"}},
+	}
+	for _, tt := range tests {
+		got := table.RollN(tt.ctx, 10)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RollN(%+v) = %q, want %q", tt.ctx, got, tt.want)
+		}
+	}
+}
+
+func TestComposite_Roll_empty(t *testing.T) {
+	table := NewComposite(Entry[string]{Table: NewComposite[string](), Weight: 1})
+	if item, ok := table.Roll(RollContext{}); ok {
+		t.Errorf("Roll() = %q, want no item", item)
+	}
+}
@@ -7,136 +7,192 @@ import (
 )
 
 // Drop represents an item drop.
-type Drop struct {
-	Item   interface{}
+type Drop[T any] struct {
+	Item   T
 	Weight int
 }
 
-// Table represents a loot table.
-type Table struct {
-	sync.RWMutex
-	drops []Drop
+// Table represents a loot table holding items of type T.
+//
+// The zero value is an empty table that uses a time seeded random source.
+type Table[T any] struct {
+	mu    sync.Mutex
+	drops []Drop[T]
+	rnd   *rand.Rand
+	alias *aliasTable
 }
 
-// DefaultTable is the default loot table that is going to be used when the user
-// specifies no drops for the new table.
-var DefaultTable = &Table{drops: []Drop{}}
-
 // NewTable creates a new loot table based on the user specified drops. If no
-// drops are specified an empty default table is used.
-func NewTable(d []Drop) *Table {
-	if d == nil {
-		return DefaultTable
+// drops are specified an empty table is returned.
+func NewTable[T any](d []Drop[T]) *Table[T] {
+	return &Table[T]{drops: d}
+}
+
+// NewTableSource creates a new loot table that uses src to generate its
+// random rolls. Using the same source and seed produces the same sequence of
+// rolls which makes results reproducible.
+func NewTableSource[T any](d []Drop[T], src rand.Source) *Table[T] {
+	return &Table[T]{drops: d, rnd: rand.New(src)}
+}
+
+// SetSource replaces the random source used by the table.
+func (t *Table[T]) SetSource(src rand.Source) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rnd = rand.New(src)
+}
+
+// random returns the random generator of the table, creating a time seeded
+// one if needed. It must be called with the table lock held.
+func (t *Table[T]) random() *rand.Rand {
+	if t.rnd == nil {
+		t.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
-	return &Table{drops: d}
+	return t.rnd
 }
 
-// Drops returns the item drops of a loot table.
-func (t *Table) Drops() []Drop {
-	t.RLock()
-	defer t.RUnlock()
-	return t.drops
+// Drops returns a copy of the item drops of a loot table.
+func (t *Table[T]) Drops() []Drop[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	drops := make([]Drop[T], len(t.drops))
+	copy(drops, t.drops)
+	return drops
 }
 
 // Add adds an item to the loot table with a specific weight chance.
-func (t *Table) Add(item interface{}, weight int) {
-	t.Lock()
-	defer t.Unlock()
-	d := Drop{Item: item, Weight: weight}
+func (t *Table[T]) Add(item T, weight int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := Drop[T]{Item: item, Weight: weight}
 	t.drops = append(t.drops, d)
+	t.alias = nil
 }
 
 // TotalWeight reports the total weight of the items in the loot table.
-func (t *Table) TotalWeight() int {
-	t.RLock()
-	defer t.RUnlock()
+func (t *Table[T]) TotalWeight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totalWeight()
+}
+
+func (t *Table[T]) totalWeight() int {
 	var totalWeight int
 	for _, d := range t.drops {
-		totalWeight += d.Weight
+		if d.Weight > 0 {
+			totalWeight += d.Weight
+		}
 	}
 	return totalWeight
 }
 
 // Len returns the length of the table i.e. how many items it holds.
-func (t *Table) Len() int {
-	t.RLock()
-	defer t.RUnlock()
-	if t.drops == nil {
-		return 0
-	}
+func (t *Table[T]) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return len(t.drops)
 }
 
-// Roll uses a random seed to randomly select an item from the loot table.
-func (t *Table) Roll(seed int64) (int, interface{}) {
-	t.RLock()
-	defer t.RUnlock()
-	totalWeight := t.TotalWeight()
-	if totalWeight == 0 {
-		return 0, nil
-	}
+// Roll randomly selects an item from the loot table. Each item is selected
+// with probability equal to its weight divided by the total weight of the
+// table. It returns false if the table has no item with positive weight.
+func (t *Table[T]) Roll() (int, T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.roll()
+}
 
-	r := rand.New(rand.NewSource(seed))
-	roll := r.Intn(totalWeight + 1)
+func (t *Table[T]) roll() (int, T, bool) {
+	var zero T
+	if t.alias == nil {
+		t.alias = newAliasTable(t.weights())
+	}
+	i := t.alias.sample(t.random())
+	if i < 0 {
+		return -1, zero, false
+	}
+	return i, t.drops[i].Item, true
+}
 
-	var weight int
-	var drop int
+func (t *Table[T]) weights() []int {
+	w := make([]int, len(t.drops))
 	for i, d := range t.drops {
-		weight += d.Weight
-		if weight >= roll && roll <= weight {
-			drop = i
-			break
-		}
+		w[i] = d.Weight
 	}
-	return drop, t.drops[drop].Item
+	return w
 }
 
 type namer interface {
 	Name() string
 }
 
-// RollDecreaseWeight returns a random item and decreases its weight.
-func (t *Table) RollDecreaseWeight(seed int64) (int, interface{}) {
-	roll, item := t.Roll(seed)
-	// TODO(kusuboorujin): item can be nil. Maybe add a nil check in the future
-	// or just not let item be nil.
-	rolledItem, ok := item.(namer)
+// RollDecreaseWeight returns a random item and decreases its weight. If the
+// items implement Name() string, the weight of every item with the same name
+// is decreased as well.
+func (t *Table[T]) RollDecreaseWeight() (int, T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rollDecreaseWeight()
+}
+
+func (t *Table[T]) rollDecreaseWeight() (int, T, bool) {
+	roll, item, ok := t.roll()
 	if !ok {
-		return roll, item
+		return roll, item, ok
+	}
+	t.decreaseWeight(roll)
+	rolled, isNamer := any(item).(namer)
+	if !isNamer {
+		return roll, item, ok
 	}
-	t.Lock()
-	defer t.Unlock()
-	for i := 0; i < len(t.drops); i++ {
-		if t.drops[i].Item == nil {
+	for i := range t.drops {
+		if i == roll {
 			continue
 		}
-		n, ok := t.drops[i].Item.(namer)
-		if !ok {
+		n, isNamer := any(t.drops[i].Item).(namer)
+		if !isNamer {
 			continue
 		}
-		if n.Name() == rolledItem.Name() {
-			t.drops[i].Weight--
-			if t.drops[i].Weight < 0 {
-				t.drops[i].Weight = 0
-			}
+		if n.Name() == rolled.Name() {
+			t.decreaseWeight(i)
 		}
 	}
-	return roll, item
+	return roll, item, ok
 }
 
-// Sim simulates a number of rolls.
-func (t *Table) Sim(rolls int) (map[int]int, map[int]float64) {
-	t.RLock()
-	defer t.RUnlock()
-	dropsMap := make(map[int]int, t.Len())
+func (t *Table[T]) decreaseWeight(i int) {
+	t.drops[i].Weight--
+	if t.drops[i].Weight < 0 {
+		t.drops[i].Weight = 0
+	}
+	t.alias = nil
+}
+
+// Sim simulates a number of rolls. It returns how many times each item was
+// dropped and the observed probability of each item, both keyed by the
+// index of the item in the table.
+func (t *Table[T]) Sim(rolls int) (map[int]int, map[int]float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dropsMap := make(map[int]int, len(t.drops))
+	if t.alias == nil {
+		t.alias = newAliasTable(t.weights())
+	}
+	r := t.random()
 	for k := 0; k < rolls; k++ {
-		seed := time.Now().UnixNano()
-		i, _ := t.Roll(seed)
+		i := t.alias.sample(r)
+		if i < 0 {
+			break
+		}
 		dropsMap[i]++
 	}
 
-	prMap := make(map[int]float64, t.Len())
+	prMap := make(map[int]float64, len(t.drops))
 	for i := range t.drops {
+		if rolls == 0 {
+			prMap[i] = 0
+			continue
+		}
 		prMap[i] = float64(dropsMap[i]) / float64(rolls)
 	}
 	return dropsMap, prMap
@@ -3,29 +3,29 @@ package loot
 import (
 	"fmt"
 	"math"
+	"math/rand"
 	"testing"
-	"time"
 )
 
 func TestTable_Roll(t *testing.T) {
-	drops := []Drop{
+	drops := []Drop[string]{
 		{Item: "sword", Weight: 100},
 		{Item: "shield", Weight: 400},
 		{Item: "null", Weight: 499},
 		{Item: "legendary", Weight: 1},
 	}
-	table := NewTable(drops)
+	table := NewTableSource(drops, rand.NewSource(1))
 	m := make(map[int]int, len(drops))
-	// TODO(kusubooruji): this used to pass with 10000 rolls. Rework the numbers
-	// and reduce the rolls for faster test.
-	rolls := 1000000
+	rolls := 100000
 	for k := 0; k < rolls; k++ {
-		seed := time.Now().UnixNano()
-		i, _ := table.Roll(seed)
+		i, _, ok := table.Roll()
+		if !ok {
+			t.Fatal("Roll returned no item")
+		}
 		m[i]++
 	}
 
-	delta := 0.015
+	delta := 0.005
 	for i, d := range drops {
 		pr := float64(m[i]) / float64(rolls)
 		expectedPr := float64(d.Weight) / float64(table.TotalWeight())
@@ -36,25 +36,146 @@ func TestTable_Roll(t *testing.T) {
 	}
 }
 
+func TestTable_Roll_reproducible(t *testing.T) {
+	drops := []Drop[string]{
+		{Item: "sword", Weight: 10},
+		{Item: "shield", Weight: 40},
+		{Item: "legendary", Weight: 1},
+	}
+	t1 := NewTableSource(drops, rand.NewSource(42))
+	t2 := NewTableSource(drops, rand.NewSource(42))
+	for k := 0; k < 100; k++ {
+		i1, item1, _ := t1.Roll()
+		i2, item2, _ := t2.Roll()
+		if i1 != i2 || item1 != item2 {
+			t.Fatalf("roll %d: (%d, %q) != (%d, %q)", k, i1, item1, i2, item2)
+		}
+	}
+}
+
+func TestTable_Roll_empty(t *testing.T) {
+	tests := []struct {
+		name  string
+		drops []Drop[string]
+	}{
+		{"nil", nil},
+		{"zero weights", []Drop[string]{{Item: "a"}, {Item: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := NewTable(tt.drops)
+			if i, item, ok := table.Roll(); ok {
+				t.Errorf("Roll() = %d, %q, %v, want no item", i, item, ok)
+			}
+		})
+	}
+}
+
+func TestTable_Roll_zeroWeightNeverDrops(t *testing.T) {
+	drops := []Drop[string]{
+		{Item: "a", Weight: 0},
+		{Item: "b", Weight: 1},
+		{Item: "c", Weight: 0},
+	}
+	table := NewTableSource(drops, rand.NewSource(1))
+	for k := 0; k < 1000; k++ {
+		if _, item, _ := table.Roll(); item != "b" {
+			t.Fatalf("Roll() = %q, want %q", item, "b")
+		}
+	}
+}
+
+func TestAliasTable_exact(t *testing.T) {
+	tests := [][]int{
+		{1},
+		{1, 1},
+		{100, 400, 499, 1},
+		{8, 40, 4},
+		{0, 3, 0, 7, 5},
+		{50, 50, 50, 30, 20, 5, 1},
+	}
+	for _, weights := range tests {
+		a := newAliasTable(weights)
+		n := len(weights)
+		// Every column i is picked with probability 1/n and then keeps i with
+		// probability prob[i]/total or moves to alias[i]. Multiplying by
+		// n*total keeps everything in integers.
+		mass := make([]int, n)
+		for i := 0; i < n; i++ {
+			mass[i] += a.prob[i]
+			mass[a.alias[i]] += a.total - a.prob[i]
+		}
+		for i, w := range weights {
+			if got, want := mass[i], w*n; got != want {
+				t.Errorf("weights %v: item %d mass = %d, want %d", weights, i, got, want)
+			}
+		}
+	}
+}
+
+func TestTable_RollDecreaseWeight(t *testing.T) {
+	table := NewTableSource([]Drop[string]{{Item: "a", Weight: 2}}, rand.NewSource(1))
+	for k := 0; k < 2; k++ {
+		if _, _, ok := table.RollDecreaseWeight(); !ok {
+			t.Fatalf("roll %d: RollDecreaseWeight returned no item", k)
+		}
+	}
+	if _, _, ok := table.RollDecreaseWeight(); ok {
+		t.Errorf("RollDecreaseWeight on exhausted table returned an item")
+	}
+	if got, want := table.TotalWeight(), 0; got != want {
+		t.Errorf("TotalWeight() = %d, want %d", got, want)
+	}
+}
+
+type named string
+
+func (n named) Name() string { return string(n) }
+
+func TestTable_RollDecreaseWeight_sameName(t *testing.T) {
+	table := NewTableSource([]Drop[named]{
+		{Item: "a", Weight: 5},
+		{Item: "a", Weight: 5},
+	}, rand.NewSource(1))
+	table.RollDecreaseWeight()
+	for _, d := range table.Drops() {
+		if got, want := d.Weight, 4; got != want {
+			t.Errorf("%q weight = %d, want %d", d.Item, got, want)
+		}
+	}
+}
+
 func TestQualityDrops(t *testing.T) {
 	t.Skip("not really a test")
 
-	d := []Drop{
+	d := []Drop[string]{
 		{Item: "poor", Weight: 8},
 		{Item: "common", Weight: 40},
 		{Item: "uncommon", Weight: 4},
 	}
 	table := NewTable(d)
-	m := make(map[int]int, len(d))
 	rolls := 1000
-	for k := 0; k < rolls; k++ {
-		seed := time.Now().UnixNano()
-		i, _ := table.Roll(seed)
-		m[i]++
-	}
-
 	drops, pr := table.Sim(rolls)
 	for i := range drops {
 		fmt.Printf("%s = %d, %.1f%%\n", d[i].Item, drops[i], pr[i]*100.0)
 	}
 }
+
+func TestTable_RollSeed(t *testing.T) {
+	table := NewTable([]AnyDrop{{Item: "sword", Weight: 1}, {Item: "shield", Weight: 3}})
+	i, item := table.RollSeed(42)
+	for k := 0; k < 10; k++ {
+		if j, again := table.RollSeed(42); j != i || again != item {
+			t.Fatalf("RollSeed(42) = %d, %v, want %d, %v as before", j, again, i, item)
+		}
+	}
+	if i, item := DefaultTable.RollSeed(42); i != 0 || item != nil {
+		t.Errorf("DefaultTable.RollSeed(42) = %d, %v, want 0, nil", i, item)
+	}
+	if _, item := table.RollDecreaseWeightSeed(1); item == nil {
+		t.Error("RollDecreaseWeightSeed returned no item")
+	}
+	if got, want := table.TotalWeight(), 3; got != want {
+		t.Errorf("TotalWeight after RollDecreaseWeightSeed = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,330 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text exposition format.
+//
+// Metrics are created once, usually as package variables, and registered in
+// the default registry by the package level constructors:
+//
+//	var sent = metrics.NewCounter("messages_sent_total", "Messages sent.", "command")
+//
+//	sent.Inc("MSG")
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, which suit
+// latencies from a few milliseconds to ten seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default is the registry of the package level constructors and Handler.
+var Default = NewRegistry()
+
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[m.name()] {
+		panic("metrics: duplicate metric " + m.name())
+	}
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes the metrics of the registry sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ServeHTTP serves the metrics of the registry.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// Handler returns a handler that serves the metrics of the default registry.
+func Handler() http.Handler { return Default }
+
+// desc is the description of a metric that all its series share.
+type desc struct {
+	metricName string
+	help       string
+	typ        string
+	labels     []string
+}
+
+func (d *desc) name() string { return d.metricName }
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, helpEscaper.Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, d.typ)
+}
+
+// key returns the key of the series with the label values. It panics if the
+// number of values does not match the labels, which is a programming error.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats the label values of a series with extra label pairs,
+// e.g. `{command="MSG",le="0.5"}`.
+func (d *desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) != 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, d.labels[i]+`="`+labelEscaper.Replace(v)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+extra[i+1]+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is a value that only goes up, with one series for each
+// combination of label values.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter returns a counter with the labels registered in r.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name, help, "counter", labels}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// NewCounter returns a counter with the labels registered in the default
+// registry.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// Inc adds one to the series with the label values.
+func (c *Counter) Inc(values ...string) { c.Add(1, values...) }
+
+// Add adds v, which must not be negative, to the series with the label
+// values.
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.metricName + " cannot decrease")
+	}
+	k := c.key(values)
+	c.mu.Lock()
+	c.values[k] += v
+	c.mu.Unlock()
+}
+
+// Value returns the value of the series with the label values.
+func (c *Counter) Value(values ...string) float64 {
+	k := c.key(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[k]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labelPairs(k), formatFloat(c.values[k]))
+	}
+}
+
+// Histogram counts observations in buckets, with one series for each
+// combination of label values.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // counts[i] is the observations <= buckets[i]
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram returns a histogram with the buckets and labels registered in
+// r. With no buckets it uses DefBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{desc: desc{name, help, "histogram", labels}, buckets: buckets, series: make(map[string]*histogramSeries)}
+	r.register(h)
+	return h
+}
+
+// NewHistogram returns a histogram with the buckets and labels registered in
+// the default registry. With no buckets it uses DefBuckets.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// Observe adds an observation to the series with the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	k := h.key(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// ObserveSince observes the seconds passed since start.
+func (h *Histogram) ObserveSince(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+// Count returns the number of observations of the series with the label
+// values.
+func (h *Histogram) Count(values ...string) uint64 {
+	k := h.key(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[k]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(k, "le", formatFloat(b)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labelPairs(k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labelPairs(k), s.count)
+	}
+}
+
+// GaugeFunc is a gauge whose series are collected when the metrics are
+// written, for values that are kept elsewhere.
+type GaugeFunc struct {
+	desc
+	collect func(set func(v float64, values ...string))
+}
+
+// NewGaugeFunc returns a gauge with the labels registered in r. When the
+// metrics are written, collect calls set for each series of the gauge.
+func (r *Registry) NewGaugeFunc(name, help string, collect func(set func(v float64, values ...string)), labels ...string) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name, help, "gauge", labels}, collect: collect}
+	r.register(g)
+	return g
+}
+
+// NewGaugeFunc returns a gauge with the labels registered in the default
+// registry. When the metrics are written, collect calls set for each series
+// of the gauge.
+func NewGaugeFunc(name, help string, collect func(set func(v float64, values ...string)), labels ...string) *GaugeFunc {
+	return Default.NewGaugeFunc(name, help, collect, labels...)
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	values := make(map[string]float64)
+	g.collect(func(v float64, labelValues ...string) {
+		values[g.key(labelValues)] = v
+	})
+	for _, k := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, g.labelPairs(k), formatFloat(values[k]))
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("sent_total", "Messages sent.\nBy command.", "command")
+	c.Inc("MSG")
+	c.Add(2, "MSG")
+	c.Inc(`PR"I`)
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "op")
+	h.Observe(0.05, "get")
+	h.Observe(0.5, "get")
+	h.Observe(5, "get")
+	r.NewGaugeFunc("players", "Players.", func(set func(float64, ...string)) {
+		set(3, "Room")
+		set(1, "Other")
+	}, "channel")
+	r.NewCounter("plain_total", "No labels.").Inc()
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 1
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 5.55
+latency_seconds_count{op="get"} 3
+# HELP plain_total No labels.
+# TYPE plain_total counter
+plain_total 1
+# HELP players Players.
+# TYPE players gauge
+players{channel="Other"} 1
+players{channel="Room"} 3
+# HELP sent_total Messages sent.\nBy command.
+# TYPE sent_total counter
+sent_total{command="MSG"} 3
+sent_total{command="PR\"I"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteTo =\n%s\nwant\n%s", got, want)
+	}
+	if got := c.Value("MSG"); got != 3 {
+		t.Errorf("Value(MSG) = %v, want 3", got)
+	}
+	if got := h.Count("get"); got != 3 {
+		t.Errorf("Count(get) = %v, want 3", got)
+	}
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("up_total", "Up.").Inc()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want text exposition format", ct)
+	}
+	if !strings.Contains(w.Body.String(), "up_total 1\n") {
+		t.Errorf("body = %q, want up_total 1", w.Body)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	var tests = []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"duplicate", func(r *Registry) {
+			r.NewCounter("a_total", "")
+			r.NewCounter("a_total", "")
+		}},
+		{"label values", func(r *Registry) { r.NewCounter("a_total", "", "x").Inc() }},
+		{"decrease", func(r *Registry) { r.NewCounter("a_total", "").Add(-1) }},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", tt.name)
+				}
+			}()
+			tt.fn(NewRegistry())
+		}()
+	}
+}
@@ -0,0 +1,342 @@
+package rp
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// The default content is compiled into the binary. A content directory with
+// files of the same names can replace any of them at runtime.
+//
+//go:embed content/*.json
+var defaultContentFS embed.FS
+
+// Content files.
+const (
+	TietoolsFile     = "tietools.json"
+	TietoolsHardFile = "tietools_hard.json"
+	TktoolsFile      = "tktools.json"
+	StandsFile       = "stands.json"
+	MuffinsFile      = "muffins.json"
+	VonprovesFile    = "vonproves.json"
+	TieUpsFile       = "tieups.json"
+	GroupTieUpsFile  = "group_tieups.json"
+	BodyPartsFile    = "bodyparts.json"
+	AnimeOpsFile     = "animeops.json"
+)
+
+// Content holds all the items, emotes and messages that the rp commands use.
+type Content struct {
+	Tietools     []Tietool
+	TietoolsHard []Tietool
+	Tktools      []Tktool
+	stands       []stand
+	muffins      []muffin
+	vonproves    []Vonprove
+	tieUps       []tieUp
+	groupTieUps  []tieUp
+	bodyParts    []bodyPart
+	animeOps     []animeOp
+}
+
+var (
+	contentMu      sync.RWMutex
+	currentContent *Content
+)
+
+func init() {
+	c, err := DefaultContent()
+	if err != nil {
+		panic(fmt.Sprintf("rp: loading default content: %v", err))
+	}
+	currentContent = c
+}
+
+func content() *Content {
+	contentMu.RLock()
+	defer contentMu.RUnlock()
+	return currentContent
+}
+
+// SetContent replaces the content used by the rp commands. Loot tables that
+// were created with the previous content keep using it until they are reset.
+func SetContent(c *Content) {
+	contentMu.Lock()
+	defer contentMu.Unlock()
+	currentContent = c
+}
+
+// DefaultContent loads the content that is compiled into the binary.
+func DefaultContent() (*Content, error) {
+	sub, err := fs.Sub(defaultContentFS, "content")
+	if err != nil {
+		return nil, err
+	}
+	return LoadContent(sub)
+}
+
+// LoadContentDir loads the content files found in dir. Files that do not
+// exist in dir are loaded from the default content.
+func LoadContentDir(dir string) (*Content, error) {
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("content: %s is not a directory", dir)
+	}
+	def, err := fs.Sub(defaultContentFS, "content")
+	if err != nil {
+		return nil, err
+	}
+	return LoadContent(overlayFS{os.DirFS(dir), def})
+}
+
+// overlayFS opens files from top and falls back to bottom if they do not
+// exist.
+type overlayFS struct {
+	top, bottom fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.bottom.Open(name)
+	}
+	return f, err
+}
+
+// ContentError holds all the problems that were found while loading content.
+type ContentError struct {
+	Problems []string
+}
+
+func (e *ContentError) Error() string {
+	return fmt.Sprintf("content has %d problem(s):\n%s", len(e.Problems), strings.Join(e.Problems, "\n"))
+}
+
+type contentLoader struct {
+	fsys     fs.FS
+	problems []string
+}
+
+func (l *contentLoader) problemf(file string, i int, format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf("%s: item %d: %s", file, i, fmt.Sprintf(format, args...)))
+}
+
+func (l *contentLoader) decode(file string, v interface{}) bool {
+	f, err := l.fsys.Open(file)
+	if err != nil {
+		l.problems = append(l.problems, err.Error())
+		return false
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: %v", file, err))
+		return false
+	}
+	if reflect.ValueOf(v).Elem().Len() == 0 {
+		l.problems = append(l.problems, fmt.Sprintf("%s: no items", file))
+		return false
+	}
+	return true
+}
+
+// LoadContent loads and validates all the content files from fsys. Every
+// template is parsed and executed with sample data so that mistakes are
+// reported at load time instead of when a command is used.
+func LoadContent(fsys fs.FS) (*Content, error) {
+	l := &contentLoader{fsys: fsys}
+	c := &Content{
+		Tietools:     l.tietools(TietoolsFile),
+		TietoolsHard: l.tietools(TietoolsHardFile),
+		Tktools:      l.tktools(TktoolsFile),
+		stands:       l.stands(StandsFile),
+		muffins:      l.muffins(MuffinsFile),
+		vonproves:    l.vonproves(VonprovesFile),
+		tieUps:       l.tieUps(TieUpsFile),
+		groupTieUps:  l.tieUps(GroupTieUpsFile),
+		bodyParts:    l.bodyParts(BodyPartsFile),
+		animeOps:     l.animeOps(AnimeOpsFile),
+	}
+	if len(l.problems) != 0 {
+		return nil, &ContentError{Problems: l.problems}
+	}
+	return c, nil
+}
+
+func (l *contentLoader) template(file string, i int, s string, data interface{}) *template.Template {
+	tmpl, err := newTemplate(file).Parse(s)
+	if err != nil {
+		l.problemf(file, i, "%v", err)
+		return nil
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, data); err != nil {
+		l.problemf(file, i, "%v", err)
+		return nil
+	}
+	return tmpl
+}
+
+func (l *contentLoader) quality(file string, i int, s string) Quality {
+	q := makeQuality(s)
+	if q == Unknown {
+		l.problemf(file, i, "unknown quality %q", s)
+	}
+	return q
+}
+
+func (l *contentLoader) nonEmpty(file string, i int, field, s string) {
+	if strings.TrimSpace(s) == "" {
+		l.problemf(file, i, "%s is empty", field)
+	}
+}
+
+func (l *contentLoader) tietools(file string) []Tietool {
+	var items []struct {
+		Name    string `json:"name"`
+		Quality string `json:"quality"`
+		Desc    string `json:"desc"`
+	}
+	if !l.decode(file, &items) {
+		return nil
+	}
+	tools := make([]Tietool, 0, len(items))
+	for i, it := range items {
+		l.nonEmpty(file, i, "name", it.Name)
+		tools = append(tools, Tietool{
+			name:    it.Name,
+			Quality: l.quality(file, i, it.Quality),
+			Desc:    l.template(file, i, it.Desc, tietoolData{Data: sampleData("User")}),
+		})
+	}
+	return tools
+}
+
+func (l *contentLoader) tktools(file string) []Tktool {
+	var items []struct {
+		Name    string   `json:"name"`
+		Quality string   `json:"quality"`
+		Colors  []string `json:"colors"`
+		Weight  int      `json:"weight"`
+		Emote   string   `json:"emote"`
+	}
+	if !l.decode(file, &items) {
+		return nil
+	}
+	tools := make([]Tktool, 0, len(items))
+	for i, it := range items {
+		l.nonEmpty(file, i, "name", it.Name)
+		if it.Weight < 0 {
+			l.problemf(file, i, "negative weight %d", it.Weight)
+		}
+		var colors []Color
+		for _, s := range it.Colors {
+			c := makeColor(s)
+			if c == Colorless {
+				l.problemf(file, i, "unknown color %q", s)
+			}
+			colors = append(colors, c)
+		}
+		tools = append(tools, Tktool{
+			name:    it.Name,
+			Colors:  colors,
+			Quality: l.quality(file, i, it.Quality),
+			Emote:   l.template(file, i, it.Emote, tktoolData{Data: sampleData("User")}),
+			weight:  it.Weight,
+		})
+	}
+	return tools
+}
+
+func (l *contentLoader) stands(file string) []stand {
+	var items []stand
+	if !l.decode(file, &items) {
+		return nil
+	}
+	for i, it := range items {
+		l.nonEmpty(file, i, "name", it.Name)
+		l.nonEmpty(file, i, "type", it.Type)
+		l.nonEmpty(file, i, "desc", it.Desc)
+	}
+	return items
+}
+
+func (l *contentLoader) muffins(file string) []muffin {
+	var items []muffin
+	if !l.decode(file, &items) {
+		return nil
+	}
+	for i, it := range items {
+		l.nonEmpty(file, i, "name", it.Name)
+		l.nonEmpty(file, i, "url", it.URL)
+	}
+	return items
+}
+
+func (l *contentLoader) vonproves(file string) []Vonprove {
+	var items []Vonprove
+	if !l.decode(file, &items) {
+		return nil
+	}
+	for i, it := range items {
+		l.nonEmpty(file, i, "raw", it.Raw)
+		items[i].msg = l.template(file, i, it.Raw, vonproveData{Data: sampleData("User")})
+	}
+	return items
+}
+
+func (l *contentLoader) tieUps(file string) []tieUp {
+	var items []struct {
+		Tags []string `json:"tags"`
+		Msg  string   `json:"msg"`
+	}
+	if !l.decode(file, &items) {
+		return nil
+	}
+	ties := make([]tieUp, 0, len(items))
+	for i, it := range items {
+		tmpl := l.template(file, i, it.Msg, sampleData("User"))
+		if tmpl != nil && !templateFields(tmpl.Root)["User"] {
+			l.problemf(file, i, "missing {{.User}}")
+		}
+		ties = append(ties, tieUp{tags: it.Tags, msg: tmpl})
+	}
+	return ties
+}
+
+func (l *contentLoader) bodyParts(file string) []bodyPart {
+	var items []struct {
+		Name   string `json:"name"`
+		Plural bool   `json:"plural"`
+	}
+	if !l.decode(file, &items) {
+		return nil
+	}
+	parts := make([]bodyPart, 0, len(items))
+	for i, it := range items {
+		l.nonEmpty(file, i, "name", it.Name)
+		parts = append(parts, bodyPart{Name: it.Name, Plural: it.Plural})
+	}
+	return parts
+}
+
+func (l *contentLoader) animeOps(file string) []animeOp {
+	var items []animeOp
+	if !l.decode(file, &items) {
+		return nil
+	}
+	for i, it := range items {
+		l.nonEmpty(file, i, "raw", it.Raw)
+	}
+	return items
+}
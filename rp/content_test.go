@@ -0,0 +1,71 @@
+package rp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultContent(t *testing.T) {
+	c, err := DefaultContent()
+	if err != nil {
+		t.Fatalf("DefaultContent returned error: %v", err)
+	}
+	if len(c.Tietools) == 0 || len(c.TietoolsHard) == 0 || len(c.Tktools) == 0 {
+		t.Errorf("DefaultContent has no tools")
+	}
+}
+
+func TestLoadContentDir(t *testing.T) {
+	dir := t.TempDir()
+	muffins := `[{"name": "Test Muffin", "url": "https://example.com/muffin.jpg"}]`
+	if err := os.WriteFile(filepath.Join(dir, MuffinsFile), []byte(muffins), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadContentDir(dir)
+	if err != nil {
+		t.Fatalf("LoadContentDir returned error: %v", err)
+	}
+	if got, want := len(c.muffins), 1; got != want {
+		t.Fatalf("loaded %d muffins, want %d", got, want)
+	}
+	if got, want := len(c.Tietools), len(content().Tietools); got != want {
+		t.Errorf("loaded %d default tietools, want %d", got, want)
+	}
+}
+
+func TestLoadContentDir_invalid(t *testing.T) {
+	var tests = []struct {
+		file    string
+		data    string
+		problem string
+	}{
+		{TietoolsFile, `[{"name": "x", "quality": "common", "desc": "/me {{.User}"}]`, "tietools.json: item 0"},
+		{TietoolsFile, `[{"name": "x", "quality": "common", "desc": "/me {{.Usr}}"}]`, "can't evaluate field Usr"},
+		{TietoolsFile, `[{"name": "x", "quality": "shiny", "desc": "/me {{.User}}"}]`, `unknown quality "shiny"`},
+		{TktoolsFile, `[{"name": "x", "quality": "rare", "colors": ["mauve"], "emote": "/me"}]`, `unknown color "mauve"`},
+		{TieUpsFile, `[{"tags": ["feet"], "msg": "/me ties up"}]`, "missing {{.User}}"},
+		{TieUpsFile, `[{"tags": ["feet"], "msg": "/me ties up {{.User}} by {{.Her}} feet"}]`, "can't evaluate field Her"},
+		{VonprovesFile, `[{"raw": "/me %v", "has_user": true}]`, `unknown field "has_user"`},
+		{VonprovesFile, `[{"raw": "/me approves {{.Usr}}"}]`, "can't evaluate field Usr"},
+		{MuffinsFile, `[{"name": "x", "link": "y"}]`, `unknown field "link"`},
+		{StandsFile, `[]`, "stands.json: no items"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadContentDir(dir)
+		var cerr *ContentError
+		if !errors.As(err, &cerr) {
+			t.Errorf("LoadContentDir with %s %s returned %v, want ContentError", tt.file, tt.data, err)
+			continue
+		}
+		if !strings.Contains(cerr.Error(), tt.problem) {
+			t.Errorf("LoadContentDir with %s %s error = %v, want it to contain %q", tt.file, tt.data, cerr, tt.problem)
+		}
+	}
+}
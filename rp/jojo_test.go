@@ -16,7 +16,7 @@ func TestStand_apply(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		got := tt.stand.apply(tt.user)
+		got := tt.stand.apply(sampleData(tt.user))
 		want := tt.out
 		if got != want {
 			t.Errorf("stand apply on user %q = \nhave: %q\nwant: %q", tt.user, got, want)
@@ -0,0 +1,296 @@
+package rp
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+// bbcodeColors are the colors that the F-Chat [color] tag accepts.
+var bbcodeColors = []string{
+	"red", "blue", "white", "yellow", "pink", "gray",
+	"green", "orange", "purple", "black", "brown", "cyan",
+}
+
+// bbcodeTags are the F-Chat BBCode tags that need a closing tag.
+var bbcodeTags = []string{
+	"b", "i", "u", "s", "sup", "sub", "color", "url", "user", "icon",
+	"eicon", "noparse", "big", "small", "spoiler",
+}
+
+// CheckBBCode reports problems with the BBCode of a message such as tags
+// that are not closed, closed in the wrong order or colors that F-Chat does
+// not support.
+func CheckBBCode(s string) []string {
+	var problems []string
+	var stack []string
+	rest := s
+	for {
+		i := strings.Index(rest, "[")
+		if i == -1 {
+			break
+		}
+		rest = rest[i+1:]
+		j := strings.Index(rest, "]")
+		if j == -1 {
+			problems = append(problems, "unclosed bracket [")
+			break
+		}
+		tag := rest[:j]
+		rest = rest[j+1:]
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToLower(tag[1:])
+			if !isBBCodeTag(name) {
+				continue
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				problems = append(problems, fmt.Sprintf("unexpected [/%s]", name))
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		name, value := tag, ""
+		if k := strings.Index(tag, "="); k != -1 {
+			name, value = tag[:k], tag[k+1:]
+		}
+		name = strings.ToLower(name)
+		if !isBBCodeTag(name) {
+			continue
+		}
+		if name == "color" && !isBBCodeColor(value) {
+			problems = append(problems, fmt.Sprintf("unsupported color %q", value))
+		}
+		stack = append(stack, name)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		problems = append(problems, fmt.Sprintf("unclosed [%s]", stack[i]))
+	}
+	return problems
+}
+
+func isBBCodeTag(name string) bool {
+	for _, t := range bbcodeTags {
+		if name == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isBBCodeColor(c string) bool {
+	for _, bc := range bbcodeColors {
+		if c == bc {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFields returns the names of the fields a template uses, e.g.
+// "User" for {{.User}}.
+func templateFields(node parse.Node) map[string]bool {
+	fields := make(map[string]bool)
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) != 0 {
+				fields[n.Ident[0]] = true
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+	return fields
+}
+
+type linter struct {
+	problems []string
+}
+
+func (l *linter) problemf(file string, i int, format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf("%s: item %d: %s", file, i, fmt.Sprintf(format, args...)))
+}
+
+func (l *linter) message(file string, i int, s string) {
+	if !strings.HasPrefix(s, "/me ") {
+		l.problemf(file, i, "expected '/me ' prefix")
+	}
+	for _, p := range CheckBBCode(s) {
+		l.problemf(file, i, "%s", p)
+	}
+	for _, verb := range []string{"%s", "%v"} {
+		if strings.Contains(s, verb) {
+			l.problemf(file, i, "found %s, use template fields such as {{.User}} instead", verb)
+		}
+	}
+}
+
+func (l *linter) fields(file string, i int, root parse.Node, want ...string) {
+	fields := templateFields(root)
+	for _, f := range want {
+		if !fields[f] {
+			l.problemf(file, i, "missing {{.%s}}", f)
+		}
+	}
+}
+
+// Lint checks the content for mistakes that the loader does not catch such
+// as messages that do not start with /me, unbalanced BBCode or templates
+// that never mention the user. Messages are checked after being rendered
+// with sample data so that the BBCode the tools add is checked as well.
+func (c *Content) Lint() []string {
+	l := &linter{}
+	user := sampleData("John Doe")
+	for _, tools := range []struct {
+		file  string
+		tools []Tietool
+	}{{TietoolsFile, c.Tietools}, {TietoolsHardFile, c.TietoolsHard}} {
+		for i, t := range tools.tools {
+			l.fields(tools.file, i, t.Desc.Root, "User", "Tool")
+			s, err := t.Apply(user)
+			if err != nil {
+				l.problemf(tools.file, i, "%v", err)
+				continue
+			}
+			l.message(tools.file, i, s)
+		}
+	}
+	for i, t := range c.Tktools {
+		l.fields(TktoolsFile, i, t.Emote.Root, "User", "Tool")
+		if templateFields(t.Emote.Root)["Color"] && len(t.Colors) == 0 {
+			l.problemf(TktoolsFile, i, "uses {{.Color}} but has no colors")
+		}
+		s, err := t.Apply(user)
+		if err != nil {
+			l.problemf(TktoolsFile, i, "%v", err)
+			continue
+		}
+		l.message(TktoolsFile, i, s)
+	}
+	group := user.WithTargets([]*eribo.Player{{Name: "John Doe"}, {Name: "Jane Doe"}})
+	for _, ties := range []struct {
+		file string
+		ties []tieUp
+		data Data
+	}{{TieUpsFile, c.tieUps, user}, {GroupTieUpsFile, c.groupTieUps, group}} {
+		for i, t := range ties.ties {
+			if len(t.tags) == 0 {
+				l.problemf(ties.file, i, "no tags")
+			}
+			l.message(ties.file, i, t.apply(ties.data))
+		}
+	}
+	for i, v := range c.vonproves {
+		l.message(VonprovesFile, i, v.Apply(user))
+	}
+	for i, p := range c.bodyParts {
+		if strings.ContainsAny(p.Name, " \t") {
+			l.problemf(BodyPartsFile, i, "body part %q must be a single word to be used as a filter", p.Name)
+		}
+	}
+	for i, st := range c.stands {
+		for _, p := range CheckBBCode(st.apply(user)) {
+			l.problemf(StandsFile, i, "%s", p)
+		}
+	}
+	for i, op := range c.animeOps {
+		l.message(AnimeOpsFile, i, op.Apply())
+	}
+	return l.problems
+}
+
+// PreviewItem is a content item rendered with sample data.
+type PreviewItem struct {
+	File    string
+	Item    int
+	Name    string
+	Message string
+}
+
+// Preview renders every item of the content with the data, so that content
+// can be reviewed the way it is going to appear in chat.
+func (c *Content) Preview(d Data) []PreviewItem {
+	var items []PreviewItem
+	add := func(file string, i int, name, msg string) {
+		items = append(items, PreviewItem{File: file, Item: i, Name: name, Message: msg})
+	}
+	for _, tools := range []struct {
+		file  string
+		tools []Tietool
+	}{{TietoolsFile, c.Tietools}, {TietoolsHardFile, c.TietoolsHard}} {
+		for i, t := range tools.tools {
+			s, err := t.Apply(d)
+			if err != nil {
+				s = err.Error()
+			}
+			add(tools.file, i, t.Name(), s)
+		}
+	}
+	for i, t := range c.Tktools {
+		s, err := t.Apply(d)
+		if err != nil {
+			s = err.Error()
+		}
+		add(TktoolsFile, i, t.Name(), s)
+	}
+	for i, t := range c.tieUps {
+		add(TieUpsFile, i, strings.Join(t.tags, ","), t.apply(d))
+	}
+	group := d.WithTargets([]*eribo.Player{{Name: d.Target.Name, Gender: d.Target.Gender}, {Name: "Sam Doe"}})
+	for i, t := range c.groupTieUps {
+		add(GroupTieUpsFile, i, strings.Join(t.tags, ","), t.apply(group))
+	}
+	for i, p := range c.bodyParts {
+		for _, intensity := range ticklizerIntensities {
+			add(BodyPartsFile, i, fmt.Sprintf("%s x%d", p.Name, intensity), ticklizerMsg(d, normal, p, intensity, false))
+		}
+	}
+	for i, v := range c.vonproves {
+		add(VonprovesFile, i, "", v.Apply(d))
+	}
+	for i, st := range c.stands {
+		add(StandsFile, i, st.Name, st.apply(d))
+	}
+	for i, m := range c.muffins {
+		add(MuffinsFile, i, m.Name, m.apply(d))
+	}
+	for i, op := range c.animeOps {
+		add(AnimeOpsFile, i, "", op.Apply())
+	}
+	return items
+}
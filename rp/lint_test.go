@@ -0,0 +1,93 @@
+package rp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBBCode(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want []string
+	}{
+		{"/me [b]hi[/b]", nil},
+		{"/me [color=red][b]hi[/b][/color]", nil},
+		{"/me [url=https://example.com]x[/url] [Blindfold]", nil},
+		{"/me [b]hi", []string{"unclosed [b]"}},
+		{"/me hi[/i]", []string{"unexpected [/i]"}},
+		{"/me [b][i]hi[/b][/i]", []string{"unexpected [/b]", "unclosed [b]"}},
+		{"/me [color=mauve]hi[/color]", []string{`unsupported color "mauve"`}},
+		{"/me [b hi", []string{"unclosed bracket ["}},
+	}
+	for _, tt := range tests {
+		got := CheckBBCode(tt.in)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("CheckBBCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLint_defaultContent(t *testing.T) {
+	c, err := DefaultContent()
+	if err != nil {
+		t.Fatalf("DefaultContent returned error: %v", err)
+	}
+	if problems := c.Lint(); len(problems) != 0 {
+		t.Errorf("default content has lint problems:\n%s", strings.Join(problems, "\n"))
+	}
+}
+
+func TestLint(t *testing.T) {
+	var tests = []struct {
+		file    string
+		data    string
+		problem string
+	}{
+		{TietoolsFile, `[{"name": "x", "quality": "common", "desc": "/me makes a {{.Tool}}."}]`, "tietools.json: item 0: missing {{.User}}"},
+		{TietoolsFile, `[{"name": "x", "quality": "common", "desc": "makes {{.User}} a {{.Tool}}."}]`, "expected '/me ' prefix"},
+		{TktoolsFile, `[{"name": "x", "quality": "rare", "emote": "/me [b]{{.User}} {{.Tool}}"}]`, "unclosed [b]"},
+		{TktoolsFile, `[{"name": "x", "quality": "rare", "emote": "/me {{.User}} {{.Tool}} {{.Color}}"}]`, "uses {{.Color}} but has no colors"},
+		{TieUpsFile, `[{"tags": ["feet"], "msg": "/me ties up {{.User}} [color=mauve]hard[/color]"}]`, `unsupported color "mauve"`},
+		{TieUpsFile, `[{"msg": "/me ties up {{.User}}"}]`, "tieups.json: item 0: no tags"},
+		{BodyPartsFile, `[{"name": "left foot"}]`, "must be a single word"},
+		{TieUpsFile, `[{"tags": ["feet"], "msg": "/me ties up {{.User}} and %s"}]`, "found %s, use template fields"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := LoadContentDir(dir)
+		if err != nil {
+			t.Fatalf("LoadContentDir(%s) returned error: %v", tt.data, err)
+		}
+		problems := c.Lint()
+		if !strings.Contains(strings.Join(problems, "\n"), tt.problem) {
+			t.Errorf("Lint(%s) = %q, want problem %q", tt.data, problems, tt.problem)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	c, err := DefaultContent()
+	if err != nil {
+		t.Fatalf("DefaultContent returned error: %v", err)
+	}
+	items := c.Preview(sampleData("Jane Doe"))
+	parts := 0
+	for _, it := range items {
+		if it.File == BodyPartsFile {
+			parts++
+		}
+	}
+	if got, want := parts, len(c.bodyParts)*len(ticklizerIntensities); got != want {
+		t.Errorf("Preview rendered %d ticklizer messages, want %d", got, want)
+	}
+	for _, it := range items {
+		if it.File == TietoolsFile && !strings.Contains(it.Message, "Jane Doe") {
+			t.Errorf("Preview %s item %d does not mention user: %q", it.File, it.Item, it.Message)
+		}
+	}
+}
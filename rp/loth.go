@@ -22,6 +22,26 @@ func LothWarning() string {
 	return "By using this command, you agree that you intend to play with the randomly chosen victim (assuming they are not afk). To continue, type: !loth confirm"
 }
 
+var (
+	lothOnlyTarget = tmplMust(`/me looks around the room while performing
+	calculations and seeking potentials targets. After a few seconds it stops
+	and stares at what seems to be the only eligible target. It grabs
+	{{.User}} and injects {{.Them}} with a powerful serum which numbs
+	{{.Their}} strength and reflexes but sharply increases {{.Their}}
+	sensitivity. It leaves the victim half incapacitated on the floor then
+	proceeds to announce to the whole room: "New 'lee of the hour is
+	{{.User}}!"`)
+	lothMalfunction = tmplMust(`/me appears to be malfunctioning as it
+	doesn't seem to be seeking for other targets and turns towards the person
+	that issued the command. It grabs {{.User}} and injects {{.Them}} with the
+	serum instead!`)
+	lothNew = tmplMust(`/me grabs {{.User}} and injects {{.Them}} with a
+	powerful serum which numbs {{.Their}} strength and reflexes but sharply
+	increases {{.Their}} sensitivity. It leaves the victim half incapacitated
+	on the floor then proceeds to announce to the whole room: "New 'lee of the
+	hour is {{.User}}!"`)
+)
+
 // Loth returns a different message depending on the different states of loth.
 func Loth(user string, loth *eribo.Loth, isNew bool, targets []*eribo.Player) string {
 	switch {
@@ -34,28 +54,11 @@ func Loth(user string, loth *eribo.Loth, isNew bool, targets []*eribo.Player) st
 
 		return fmt.Sprintf(clean(msg), loth.Name, loth.TimeLeft())
 	case loth != nil && isNew && user == loth.Name && len(targets) == 1:
-		msg := `/me looks around the room while performing calculations and
-		seeking potentials targets. After a few seconds it stops and stares at
-		what seems to be the only eligible target. It grabs %s and injects them
-		with a powerful serum which numbs their strength and reflexes but
-		sharply increases their sensitivity. It leaves the victim half
-		incapacitated on the floor then proceeds to announce to the whole room:
-		"New 'lee of the hour is %s!"`
-
-		return fmt.Sprintf(clean(msg), loth.Name, loth.Name)
+		return render(lothOnlyTarget, Data{}.WithTarget(loth.Player))
 	case loth != nil && isNew && user == loth.Name && len(targets) != 1:
-		msg := `/me appears to be malfunctioning as it doesn't seem to be
-		seeking for other targets and turns towards the person that issued the
-		command. It grabs %s and injects them with the serum instead!`
-
-		return fmt.Sprintf(clean(msg), loth.Name)
+		return render(lothMalfunction, Data{}.WithTarget(loth.Player))
 	case loth != nil && isNew && user != loth.Name:
-		msg := `/me grabs %s and injects them with a powerful serum which numbs
-		their strength and reflexes but sharply increases their sensitivity. It
-		leaves the victim half incapacitated on the floor then proceeds to
-		announce to the whole room: "New 'lee of the hour is %s!"`
-
-		return fmt.Sprintf(clean(msg), loth.Name, loth.Name)
+		return render(lothNew, Data{}.WithTarget(loth.Player))
 	default:
 		return fmt.Sprintf("/me looks confused and doesn't do anything at all.")
 	}
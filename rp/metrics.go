@@ -0,0 +1,13 @@
+package rp
+
+import "github.com/kusubooru/eribo/metrics"
+
+var lootRolls = metrics.NewCounter("eribo_loot_rolls_total", "Tools rolled from the loot tables by table and quality.", "table", "quality")
+
+// tietoolsTable returns the name of the tietools loot table of a tool type.
+func tietoolsTable(toolType string) string {
+	if toolType == "" {
+		return "tietools"
+	}
+	return "tietools_" + toolType
+}
@@ -0,0 +1,44 @@
+package rp
+
+import "github.com/kusubooru/eribo/flist"
+
+// Pronouns are the pronouns used to refer to a character in rp messages.
+// Templates can use them as {{.They}}, {{.Them}}, {{.Their}}, {{.Theirs}} and
+// {{.Themself}}, {{.Verb "are" "is"}} for verbs that follow {{.They}} and
+// {{capitalize .Their}} at the start of a sentence.
+type Pronouns struct {
+	They     string
+	Them     string
+	Their    string
+	Theirs   string
+	Themself string
+	plural   bool
+}
+
+var (
+	pronounsHe   = Pronouns{They: "he", Them: "him", Their: "his", Theirs: "his", Themself: "himself"}
+	pronounsShe  = Pronouns{They: "she", Them: "her", Their: "her", Theirs: "hers", Themself: "herself"}
+	pronounsThey = Pronouns{They: "they", Them: "them", Their: "their", Theirs: "theirs", Themself: "themselves", plural: true}
+)
+
+// PronounsFor returns the pronouns for a gender. Genders that do not map to
+// he or she, as well as an unknown gender, use they.
+func PronounsFor(g flist.Gender) Pronouns {
+	switch g {
+	case flist.GenderMale:
+		return pronounsHe
+	case flist.GenderFemale, flist.GenderShemale:
+		return pronounsShe
+	default:
+		return pronounsThey
+	}
+}
+
+// Verb returns the plural form of a verb when the pronouns are plural, e.g.
+// "they are", and the singular form otherwise, e.g. "she is".
+func (p Pronouns) Verb(plural, singular string) string {
+	if p.plural {
+		return plural
+	}
+	return singular
+}
@@ -0,0 +1,75 @@
+package rp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+func TestPronounsFor(t *testing.T) {
+	var tests = []struct {
+		gender flist.Gender
+		want   string
+	}{
+		{flist.GenderMale, "he him his himself"},
+		{flist.GenderFemale, "she her her herself"},
+		{flist.GenderShemale, "she her her herself"},
+		{flist.GenderHerm, "they them their themselves"},
+		{flist.GenderNone, "they them their themselves"},
+		{"", "they them their themselves"},
+	}
+	for _, tt := range tests {
+		p := PronounsFor(tt.gender)
+		if got := strings.Join([]string{p.They, p.Them, p.Their, p.Themself}, " "); got != tt.want {
+			t.Errorf("PronounsFor(%q) = %q, want %q", tt.gender, got, tt.want)
+		}
+	}
+}
+
+func TestPronounsTemplate(t *testing.T) {
+	tmpl := tmplMust(`{{capitalize .They}} {{.Verb "are" "is"}} tied by {{.Their}} feet.`)
+	var tests = []struct {
+		gender flist.Gender
+		want   string
+	}{
+		{flist.GenderMale, "He is tied by his feet."},
+		{flist.GenderFemale, "She is tied by her feet."},
+		{flist.GenderCuntBoy, "They are tied by their feet."},
+	}
+	for _, tt := range tests {
+		got := render(tmpl, Data{}.WithTarget(&eribo.Player{Name: "Bob", Gender: tt.gender}))
+		if got != tt.want {
+			t.Errorf("render with gender %q = %q, want %q", tt.gender, got, tt.want)
+		}
+	}
+}
+
+func TestRandTieUp_owner(t *testing.T) {
+	owner := &eribo.Player{Name: "Alice", Gender: flist.GenderFemale}
+	got := RandTieUp(NewData(owner, owner, owner, "", "Bot"), "")
+	if want := "It kindly offers her a tomato instead."; !strings.HasSuffix(got, want) {
+		t.Errorf("RandTieUp on owner = %q, want suffix %q", got, want)
+	}
+}
+
+func TestTieUps_gender(t *testing.T) {
+	victim := &eribo.Player{Name: "Bob", Gender: flist.GenderMale}
+	for _, tie := range content().tieUps {
+		s := tie.apply(Data{}.WithTarget(victim))
+		if strings.Contains(s, "{{") || strings.Contains(s, "<no value>") {
+			t.Errorf("tieup %q was not fully applied: %q", tie.msg.Root, s)
+		}
+	}
+}
+
+func TestLoth_gender(t *testing.T) {
+	p := &eribo.Player{Name: "Bob", Gender: flist.GenderMale}
+	loth := eribo.NewLoth(p, time.Hour)
+	got := Loth("Alice", loth, true, []*eribo.Player{p})
+	if want := "injects him with a powerful serum which numbs his strength"; !strings.Contains(got, want) {
+		t.Errorf("Loth = %q, want it to contain %q", got, want)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"math/rand"
 	"strings"
 	"time"
+
+	"github.com/kusubooru/eribo/loot"
 )
 
 // Quality is the quality of an  item, folowing the World of Warcrarft system.
@@ -139,6 +141,15 @@ func (c Color) String() string {
 	}
 }
 
+func makeColor(s string) Color {
+	for c := Red; c <= Turquoise; c++ {
+		if c.String() == s {
+			return c
+		}
+	}
+	return Colorless
+}
+
 func newRand(n int) int {
 	seed := time.Now().UnixNano()
 	r := rand.New(rand.NewSource(seed))
@@ -164,14 +175,18 @@ var feedback = []string{
 	`/me nods affirmatively, "Understood %s. Your feedback has been recorded".`,
 }
 
+var (
+	tomatoOwner = tmplMust(`/me humbly offers a juicy and fresh-looking tomato
+	to {{.User}}, "A pleasure to serve you Ryuunosuke-sama".`)
+	tomato = tmplMust(`/me gives a fresh-looking tomato to {{.User}}.`)
+)
+
 // Tomato returns a message for when the player uses the tomato command.
-func Tomato(name, owner string) string {
-	if name == owner {
-		var s = `/me humbly offers a juicy and fresh-looking tomato to %s, "A
-		pleasure to serve you Ryuunosuke-sama".`
-		return fmt.Sprintf(clean(s), name)
+func Tomato(d Data) string {
+	if d.Target.Name == d.Owner.Name {
+		return render(tomatoOwner, d)
 	}
-	return fmt.Sprintf("/me gives a fresh-looking tomato to %s.", name)
+	return render(tomato, d)
 }
 
 func qualityColor(q Quality) string {
@@ -197,41 +212,34 @@ func qualityColorBBCode(q Quality, s string) string {
 	return fmt.Sprintf("[color=%s]%s[/color]", qualityColor(q), s)
 }
 
+// qualityTable creates a composite loot table that first rolls a quality and
+// then one of the items of that quality. The weight of each quality is the sum
+// of the weights of its items so the chance of each item stays the same as in
+// a flat table.
+func qualityTable[T any](items []T, quality func(T) Quality, weight func(T) int) *loot.Composite[T] {
+	root := loot.NewComposite[T]()
+	byQuality := make(map[Quality]*loot.Composite[T])
+	totals := make(map[Quality]int)
+	for _, it := range items {
+		q := quality(it)
+		if _, ok := byQuality[q]; !ok {
+			byQuality[q] = loot.NewComposite[T]()
+		}
+		byQuality[q].AddItem(it, weight(it))
+		totals[q] += weight(it)
+	}
+	for q := Unknown; q <= Legendary; q++ {
+		if totals[q] != 0 {
+			root.AddTable(byQuality[q], totals[q])
+		}
+	}
+	return root
+}
+
 type animeOp struct {
-	Raw string
+	Raw string `json:"raw"`
 }
 
 func (j animeOp) Apply() string {
 	return clean(j.Raw)
 }
-
-var animeOps = []animeOp{
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=yF00xX-p28Y]Jojo
-		Opening 1 - Sono Chi no Sadame[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=i-GWFGwbEPg]Jojo
-		Opening 2 - BLOODY STREAM[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=RordBk3Ztk4]Jojo
-		Opening 3 - STAND PROUD[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=f0yK_7adSCA]Jojo
-		Opening 4 - Sono Chi no Kioku[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=nNQ-Qi7pBpw]Jojo
-		Opening 5 - Crazy Noisy Bizarre Town[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=qr73XbBFDA8]Jojo
-		Opening 6 - chase[/url]`,
-	},
-	{
-		Raw: `/me plays: [url=https://www.youtube.com/watch?v=zoqH1Rk4ANM]Jojo
-		Opening 7 - Great Days[/url]`,
-	},
-}
@@ -6,10 +6,11 @@ import (
 )
 
 func TestTieUps(t *testing.T) {
-	for _, tt := range tieUps {
-		checkMePrefix(t, tt.msg)
-		checkBBCode(t, tt.msg)
-		checkVerbCount(t, tt.msg, "%s", 1)
+	for _, tt := range content().tieUps {
+		s := tt.apply(sampleData("John Doe"))
+		checkMePrefix(t, s)
+		checkBBCode(t, s)
+		checkVerbCount(t, s, "John Doe", 1)
 	}
 }
 
@@ -55,8 +56,8 @@ func checksSubstringExists(t *testing.T, s, substr string) {
 }
 
 func TestTietools(t *testing.T) {
-	for _, tt := range tietools {
-		s, err := tt.Apply("John Doe")
+	for _, tt := range content().Tietools {
+		s, err := tt.Apply(sampleData("John Doe"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -67,14 +68,11 @@ func TestTietools(t *testing.T) {
 }
 
 func TestVonproves(t *testing.T) {
-	for _, tt := range vonproves {
-		checkMePrefix(t, tt.Raw)
-		checkBBCode(t, tt.Raw)
-		if tt.HasDate || tt.HasDuration {
-			checkVerbCount(t, tt.Raw, "%v", 1)
-		}
-		if tt.HasUser {
-			checkVerbCount(t, tt.Raw, "%s", 1)
-		}
+	for _, tt := range content().vonproves {
+		s := tt.Apply(sampleData("John Doe"))
+		checkMePrefix(t, s)
+		checkBBCode(t, s)
+		checkVerbCount(t, s, "%", 0)
+		checkVerbCount(t, s, "{{", 0)
 	}
 }
@@ -0,0 +1,303 @@
+package rp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+// Char is a character that an rp message refers to.
+type Char struct {
+	Name   string
+	Gender flist.Gender
+	Pronouns
+
+	optsOut bool
+}
+
+func newChar(p *eribo.Player) Char {
+	if p == nil {
+		return Char{Pronouns: pronounsThey}
+	}
+	return Char{Name: p.Name, Gender: p.Gender, Pronouns: PronounsFor(p.Gender), optsOut: p.OptsOut()}
+}
+
+// Data is the data that every rp template is executed with. Command specific
+// data such as the tool of a tietool embed it.
+//
+// User and the pronouns are the ones of the target so that templates can
+// simply use {{.User}} and {{.Their}}. The other characters are available as
+// {{.Issuer.Name}}, {{.Owner.Them}} etc. When a message is aimed at a group,
+// Targets holds each of them and the target is the whole group, e.g. "Alice,
+// Bob and Carol" with plural pronouns. Suggestions are the names the issuer
+// might have meant when the target was not found or was ambiguous, and
+// Unresolved the names that were not found or were ambiguous when others
+// were. Skipped are the targets that were left out because they opt out.
+type Data struct {
+	User string
+	Pronouns
+	Issuer  Char
+	Target  Char
+	Targets []Char
+	Owner   Char
+	Channel string
+	BotName string
+	Time    time.Time
+
+	Suggestions []string
+	Unresolved  []string
+	Skipped     []Char
+}
+
+// NewData returns the data for a message that the issuer of a command aims at
+// the target.
+func NewData(issuer, target, owner *eribo.Player, channel, botName string) Data {
+	d := Data{
+		Issuer:  newChar(issuer),
+		Owner:   newChar(owner),
+		Channel: channel,
+		BotName: botName,
+		Time:    time.Now(),
+	}
+	return d.WithTarget(target)
+}
+
+// WithTarget returns a copy of the data aimed at another target.
+func (d Data) WithTarget(target *eribo.Player) Data {
+	d.Target = newChar(target)
+	d.Targets = []Char{d.Target}
+	d.User = d.Target.Name
+	d.Pronouns = d.Target.Pronouns
+	return d
+}
+
+// WithTargets returns a copy of the data aimed at a group of targets.
+func (d Data) WithTargets(targets []*eribo.Player) Data {
+	if len(targets) == 1 {
+		return d.WithTarget(targets[0])
+	}
+	chars := make([]Char, 0, len(targets))
+	for _, t := range targets {
+		chars = append(chars, newChar(t))
+	}
+	return d.withChars(chars)
+}
+
+func (d Data) withChars(chars []Char) Data {
+	d.Targets = chars
+	d.Target = Char{Name: names(chars), Pronouns: pronounsThey}
+	if len(chars) == 1 {
+		d.Target = chars[0]
+	}
+	d.User = d.Target.Name
+	d.Pronouns = d.Target.Pronouns
+	return d
+}
+
+// WithSuggestions returns a copy of the data with the names the issuer might
+// have meant.
+func (d Data) WithSuggestions(names []string) Data {
+	d.Suggestions = names
+	return d
+}
+
+// WithUnresolved returns a copy of the data with the names that did not
+// resolve to a target while others did.
+func (d Data) WithUnresolved(names []string) Data {
+	d.Unresolved = names
+	return d
+}
+
+// withoutRefusing returns a copy of the data without the bot, its owner and
+// the players who opt out among the targets. The players who opt out are kept
+// in Skipped so that the message can tell who was left alone.
+func (d Data) withoutRefusing() Data {
+	chars := make([]Char, 0, len(d.Targets))
+	var skipped []Char
+	for _, c := range d.Targets {
+		switch {
+		case d.refuses(c):
+		case c.optsOut:
+			skipped = append(skipped, c)
+		default:
+			chars = append(chars, c)
+		}
+	}
+	d = d.withChars(chars)
+	d.Skipped = skipped
+	return d
+}
+
+// refuses reports whether the character is the bot or its owner, who refuse
+// to be the target of rp commands.
+func (d Data) refuses(c Char) bool {
+	return c.Name == d.BotName || c.Name == d.Owner.Name
+}
+
+// refusal returns the message when no target of d is left in g, its copy
+// without the refusing targets. The first of the bot and its owner among the
+// targets gets the refusal of the command or, if neither is, the bot does
+// nothing.
+func refusal(d, g Data, refuse func(Data) string) string {
+	for _, c := range d.Targets {
+		if d.refuses(c) {
+			return refuse(g.withChars([]Char{c})) + render(targetNotesMsg, g)
+		}
+	}
+	return render(optedOutMsg, g)
+}
+
+// IsGroup reports whether the data is aimed at more than one target.
+func (d Data) IsGroup() bool {
+	return len(d.Targets) > 1
+}
+
+// sampleData is the data used to check templates when the content is loaded
+// and linted.
+func sampleData(name string) Data {
+	p := &eribo.Player{Name: name}
+	return NewData(p, p, &eribo.Player{Name: "Owner"}, "Channel", "Bot")
+}
+
+// funcs are the functions available to all rp templates.
+var funcs = template.FuncMap{
+	"capitalize":   capitalize,
+	"pick":         pick,
+	"plural":       plural,
+	"qualityColor": qualityColorBBCode,
+	"bbsafe":       bbsafe,
+	"intensity":    randIntensity,
+	"spell":        spell,
+	"names":        names,
+	"andList":      andList,
+	"orList":       orList,
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// pick returns one of the choices at random, e.g. {{pick "soft" "fluffy"}}.
+func pick(choices ...string) string {
+	if len(choices) == 0 {
+		return ""
+	}
+	return choices[newRand(len(choices))]
+}
+
+// plural returns the plural form when n is true or a number other than one and
+// the singular form otherwise, e.g. {{plural .Part.Plural "it" "them"}}.
+func plural(n interface{}, singular, plural string) (string, error) {
+	switch n := n.(type) {
+	case bool:
+		if n {
+			return plural, nil
+		}
+	case int:
+		if n != 1 {
+			return plural, nil
+		}
+	default:
+		return "", fmt.Errorf("plural: unsupported count %v of type %T", n, n)
+	}
+	return singular, nil
+}
+
+// bbsafe keeps BBCode in a name from being parsed by the chat.
+func bbsafe(s string) string {
+	if !strings.ContainsAny(s, "[]") {
+		return s
+	}
+	return "[noparse]" + strings.Replace(s, "[/noparse]", "", -1) + "[/noparse]"
+}
+
+var numberWords = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight",
+	"nine", "ten", "eleven", "twelve",
+}
+
+// spell spells out small numbers, e.g. {{spell 5}} is "five".
+func spell(n int) string {
+	if n >= 0 && n < len(numberWords) {
+		return numberWords[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// names lists the names of the characters, e.g. "Alice, Bob and Carol".
+func names(chars []Char) string {
+	s := make([]string, len(chars))
+	for i, c := range chars {
+		s[i] = c.Name
+	}
+	return andList(s)
+}
+
+func andList(s []string) string {
+	return joinList(s, "and")
+}
+
+// orList lists alternatives, e.g. "Ann, Anna or Annie".
+func orList(s []string) string {
+	return joinList(s, "or")
+}
+
+func joinList(s []string, conj string) string {
+	switch len(s) {
+	case 0:
+		return ""
+	case 1:
+		return s[0]
+	}
+	return strings.Join(s[:len(s)-1], ", ") + " " + conj + " " + s[len(s)-1]
+}
+
+// didYouMean is appended to the messages of targets that were not found.
+const didYouMean = `{{if .Suggestions}} Did you mean {{orList .Suggestions}}?{{end}}`
+
+// targetNotes is appended to the messages aimed at named targets to tell who
+// was left alone and which names were not found.
+const targetNotes = `{{if .Skipped}} {{names .Skipped}} opted out and
+	{{plural (len .Skipped) "was" "were"}} left alone.{{end}}
+	{{- if .Unresolved}} Could not find {{andList .Unresolved}}.{{end}}` + didYouMean
+
+var (
+	targetNotesMsg = tmplMust(targetNotes)
+	optedOutMsg    = tmplMust(`/me does nothing.` + targetNotes)
+)
+
+// newTemplate returns a new rp template with the rp functions.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(funcs)
+}
+
+// execute executes tmpl with data and returns the cleaned result.
+func execute(tmpl *template.Template, data interface{}) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return clean(b.String()), nil
+}
+
+// render is like execute but for templates that are known to execute
+// with their data, such as the ones validated when the content is loaded. It
+// returns the error as the message if they do not.
+func render(tmpl *template.Template, data interface{}) string {
+	s, err := execute(tmpl, data)
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+package rp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/flist"
+)
+
+func TestFuncs(t *testing.T) {
+	var tests = []struct {
+		tmpl string
+		want string
+	}{
+		{`{{pick "soft"}}`, "soft"},
+		{`{{plural true "it" "them"}}`, "them"},
+		{`{{plural false "it" "them"}}`, "it"},
+		{`{{plural 1 "rope" "ropes"}}`, "rope"},
+		{`{{plural 3 "rope" "ropes"}}`, "ropes"},
+		{`{{spell 10}} {{spell 42}}`, "ten 42"},
+		{`{{qualityColor .Quality "Rope"}}`, "[color=blue]Rope[/color]"},
+		{`{{bbsafe "Bob"}} {{bbsafe "[b]Bob[/b]"}}`, "Bob [noparse][b]Bob[/b][/noparse]"},
+		{`{{capitalize .Their}} {{.Issuer.Name}} {{.Owner.Them}} {{.BotName}}`, "His Alice her Bot"},
+	}
+	issuer := &eribo.Player{Name: "Alice", Gender: flist.GenderFemale}
+	target := &eribo.Player{Name: "Bob", Gender: flist.GenderMale}
+	d := NewData(issuer, target, issuer, "Room", "Bot")
+	for _, tt := range tests {
+		data := struct {
+			Data
+			Quality Quality
+		}{d, Rare}
+		tmpl, err := newTemplate("").Parse(tt.tmpl)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", tt.tmpl, err)
+		}
+		got, err := execute(tmpl, data)
+		if err != nil {
+			t.Fatalf("executing %q: %v", tt.tmpl, err)
+		}
+		if got != tt.want {
+			t.Errorf("executing %q = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestIntensity(t *testing.T) {
+	got, err := strconv.Atoi(render(tmplMust(`{{intensity}}`), nil))
+	if err != nil {
+		t.Fatalf("intensity is not a number: %v", err)
+	}
+	for _, i := range ticklizerIntensities {
+		if got == i {
+			return
+		}
+	}
+	t.Errorf("intensity = %d, want one of %v", got, ticklizerIntensities)
+}
+
+func TestTicklizerMsg(t *testing.T) {
+	d := NewData(nil, &eribo.Player{Name: "Bob", Gender: flist.GenderMale}, &eribo.Player{Name: "Alice"}, "", "Bot")
+	var tests = []struct {
+		part      bodyPart
+		intensity int
+		filtered  bool
+		want      string
+	}{
+		{
+			bodyPart{Name: "feet", Plural: true}, 2, false,
+			"/me fires two small rays of the ticklizer beam from the tips of its index fingers, zapping Bob's [u]feet[/u], making them two times more ticklish!",
+		},
+		{
+			bodyPart{Name: "belly"}, 10, true,
+			"/me concentrates its aim and fires a large ray of the ticklizer beam from the center of its chest, zapping Bob's [u]belly[/u], making it ten times more ticklish!",
+		},
+	}
+	for _, tt := range tests {
+		got := ticklizerMsg(d, normal, tt.part, tt.intensity, tt.filtered)
+		if got != tt.want {
+			t.Errorf("ticklizerMsg(%v, %d, %v) =\nhave: %q\nwant: %q", tt.part, tt.intensity, tt.filtered, got, tt.want)
+		}
+	}
+	if got, want := ticklizerMsg(d, confused, bodyPart{Name: "neck"}, 5, false), "hitting his [u]neck[/u]"; !strings.Contains(got, want) {
+		t.Errorf("confused ticklizerMsg = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestWithTargets(t *testing.T) {
+	d := Data{}.WithTargets([]*eribo.Player{
+		{Name: "Alice", Gender: flist.GenderFemale},
+		{Name: "Bob", Gender: flist.GenderMale},
+		{Name: "Carol"},
+	})
+	if !d.IsGroup() {
+		t.Fatal("WithTargets of three players is not a group")
+	}
+	if got, want := render(tmplMust(`{{capitalize .They}}: {{.User}}`), d), "They: Alice, Bob and Carol"; got != want {
+		t.Errorf("group data rendered %q, want %q", got, want)
+	}
+	if got, want := render(tmplMust(`{{names .Targets}}`), d), "Alice, Bob and Carol"; got != want {
+		t.Errorf("names rendered %q, want %q", got, want)
+	}
+	if d := (Data{}).WithTargets([]*eribo.Player{{Name: "Alice"}}); d.IsGroup() {
+		t.Errorf("WithTargets of one player is a group")
+	}
+}
+
+func TestGroup_refusing(t *testing.T) {
+	owner := &eribo.Player{Name: "Owner", Gender: flist.GenderFemale}
+	bot := &eribo.Player{Name: "Eribo"}
+	alice := &eribo.Player{Name: "Alice"}
+	bob := &eribo.Player{Name: "Bob"}
+	carol := &eribo.Player{Name: "Carol", Role: flist.RoleFullDom}
+	dave := &eribo.Player{Name: "Dave", Status: flist.StatusDND}
+	d := NewData(alice, alice, owner, "", "Eribo")
+	var tests = []struct {
+		targets  []*eribo.Player
+		contains []string
+		excludes []string
+	}{
+		{[]*eribo.Player{alice, bob, owner}, []string{"Alice", "Bob"}, []string{"Owner", "tomato"}},
+		{[]*eribo.Player{alice, bot}, []string{"Alice"}, []string{"Eribo"}},
+		{[]*eribo.Player{owner, bot}, []string{"tomato"}, nil},
+		{[]*eribo.Player{carol}, []string{"does nothing. Carol opted out and was left alone."}, nil},
+		{[]*eribo.Player{alice, carol, dave}, []string{"Alice", "Carol and Dave opted out and were left alone."}, nil},
+		{[]*eribo.Player{alice, bob, carol}, []string{"Alice", "Bob", "Carol opted out"}, nil},
+		{[]*eribo.Player{carol, owner}, []string{"tomato", "Carol opted out"}, nil},
+	}
+	for _, tt := range tests {
+		for name, f := range map[string]func(Data, string) string{
+			"RandTieUpGroup": RandTieUpGroup,
+			"TicklizerGroup": TicklizerGroup,
+		} {
+			got := f(d.WithTargets(tt.targets), "")
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("%s(%q) = %q, want it to contain %q", name, names(d.WithTargets(tt.targets).Targets), got, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("%s(%q) = %q, want it not to contain %q", name, names(d.WithTargets(tt.targets).Targets), got, s)
+				}
+			}
+		}
+	}
+}
+
+func TestGroup_unresolved(t *testing.T) {
+	alice := &eribo.Player{Name: "Alice"}
+	d := NewData(alice, alice, &eribo.Player{Name: "Owner"}, "", "Eribo").
+		WithTargets([]*eribo.Player{alice}).
+		WithUnresolved([]string{"An", "Bo"}).
+		WithSuggestions([]string{"Ann", "Bob"})
+	want := " Could not find An and Bo. Did you mean Ann or Bob?"
+	for name, f := range map[string]func(Data, string) string{
+		"RandTieUpGroup": RandTieUpGroup,
+		"TicklizerGroup": TicklizerGroup,
+	} {
+		if got := f(d, ""); !strings.Contains(got, "Alice") || !strings.HasSuffix(got, want) {
+			t.Errorf("%s with unresolved names = %q, want Alice and suffix %q", name, got, want)
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	d := NewData(nil, &eribo.Player{Name: "Bob"}, &eribo.Player{Name: "Alice"}, "", "Bot")
+	var tests = []struct {
+		name        string
+		f           func(Data, string) string
+		suggestions []string
+		want        string
+	}{
+		{"RandTieUpNotFound", RandTieUpNotFound, []string{"Ann"}, "stays idle. Did you mean Ann?"},
+		{"RandTieUpConfused", RandTieUpConfused, []string{"Ann", "Anna", "Annie"}, "takes no action. Did you mean Ann, Anna or Annie?"},
+		{"RandTieUpNotFound", RandTieUpNotFound, nil, "stays idle."},
+		{"TicklizerNotFound", TicklizerNotFound, []string{"Ann"}, "ticklish! Did you mean Ann?"},
+		{"TicklizerConfused", TicklizerConfused, []string{"Ann", "Anna"}, "ticklish! Did you mean Ann or Anna?"},
+	}
+	for _, tt := range tests {
+		if got := tt.f(d.WithSuggestions(tt.suggestions), ""); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s with suggestions %q = %q, want suffix %q", tt.name, tt.suggestions, got, tt.want)
+		}
+	}
+}
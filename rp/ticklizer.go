@@ -12,37 +12,24 @@ const (
 )
 
 type bodyPart struct {
-	name   string
-	plural bool
-}
-
-var bodyParts = []bodyPart{
-	{"armpits", true},
-	{"underarms", true},
-	{"ribs", true},
-	{"sides", true},
-	{"feet", true},
-	{"tummy", false},
-	{"chest", false},
-	{"thighs", true},
-	{"hips", true},
-	{"genitals", true},
-	{"butt", false},
+	Name   string
+	Plural bool
 }
 
 func filterBodyParts(filter string) ([]bodyPart, bool) {
+	bodyParts := content().bodyParts
 	parts := make([]bodyPart, 0)
 	switch {
 	case filter == "ub":
 		for _, p := range bodyParts {
-			if p.name != "feet" {
+			if p.Name != "feet" {
 				parts = append(parts, p)
 			}
 		}
 		return parts, true
 	case filter != "":
 		for _, p := range bodyParts {
-			if filter == p.name {
+			if filter == p.Name {
 				parts = append(parts, p)
 			}
 		}
@@ -53,9 +40,10 @@ func filterBodyParts(filter string) ([]bodyPart, bool) {
 }
 
 func ticklizerFilters() []string {
+	bodyParts := content().bodyParts
 	filters := make([]string, len(bodyParts)+1)
 	for _, p := range bodyParts {
-		filters = append(filters, p.name)
+		filters = append(filters, p.Name)
 	}
 	filters = append(filters, "ub")
 	return filters
@@ -73,77 +61,130 @@ func InTicklizerFilters(arg string) bool {
 	return false
 }
 
-func ticklizer(name, owner, botName string, tcase ticklizerCase, filter string) string {
-	factors := []int{2, 5, 10}
-	intensity := factors[newRand(len(factors))]
+// ticklizerIntensities are the factors by which the ticklizer beam can
+// increase ticklishness.
+var ticklizerIntensities = []int{2, 5, 10}
 
+// randIntensity returns one of the ticklizer intensities at random.
+func randIntensity() int {
+	return ticklizerIntensities[newRand(len(ticklizerIntensities))]
+}
+
+func ticklizer(d Data, tcase ticklizerCase, filter string) string {
 	parts, hasFilter := filterBodyParts(filter)
 	part := parts[newRand(len(parts))]
-	itOrThem := "it"
-	if part.plural {
-		itOrThem = "them"
+	return ticklizerMsg(d, tcase, part, randIntensity(), hasFilter)
+}
+
+// ticklizerData is the data that the ticklizer templates are executed with.
+type ticklizerData struct {
+	Data
+	Part      bodyPart
+	Intensity int
+	Filtered  bool
+}
+
+var (
+	ticklizerConfused = tmplMust(`/me found more than one targets. It got
+	confused and zapped {{.User}} instead with the ticklizer beam, hitting
+	{{.Their}} [u]{{.Part.Name}}[/u] making, {{plural .Part.Plural "it" "them"}}
+	ten times more ticklish!` + didYouMean)
+	ticklizerNotFound = tmplMust(`/me could not find its target. It got
+	confused and zapped {{.User}} instead with the ticklizer beam, hitting
+	{{.Their}} [u]{{.Part.Name}}[/u], making {{plural .Part.Plural "it" "them"}}
+	ten times more ticklish!` + didYouMean)
+	ticklizerForbidden = tmplMust(`/me is forbidden from hitting that target.
+	It turns and zaps {{.User}} instead with the ticklizer beam, hitting
+	{{.Their}} [u]{{.Part.Name}}[/u], making {{plural .Part.Plural "it" "them"}}
+	ten times more ticklish!`)
+	ticklizerHit = tmplMust(`/me {{if .Filtered}}concentrates its aim and {{end}}
+	{{- if eq .Intensity 10}}fires a large ray of the ticklizer beam from the
+	center of its chest
+	{{- else if eq .Intensity 5}}fires the ticklizer beam from the palms of its
+	hands
+	{{- else}}fires two small rays of the ticklizer beam from the tips of its
+	index fingers
+	{{- end}}, zapping {{.User}}'s [u]{{.Part.Name}}[/u], making
+	{{plural .Part.Plural "it" "them"}} {{spell .Intensity}} times more
+	ticklish!`)
+	ticklizerSelf    = tmplMust(`/me refuses to hit itself and does nothing instead.`)
+	ticklizerCreator = tmplMust(`/me refuses to hit its creator. It kindly offers {{.Owner.Them}} a tomato instead.`)
+)
+
+func ticklizerMsg(d Data, tcase ticklizerCase, part bodyPart, intensity int, hasFilter bool) string {
+	data := ticklizerData{
+		Data:      d,
+		Part:      part,
+		Intensity: intensity,
+		Filtered:  hasFilter,
 	}
-	var format string
-	filterMsg := ""
 	switch tcase {
 	case confused:
-		format = `/me found more than one targets. It got confused and zapped
-		%s instead with the ticklizer beam, hitting their [u]%s[/u] making, %s
-		ten times more ticklish!`
-		return fmt.Sprintf(clean(format), name, part.name, itOrThem)
+		return render(ticklizerConfused, data)
 	case notFound:
-		format = `/me could not find its target. It got confused and zapped %s
-		instead with the ticklizer beam, hitting their [u]%s[/u], making %s ten
-		times more ticklish!`
-		return fmt.Sprintf(clean(format), name, part.name, itOrThem)
+		return render(ticklizerNotFound, data)
 	case forbidden:
-		format = `/me is forbidden from hitting that target. It turns and zaps
-		%s instead with the ticklizer beam, hitting their [u]%s[/u], making %s
-		ten times more ticklish!`
-		return fmt.Sprintf(clean(format), name, part.name, itOrThem)
-	default:
-		if hasFilter {
-			filterMsg = "concentrates its aim and"
-		}
-		switch intensity {
-		default:
-			format = `/me %s fires two small rays of the ticklizer beam from
-			the tips of its index fingers, zapping %s's [u]%s[/u], making %s
-			two times more ticklish!`
-		case 5:
-			format = `/me %s fires the ticklizer beam from the palms of its
-			hands, zapping %s's [u]%s[/u], making %s five times more ticklish!`
-		case 10:
-			format = `/me %s fires a large ray of the ticklizer beam from the
-			center of its chest, zapping %s's [u]%s[/u], making %s ten times
-			more ticklish!`
-		}
+		return render(ticklizerForbidden, data)
+	}
+	if d.Target.Name == d.BotName {
+		return render(ticklizerSelf, data)
+	}
+	if d.Target.Name == d.Owner.Name {
+		return render(ticklizerCreator, data)
 	}
-	if name == botName {
-		return fmt.Sprintf(`/me refuses to hit itself and does nothing instead.`)
+	return render(ticklizerHit, data)
+}
+
+// ticklizerGroupData is the data that the group ticklizer template is
+// executed with.
+type ticklizerGroupData struct {
+	Data
+	Hits      string
+	Intensity int
+}
+
+var ticklizerGroup = tmplMust(`/me spins around firing the ticklizer beam in
+	every direction, zapping {{.Hits}}, making them {{spell .Intensity}} times
+	more ticklish!`)
+
+// TicklizerGroup returns a message for the ticklizer command aimed at one or
+// more named targets. Each target is hit on a different random body part. The
+// bot, its creator and the players who opt out are left out of the group. If
+// less than two targets remain, the message is the one of a single target.
+func TicklizerGroup(d Data, filter string) string {
+	g := d.withoutRefusing()
+	switch len(g.Targets) {
+	case 0:
+		return refusal(d, g, func(r Data) string { return ticklizer(r, normal, filter) })
+	case 1:
+		return ticklizer(g, normal, filter) + render(targetNotesMsg, g)
 	}
-	if name == owner {
-		return fmt.Sprintf(`/me refuses to hit its creator. It kindly offers him a tomato instead.`)
+	parts, _ := filterBodyParts(filter)
+	hits := make([]string, 0, len(g.Targets))
+	for _, t := range g.Targets {
+		part := parts[newRand(len(parts))]
+		hits = append(hits, fmt.Sprintf("%s's [u]%s[/u]", t.Name, part.Name))
 	}
-	return fmt.Sprintf(clean(format), filterMsg, name, part.name, itOrThem)
+	data := ticklizerGroupData{Data: g, Hits: andList(hits), Intensity: randIntensity()}
+	return render(ticklizerGroup, data) + render(targetNotesMsg, g)
 }
 
 // Ticklizer returns a message for the homonymous command.
-func Ticklizer(name, owner, botName, filter string) string {
-	return ticklizer(name, owner, botName, normal, filter)
+func Ticklizer(d Data, filter string) string {
+	return ticklizer(d, normal, filter)
 }
 
 // TicklizerConfused returns a message when the confused state.
-func TicklizerConfused(name, owner, botName, filter string) string {
-	return ticklizer(name, owner, botName, confused, filter)
+func TicklizerConfused(d Data, filter string) string {
+	return ticklizer(d, confused, filter)
 }
 
 // TicklizerNotFound returns a message when the not found state.
-func TicklizerNotFound(name, owner, botName, filter string) string {
-	return ticklizer(name, owner, botName, notFound, filter)
+func TicklizerNotFound(d Data, filter string) string {
+	return ticklizer(d, notFound, filter)
 }
 
 // TicklizerForbidden returns a message when the forbidden state.
-func TicklizerForbidden(name, owner, botName, filter string) string {
-	return ticklizer(name, owner, botName, notFound, filter)
+func TicklizerForbidden(d Data, filter string) string {
+	return ticklizer(d, notFound, filter)
 }
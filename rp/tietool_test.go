@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/kusubooru/eribo/loot"
 )
 
 func TestTietoolMarshalText(t *testing.T) {
@@ -42,6 +44,12 @@ rare
 		Quality: Rare,
 		Desc:    tmplMust(`/me gives {{.Tool}} to {{.User}}`),
 	}
+	// Templates hold functions which are never deeply equal so the
+	// descriptions are compared as text.
+	if got, want := tool.Desc.Root.String(), want.Desc.Root.String(); got != want {
+		t.Errorf("UnmarshalText Desc \nhave: %q\nwant: %q", got, want)
+	}
+	tool.Desc, want.Desc = nil, nil
 	if got := tool; !reflect.DeepEqual(got, want) {
 		t.Errorf("UnmarshalText \nhave: %#v\nwant: %#v", got, want)
 	}
@@ -49,8 +57,8 @@ rare
 
 func TestTietoolsApply(t *testing.T) {
 	user := "bob"
-	for _, tool := range tietools {
-		msg, err := tool.Apply(user)
+	for _, tool := range content().Tietools {
+		msg, err := tool.Apply(sampleData(user))
 		if err != nil {
 			t.Fatalf("applying tool %v, returned err: %v", tool, err)
 		}
@@ -62,3 +70,27 @@ func TestTietoolsApply(t *testing.T) {
 		}
 	}
 }
+
+func TestTietoolsByQuality(t *testing.T) {
+	tools := TietoolsByQuality("").RollN(loot.RollContext{}, len(content().Tietools)+1)
+	if got, want := len(tools), len(content().Tietools); got != want {
+		t.Fatalf("RollN returned %d tietools, want %d", got, want)
+	}
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		if seen[tool.Name()] {
+			t.Errorf("tietool %q dropped twice", tool.Name())
+		}
+		seen[tool.Name()] = true
+	}
+}
+
+func TestRandTietool(t *testing.T) {
+	msg, err := RandTietool(sampleData("bob"), "")
+	if err != nil {
+		t.Fatal("RandTietool failed:", err)
+	}
+	if !strings.Contains(msg, "bob") {
+		t.Errorf("RandTietool message = %q, want bob in message", msg)
+	}
+}
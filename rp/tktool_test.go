@@ -9,7 +9,7 @@ import (
 
 func testTktoolApply(t *testing.T, tool Tktool, user string) string {
 	t.Helper()
-	s, err := tool.Apply(user)
+	s, err := tool.Apply(sampleData(user))
 	if err != nil {
 		t.Errorf("applying %q to %q returned error: %v", user, tool.Emote.Root, err)
 	}
@@ -17,7 +17,7 @@ func testTktoolApply(t *testing.T, tool Tktool, user string) string {
 }
 
 func TestTktools(t *testing.T) {
-	for _, tool := range tktools {
+	for _, tool := range content().Tktools {
 		checkSyntax(t, tool.Emote.Root.String(), "{", "}")
 		s := testTktoolApply(t, tool, "Bob")
 		checkMePrefix(t, s)
@@ -49,7 +49,7 @@ func TestTktool_Apply(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		got, err := tt.tool.Apply("Bob")
+		got, err := tt.tool.Apply(sampleData("Bob"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -61,8 +61,8 @@ func TestTktool_Apply(t *testing.T) {
 
 func TestTktoolsApply(t *testing.T) {
 	user := "bob"
-	for _, tool := range tktools {
-		msg, err := tool.Apply(user)
+	for _, tool := range content().Tktools {
+		msg, err := tool.Apply(sampleData(user))
 		if err != nil {
 			t.Fatalf("applying tool %v, returned err: %v", tool, err)
 		}
@@ -78,7 +78,7 @@ func TestTktoolsApply(t *testing.T) {
 func TestTktoolsLootTableLegendaries(t *testing.T) {
 
 	tableOneLego := loot.NewTable(
-		[]loot.Drop{
+		[]loot.Drop[Tktool]{
 			{Item: Tktool{Quality: Legendary}, Weight: 0},
 			{Item: Tktool{Quality: Epic}, Weight: 1},
 		},
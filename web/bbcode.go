@@ -0,0 +1,83 @@
+package web
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bbcodeColors are the colors F-Chat supports in [color] tags.
+var bbcodeColors = map[string]bool{
+	"red": true, "blue": true, "white": true, "yellow": true, "pink": true,
+	"gray": true, "green": true, "orange": true, "purple": true, "black": true,
+	"brown": true, "cyan": true,
+}
+
+var (
+	bbcodeSimple = strings.NewReplacer(
+		"[b]", "<b>", "[/b]", "</b>",
+		"[i]", "<i>", "[/i]", "</i>",
+		"[u]", "<u>", "[/u]", "</u>",
+		"[s]", "<s>", "[/s]", "</s>",
+		"[sup]", "<sup>", "[/sup]", "</sup>",
+		"[sub]", "<sub>", "[/sub]", "</sub>",
+		"[big]", `<span class="big">`, "[/big]", "</span>",
+		"[small]", `<span class="small">`, "[/small]", "</span>",
+		"[/color]", "</span>",
+		"\n", "<br>",
+	)
+	bbcodeColor    = regexp.MustCompile(`\[color=([a-z]+)\]`)
+	bbcodeURL      = regexp.MustCompile(`\[url=([^\]]+)\](.*?)\[/url\]`)
+	bbcodeBareURL  = regexp.MustCompile(`\[url\](.*?)\[/url\]`)
+	bbcodeUser     = regexp.MustCompile(`\[(?:user|icon)\](.*?)\[/(?:user|icon)\]`)
+	bbcodeNoparse  = regexp.MustCompile(`(?s)\[noparse\](.*?)\[/noparse\]`)
+	bbcodeNoparsed = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// BBCode renders F-Chat BBCode as HTML. The text is escaped first so only the
+// supported tags become markup, links are only made for http and https URLs
+// and tags are not checked for balance since the browser closes them.
+func BBCode(s string) template.HTML {
+	// Noparse sections are set aside behind NUL delimited placeholders.
+	s = strings.Replace(s, "\x00", "", -1)
+	var noparse []string
+	s = bbcodeNoparse.ReplaceAllStringFunc(s, func(m string) string {
+		noparse = append(noparse, html.EscapeString(bbcodeNoparse.FindStringSubmatch(m)[1]))
+		return "\x00" + strconv.Itoa(len(noparse)-1) + "\x00"
+	})
+	s = html.EscapeString(s)
+	s = bbcodeSimple.Replace(s)
+	s = bbcodeColor.ReplaceAllStringFunc(s, func(m string) string {
+		color := bbcodeColor.FindStringSubmatch(m)[1]
+		if !bbcodeColors[color] {
+			return `<span>`
+		}
+		return `<span style="color: ` + color + `">`
+	})
+	s = bbcodeURL.ReplaceAllStringFunc(s, func(m string) string {
+		sm := bbcodeURL.FindStringSubmatch(m)
+		return link(sm[1], sm[2])
+	})
+	s = bbcodeBareURL.ReplaceAllStringFunc(s, func(m string) string {
+		u := bbcodeBareURL.FindStringSubmatch(m)[1]
+		return link(u, u)
+	})
+	s = bbcodeUser.ReplaceAllString(s, `<span class="user">$1</span>`)
+	s = bbcodeNoparsed.ReplaceAllStringFunc(s, func(m string) string {
+		i, _ := strconv.Atoi(bbcodeNoparsed.FindStringSubmatch(m)[1])
+		return noparse[i]
+	})
+	return template.HTML(s)
+}
+
+// link returns a link to the escaped URL u, or just the text if u is not an
+// http or https URL.
+func link(u, text string) string {
+	lower := strings.ToLower(u)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return text
+	}
+	return `<a href="` + u + `" rel="noopener noreferrer" target="_blank">` + text + `</a>`
+}
@@ -0,0 +1,27 @@
+package web
+
+import "testing"
+
+func TestBBCode(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want string
+	}{
+		{"[b]bold[/b] and [i]italic[/i]", "<b>bold</b> and <i>italic</i>"},
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"[color=red]red[/color]", `<span style="color: red">red</span>`},
+		{"[color=expression]x[/color]", "<span>x</span>"},
+		{"[url=https://example.com]site[/url]", `<a href="https://example.com" rel="noopener noreferrer" target="_blank">site</a>`},
+		{"[url]https://example.com[/url]", `<a href="https://example.com" rel="noopener noreferrer" target="_blank">https://example.com</a>`},
+		{"[url=javascript:alert(1)]x[/url]", "x"},
+		{`[url=https://example.com/"onmouseover="x]x[/url]`, `<a href="https://example.com/&#34;onmouseover=&#34;x" rel="noopener noreferrer" target="_blank">x</a>`},
+		{"[user]Alice[/user]", `<span class="user">Alice</span>`},
+		{"[noparse][b]raw[/b][/noparse] [b]x[/b]", "[b]raw[/b] <b>x</b>"},
+		{"a\nb", "a<br>b"},
+	}
+	for _, tt := range tests {
+		if got := string(BBCode(tt.in)); got != tt.want {
+			t.Errorf("BBCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
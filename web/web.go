@@ -0,0 +1,444 @@
+// Package web serves the bot's web dashboard, where the owner can browse what
+// the bot has stored and run owner commands from a browser.
+package web
+
+import (
+	"crypto/subtle"
+	"embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const (
+	defaultLimit = 20
+	maxLimit     = 200
+)
+
+// Dashboard is the web dashboard. It is an http.Handler meant to be mounted
+// under a prefix with http.StripPrefix.
+type Dashboard struct {
+	store    eribo.Store
+	channels *eribo.ChannelMap
+	console  func(command string) string
+
+	// Credentials for HTTP basic authentication.
+	username string
+	password string
+
+	// Version of the bot and the time it started, shown on the home page.
+	Version string
+	Started time.Time
+
+	// Log is where the errors of the pages are logged.
+	Log *slog.Logger
+
+	mux       *http.ServeMux
+	templates map[string]*template.Template
+}
+
+// NewDashboard returns a dashboard for the store and channels that only
+// answers requests authenticated with the username and password. Commands
+// entered in the console are run by the console function, which returns the
+// reply of the owner command. It is called by the goroutines that serve the
+// requests.
+func NewDashboard(store eribo.Store, channels *eribo.ChannelMap, console func(command string) string, username, password string) *Dashboard {
+	d := &Dashboard{
+		store:     store,
+		channels:  channels,
+		console:   console,
+		username:  username,
+		password:  password,
+		Started:   time.Now(),
+		Log:       slog.Default(),
+		mux:       http.NewServeMux(),
+		templates: make(map[string]*template.Template),
+	}
+	for _, page := range []string{"home", "images", "feedback", "cmdlogs", "lothlogs", "cmdstats", "channels", "console"} {
+		d.templates[page] = template.Must(template.New("").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page+".html"))
+	}
+	d.mux.Handle("/", d.handle(d.serveHome))
+	d.mux.Handle("/images", d.handle(d.serveImages))
+	d.mux.Handle("/images/done", d.handle(d.serveImageDone))
+	d.mux.Handle("/images/kuid", d.handle(d.serveImageKuid))
+	d.mux.Handle("/feedback", d.handle(d.serveFeedback))
+	d.mux.Handle("/cmdlogs", d.handle(d.serveCmdLogs))
+	d.mux.Handle("/lothlogs", d.handle(d.serveLothLogs))
+	d.mux.Handle("/cmdstats", d.handle(d.serveCmdStats))
+	d.mux.Handle("/channels", d.handle(d.serveChannels))
+	d.mux.Handle("/console", d.handle(d.serveConsole))
+	return d
+}
+
+var funcs = template.FuncMap{
+	"bbcode": BBCode,
+	"join":   strings.Join,
+	"stamp": func(t time.Time) string {
+		return t.Format(time.Stamp)
+	},
+}
+
+// handle returns a handler that serves a page and logs and replies with its
+// error.
+func (d *Dashboard) handle(fn func(w http.ResponseWriter, r *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			d.Log.Error("serving page failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// httpError is an error with the status code to reply with.
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{code: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP authenticates the request and serves it.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || !equal(user, d.username) || !equal(pass, d.password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="eribo", charset="UTF-8"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" && !sameOrigin(r) {
+		http.Error(w, "cross origin request refused", http.StatusForbidden)
+		return
+	}
+	d.mux.ServeHTTP(w, r)
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// sameOrigin reports whether a request that changes something comes from a
+// page of the dashboard, since browsers send basic authentication along with
+// requests made by other sites too.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	if err != nil || origin == "" {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+func (d *Dashboard) render(w http.ResponseWriter, page string, data interface{}) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return d.templates[page].ExecuteTemplate(w, "layout", data)
+}
+
+// page is the pagination of a list. Lists of the store's Query are paged by
+// a cursor, the ID of the last item of the previous page, and the others by
+// offset.
+type page struct {
+	Limit  int
+	Offset int
+	Before int64
+	Query  url.Values
+	More   bool
+	// Last is the ID of the last item of a list paged by cursor.
+	Last int64
+}
+
+func parsePage(r *http.Request) page {
+	p := page{Limit: defaultLimit, Query: r.URL.Query()}
+	if n, err := strconv.Atoi(r.FormValue("limit")); err == nil && n > 0 {
+		p.Limit = n
+	}
+	if p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+	if n, err := strconv.Atoi(r.FormValue("offset")); err == nil && n > 0 {
+		p.Offset = n
+	}
+	if n, err := strconv.ParseInt(r.FormValue("before"), 10, 64); err == nil && n > 0 {
+		p.Before = n
+	}
+	return p
+}
+
+// query returns q selecting the page, with one more item than the limit to
+// tell whether there is a next page.
+func (p page) query(q eribo.Query) eribo.Query {
+	q.BeforeID, q.Limit = p.Before, p.Limit+1
+	return q
+}
+
+// at returns the query of the list at an offset or, if before is not 0,
+// before a cursor.
+func (p page) at(offset int, before int64) string {
+	q := url.Values{}
+	for k, v := range p.Query {
+		q[k] = v
+	}
+	q.Del("offset")
+	q.Del("before")
+	q.Set("limit", strconv.Itoa(p.Limit))
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	if before > 0 {
+		q.Set("before", strconv.FormatInt(before, 10))
+	}
+	return "?" + q.Encode()
+}
+
+// Prev returns the query of the previous page or "" if this is the first.
+// A cursor only leads to the next page, so the previous page of a list paged
+// by cursor is its first one.
+func (p page) Prev() string {
+	if p.Before != 0 {
+		return p.at(0, 0)
+	}
+	if p.Offset == 0 {
+		return ""
+	}
+	return p.at(p.Offset-p.Limit, 0)
+}
+
+// Next returns the query of the next page or "" if this is the last.
+func (p page) Next() string {
+	if !p.More {
+		return ""
+	}
+	if p.Last != 0 {
+		return p.at(0, p.Last)
+	}
+	return p.at(p.Offset+p.Limit, 0)
+}
+
+// cut removes the item past the limit of a list paged by cursor and keeps
+// the ID of its last item as the cursor of the next page.
+func cut[T any](p *page, items []T, id func(T) int64) []T {
+	if len(items) > p.Limit {
+		items, p.More = items[:p.Limit], true
+	}
+	if len(items) > 0 {
+		p.Last = id(items[len(items)-1])
+	}
+	return items
+}
+
+func (d *Dashboard) serveHome(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return nil
+	}
+	stats, err := d.store.CmdStats()
+	if err != nil {
+		return err
+	}
+	channels, players := 0, 0
+	d.channels.ForEach(func(_ string, pm *eribo.PlayerMap) {
+		channels++
+		pm.ForEach(func(string, *eribo.Player) { players++ })
+	})
+	return d.render(w, "home", struct {
+		Version  string
+		Uptime   time.Duration
+		Channels int
+		Players  int
+		Stats    []*eribo.CmdStat
+	}{d.Version, time.Since(d.Started).Round(time.Second), channels, players, stats})
+}
+
+func (d *Dashboard) serveImages(w http.ResponseWriter, r *http.Request) error {
+	p := parsePage(r)
+	all := r.FormValue("all") != ""
+	desc := r.FormValue("desc") != ""
+	typ := r.FormValue("type")
+	// One more than the limit tells whether there is a next page.
+	images, err := d.store.GetImages(p.Limit+1, p.Offset, desc, !all, typ)
+	if err != nil {
+		return err
+	}
+	if len(images) > p.Limit {
+		images, p.More = images[:p.Limit], true
+	}
+	return d.render(w, "images", struct {
+		Images []*eribo.Image
+		All    bool
+		Desc   bool
+		Type   string
+		Page   page
+	}{images, all, desc, typ, p})
+}
+
+func formID(r *http.Request) (int64, error) {
+	if r.Method != "POST" {
+		return 0, &httpError{code: http.StatusMethodNotAllowed, msg: "method not allowed"}
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return 0, badRequest("bad image id %q", r.FormValue("id"))
+	}
+	return id, nil
+}
+
+// back redirects to the page the form was sent from.
+func back(w http.ResponseWriter, r *http.Request, fallback string) {
+	to := fallback
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host == r.Host {
+		to = ref.RequestURI()
+	}
+	http.Redirect(w, r, to, http.StatusSeeOther)
+}
+
+func (d *Dashboard) serveImageDone(w http.ResponseWriter, r *http.Request) error {
+	id, err := formID(r)
+	if err != nil {
+		return replyError(w, err)
+	}
+	if err := d.store.ToggleImageDone(id); err != nil {
+		return err
+	}
+	back(w, r, "../images")
+	return nil
+}
+
+func (d *Dashboard) serveImageKuid(w http.ResponseWriter, r *http.Request) error {
+	id, err := formID(r)
+	if err != nil {
+		return replyError(w, err)
+	}
+	kuid, err := strconv.Atoi(r.FormValue("kuid"))
+	if err != nil || kuid < 0 {
+		return replyError(w, badRequest("bad kuid %q", r.FormValue("kuid")))
+	}
+	if err := d.store.SetImageKuid(id, kuid); err != nil {
+		return err
+	}
+	back(w, r, "../images")
+	return nil
+}
+
+// replyError replies with the status of an httpError and returns other errors
+// to be served as internal errors.
+func replyError(w http.ResponseWriter, err error) error {
+	if e, ok := err.(*httpError); ok {
+		http.Error(w, e.msg, e.code)
+		return nil
+	}
+	return err
+}
+
+func (d *Dashboard) serveFeedback(w http.ResponseWriter, r *http.Request) error {
+	p := parsePage(r)
+	player := r.FormValue("player")
+	feedback, err := d.store.ListFeedback(p.query(eribo.Query{Player: player}))
+	if err != nil {
+		return err
+	}
+	feedback = cut(&p, feedback, func(f *eribo.Feedback) int64 { return f.ID })
+	return d.render(w, "feedback", struct {
+		Feedback []*eribo.Feedback
+		Player   string
+		Page     page
+	}{feedback, player, p})
+}
+
+func (d *Dashboard) serveCmdLogs(w http.ResponseWriter, r *http.Request) error {
+	p := parsePage(r)
+	command, player := r.FormValue("command"), r.FormValue("player")
+	q := eribo.Query{Player: player}
+	if command != "" {
+		if q.Command, _ = eribo.ParseCommand(command); q.Command == eribo.CmdUnknown {
+			return replyError(w, badRequest("unknown command %q", command))
+		}
+	}
+	logs, err := d.store.ListCmdLogs(p.query(q))
+	if err != nil {
+		return err
+	}
+	logs = cut(&p, logs, func(l *eribo.CmdLog) int64 { return l.ID })
+	return d.render(w, "cmdlogs", struct {
+		Logs    []*eribo.CmdLog
+		Command string
+		Player  string
+		Page    page
+	}{logs, command, player, p})
+}
+
+func (d *Dashboard) serveLothLogs(w http.ResponseWriter, r *http.Request) error {
+	p := parsePage(r)
+	channel := r.FormValue("channel")
+	logs, err := d.store.ListLothLogs(p.query(eribo.Query{Channel: channel}))
+	if err != nil {
+		return err
+	}
+	logs = cut(&p, logs, func(l *eribo.LothLog) int64 { return l.ID })
+	return d.render(w, "lothlogs", struct {
+		Logs    []*eribo.LothLog
+		Channel string
+		Page    page
+	}{logs, channel, p})
+}
+
+func (d *Dashboard) serveCmdStats(w http.ResponseWriter, r *http.Request) error {
+	stats, err := d.store.CmdStats()
+	if err != nil {
+		return err
+	}
+	return d.render(w, "cmdstats", stats)
+}
+
+// channel is a channel of the channel map and its players sorted by name.
+type channel struct {
+	Name    string
+	Players []eribo.Player
+}
+
+func (d *Dashboard) serveChannels(w http.ResponseWriter, r *http.Request) error {
+	var channels []channel
+	d.channels.ForEach(func(name string, pm *eribo.PlayerMap) {
+		ch := channel{Name: name}
+		pm.ForEach(func(_ string, p *eribo.Player) {
+			ch.Players = append(ch.Players, *p)
+		})
+		sort.Slice(ch.Players, func(i, j int) bool { return ch.Players[i].Name < ch.Players[j].Name })
+		channels = append(channels, ch)
+	})
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+	return d.render(w, "channels", channels)
+}
+
+func (d *Dashboard) serveConsole(w http.ResponseWriter, r *http.Request) error {
+	var command, reply string
+	if r.Method == "POST" {
+		command = strings.TrimSpace(r.FormValue("command"))
+		if command != "" {
+			reply = d.console(command)
+			if reply == "" {
+				reply = "unknown command or no reply"
+			}
+		}
+	}
+	return d.render(w, "console", struct {
+		Command string
+		Reply   string
+	}{command, reply})
+}
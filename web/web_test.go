@@ -0,0 +1,236 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/eribo/eribo"
+	"github.com/kusubooru/eribo/eribo/memstore"
+	"github.com/kusubooru/eribo/flist"
+)
+
+func newTestDashboard(t *testing.T) (*Dashboard, *memstore.Store, *[]string) {
+	t.Helper()
+	s := memstore.New()
+	posts := []*eribo.Message{
+		{Message: "[b]look[/b]", Player: "Alice", Channel: "Room"},
+		{Message: "more", Player: "Bob", Channel: "Room"},
+	}
+	for i, m := range posts {
+		images := []*eribo.Image{{URL: fmt.Sprintf("https://example.com/%d.png", i+1)}}
+		if err := s.AddMessageWithImages(m, images); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+	if err := s.ToggleImageDone(2); err != nil {
+		t.Fatal("ToggleImageDone failed:", err)
+	}
+	for _, f := range []*eribo.Feedback{
+		{Player: "Alice", Message: "<b>nice</b>", Created: time.Now()},
+		{Player: "Bob", Message: "meh", Status: eribo.FeedbackWontfix, Tags: eribo.Tags{"rp", "loth"}},
+	} {
+		if err := s.AddFeedback(f); err != nil {
+			t.Fatal("AddFeedback failed:", err)
+		}
+	}
+	channels := eribo.NewChannelMap()
+	channels.SetPlayer("Room", &eribo.Player{Name: "Alice", Status: flist.StatusOnline})
+	var commands []string
+	console := func(command string) string {
+		commands = append(commands, command)
+		return "[b]ok[/b]"
+	}
+	return NewDashboard(s, channels, console, "admin", "secret"), s, &commands
+}
+
+// image returns a stored image or fails the test.
+func image(t *testing.T, s eribo.Store, id int64) *eribo.Image {
+	t.Helper()
+	img, err := s.GetImage(id)
+	if err != nil {
+		t.Fatal("GetImage failed:", err)
+	}
+	return img
+}
+
+func do(d http.Handler, method, target string, form url.Values, auth bool) *httptest.ResponseRecorder {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+	r := httptest.NewRequest(method, target, body)
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Origin", "http://"+r.Host)
+	}
+	if auth {
+		r.SetBasicAuth("admin", "secret")
+	}
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, r)
+	return w
+}
+
+func TestDashboard_auth(t *testing.T) {
+	d, _, _ := newTestDashboard(t)
+	w := do(d, "GET", "/", nil, false)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GET / without auth = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("GET / without auth has no WWW-Authenticate header")
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("admin", "wrong")
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GET / with wrong password = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestDashboard_pages(t *testing.T) {
+	var tests = []struct {
+		path string
+		want string
+	}{
+		{"/", "!tieup"},
+		{"/images", "https://example.com/1.png"},
+		{"/images", "<b>look</b>"},
+		{"/feedback", "&lt;b&gt;nice&lt;/b&gt;"},
+		{"/feedback?player=bob", "meh"},
+		{"/feedback", "<td>wontfix</td><td>rp, loth</td>"},
+		{"/cmdstats", "!tieup"},
+		{"/channels", "Alice"},
+		{"/console", "<form"},
+	}
+	d, s, _ := newTestDashboard(t)
+	if err := s.AddCmdLog(&eribo.CmdLog{Command: eribo.CmdTieup, Player: "Alice", Channel: "Room"}); err != nil {
+		t.Fatal("AddCmdLog failed:", err)
+	}
+	for _, tt := range tests {
+		w := do(d, "GET", tt.path, nil, true)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want %d: %s", tt.path, w.Code, http.StatusOK, w.Body)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("GET %s does not contain %q", tt.path, tt.want)
+		}
+	}
+	if w := do(d, "GET", "/missing", nil, true); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestDashboard_imagesPages(t *testing.T) {
+	d, s, _ := newTestDashboard(t)
+	for id := 3; id <= 5; id++ {
+		m := &eribo.Message{Message: "more", Player: "Carol", Channel: "Room"}
+		if err := s.AddMessageWithImages(m, []*eribo.Image{{URL: fmt.Sprintf("https://example.com/%d.png", id)}}); err != nil {
+			t.Fatal("AddMessageWithImages failed:", err)
+		}
+	}
+	body := do(d, "GET", "/images?limit=2", nil, true).Body.String()
+	if !strings.Contains(body, "offset=2") {
+		t.Errorf("first page of images has no link to the next page")
+	}
+	body = do(d, "GET", "/images?limit=2&offset=2&all=1", nil, true).Body.String()
+	if !strings.Contains(body, "3.png") || strings.Contains(body, "1.png") {
+		t.Errorf("second page of all images does not show only images 3 and 4")
+	}
+}
+
+func TestDashboard_cmdLogsPages(t *testing.T) {
+	d, s, _ := newTestDashboard(t)
+	for i, cmd := range []eribo.Command{eribo.CmdTieup, eribo.CmdTomato, eribo.CmdTieup, eribo.CmdTomato, eribo.CmdTieup} {
+		l := &eribo.CmdLog{Command: cmd, Args: fmt.Sprintf("arg%d", i+1), Player: "Alice", Channel: "Room", Created: time.Now()}
+		if err := s.AddCmdLog(l); err != nil {
+			t.Fatal("AddCmdLog failed:", err)
+		}
+	}
+	// The tieups are logs 5, 3 and 1, so filtering the two most recent logs
+	// would miss log 3.
+	body := do(d, "GET", "/cmdlogs?command=!tieup&player=alice&limit=2", nil, true).Body.String()
+	if !strings.Contains(body, "arg5") || !strings.Contains(body, "arg3") || strings.Contains(body, "arg4") {
+		t.Errorf("first page of tieups does not show only logs 5 and 3:\n%s", body)
+	}
+	if !strings.Contains(body, "before=3") {
+		t.Fatalf("first page of tieups has no link to the next page")
+	}
+	body = do(d, "GET", "/cmdlogs?command=!tieup&player=alice&limit=2&before=3", nil, true).Body.String()
+	if !strings.Contains(body, "arg1") || strings.Contains(body, "arg3") || strings.Contains(body, "arg2") {
+		t.Errorf("second page of tieups does not show only log 1:\n%s", body)
+	}
+	if strings.Contains(body, "before=1") {
+		t.Errorf("last page of tieups has a link to a next page")
+	}
+	if w := do(d, "GET", "/cmdlogs?command=!nope", nil, true); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /cmdlogs with unknown command = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDashboard_imageActions(t *testing.T) {
+	d, s, _ := newTestDashboard(t)
+
+	w := do(d, "POST", "/images/done", url.Values{"id": {"1"}}, true)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("POST /images/done = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if !image(t, s, 1).Done {
+		t.Errorf("image 1 not marked as done")
+	}
+
+	w = do(d, "POST", "/images/kuid", url.Values{"id": {"2"}, "kuid": {"42"}}, true)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("POST /images/kuid = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if kuid := image(t, s, 2).Kuid; kuid != 42 {
+		t.Errorf("image 2 kuid = %d, want 42", kuid)
+	}
+
+	if w := do(d, "POST", "/images/kuid", url.Values{"id": {"2"}, "kuid": {"x"}}, true); w.Code != http.StatusBadRequest {
+		t.Errorf("POST /images/kuid with bad kuid = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := do(d, "GET", "/images/done?id=1", nil, true); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /images/done = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDashboard_crossOrigin(t *testing.T) {
+	d, s, _ := newTestDashboard(t)
+	r := httptest.NewRequest("POST", "/images/done", strings.NewReader("id=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", "https://evil.example.com")
+	r.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("cross origin POST = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if image(t, s, 1).Done {
+		t.Errorf("cross origin POST changed image 1")
+	}
+}
+
+func TestDashboard_console(t *testing.T) {
+	d, _, commands := newTestDashboard(t)
+	w := do(d, "POST", "/console", url.Values{"command": {" !uptime "}}, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /console = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(*commands) != 1 || (*commands)[0] != "!uptime" {
+		t.Errorf("console ran %q, want [\"!uptime\"]", *commands)
+	}
+	if !strings.Contains(w.Body.String(), "<b>ok</b>") {
+		t.Errorf("console page does not show the reply")
+	}
+}